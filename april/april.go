@@ -0,0 +1,317 @@
+//Package april permite ejecutar programas April desde un programa Go. Cada Interpreter
+//tiene sus propias variables y puede registrar funciones propias del programa Go.
+package april
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/module"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+	"github.com/kenshindeveloper/april/resolver"
+	"github.com/kenshindeveloper/april/typecheck"
+)
+
+//Etapas en las que se puede producir un Error.
+const (
+	ParseStage   = "parser errors"
+	TypeStage    = "type errors"
+	ResolveStage = "resolve errors"
+	RuntimeStage = "runtime error"
+)
+
+//Error son los errores de un programa April. Stage indica en que etapa se produjeron,
+//si es RuntimeStage el programa ya se ejecuto hasta el error.
+type Error struct {
+	Stage       string
+	Diagnostics []*diag.Diagnostic
+	sources     diag.Sources
+}
+
+func (e *Error) Error() string {
+	messages := []string{}
+	for _, d := range e.Diagnostics {
+		messages = append(messages, d.Error())
+	}
+	return e.Stage + ": " + strings.Join(messages, "; ")
+}
+
+//Render muestra los errores en 'w' con un extracto del codigo que los produjo.
+func (e *Error) Render(w io.Writer) {
+	for _, d := range e.Diagnostics {
+		diag.Render(w, d, e.sources)
+		io.WriteString(w, "\n")
+	}
+}
+
+//ExitError indica que el programa termino llamando a exit(status).
+type ExitError struct {
+	Status int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Status)
+}
+
+//ErrStepLimit es la causa de un LimitError cuando se supera el limite de SetStepLimit.
+var ErrStepLimit = errors.New("step limit exceeded")
+
+//LimitError indica que la ejecucion se detuvo antes de terminar. Err es ErrStepLimit o el
+//error del contexto, context.Canceled o context.DeadlineExceeded.
+type LimitError struct {
+	Err error
+}
+
+func (e *LimitError) Error() string {
+	return "execution stopped: " + e.Err.Error()
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+//Interpreter ejecuta programas April con el evaluador. Las variables, funciones y
+//builtins registrados se conservan entre llamadas, como en la consola. Un Interpreter no
+//se puede usar desde varias goroutines a la vez.
+type Interpreter struct {
+	env      *object.Environment
+	checker  *typecheck.Checker
+	resolver *resolver.Resolver
+	sources  diag.Sources
+	loader   *module.Loader
+	paths    []string
+	maxSteps int64
+}
+
+func New() *Interpreter {
+	i := &Interpreter{
+		env:      object.NewEnvironment(),
+		checker:  typecheck.New(),
+		resolver: resolver.New(),
+		sources:  diag.Sources{},
+	}
+	i.loader = module.New(i.sources)
+	i.env.Runtime().Importer = i.loader
+	return i
+}
+
+//SetStepLimit limita la cantidad de sentencias, vueltas de for y llamadas de cada
+//ejecucion. Al superarlo la ejecucion termina con un LimitError, 0 quita el limite.
+func (i *Interpreter) SetStepLimit(maxSteps int64) {
+	i.maxSteps = maxSteps
+}
+
+//SetMaxDepth cambia la cantidad maxima de llamadas en curso, por defecto
+//object.DefaultMaxDepth. Una recursion mas profunda termina con un error StackOverflow;
+//una recursion con llamadas 'return f(...)', directa o mutua, no crece.
+func (i *Interpreter) SetMaxDepth(maxDepth int) {
+	i.env.Runtime().SetMaxDepth(maxDepth)
+}
+
+//SetSearchPath cambia los directorios donde se buscan los imports que no estan junto al
+//archivo que los importa, por defecto lexer.SearchPath(), los de APRIL_PATH. Despues de
+//esos directorios se busca en la biblioteca estandar.
+func (i *Interpreter) SetSearchPath(paths []string) {
+	i.paths = paths
+	i.loader.SetSearchPath(paths)
+}
+
+//RunString ejecuta el codigo 'source' y devuelve el valor de la ultima sentencia.
+func (i *Interpreter) RunString(source string) (object.Object, error) {
+	return i.RunStringContext(context.Background(), source)
+}
+
+//RunStringContext es RunString con una ejecucion que termina si se cancela 'ctx'.
+func (i *Interpreter) RunStringContext(ctx context.Context, source string) (object.Object, error) {
+	return i.run(ctx, lexer.New(source), "", source)
+}
+
+//RunFile ejecuta el archivo 'path' y devuelve el valor de la ultima sentencia.
+func (i *Interpreter) RunFile(path string) (object.Object, error) {
+	return i.RunFileContext(context.Background(), path)
+}
+
+//RunFileContext es RunFile con una ejecucion que termina si se cancela 'ctx'.
+func (i *Interpreter) RunFileContext(ctx context.Context, path string) (object.Object, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return i.run(ctx, lexer.NewFile(path, string(data)), path, string(data))
+}
+
+func (i *Interpreter) run(ctx context.Context, l *lexer.Lexer, name, source string) (object.Object, error) {
+	i.sources[name] = source
+
+	l.SetSearchPath(i.paths)
+	l.SetFileSystem(i.env.Runtime().FS)
+	p := parser.New(l)
+	program := p.ParserProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		return nil, i.newError(ParseStage, diagnostics)
+	}
+
+	if diagnostics := i.checker.Check(program); len(diagnostics) > 0 {
+		return nil, i.newError(TypeStage, diagnostics)
+	}
+
+	if diagnostics := i.resolver.Resolve(program); len(diagnostics) > 0 {
+		return nil, i.newError(ResolveStage, diagnostics)
+	}
+
+	i.env.Runtime().Limit(ctx, i.maxSteps)
+	evaluated := evaluator.Eval(program, i.env)
+	i.resolver.Commit(func(slot int) bool {
+		_, ok := i.env.GetAt(0, slot)
+		return ok
+	})
+	return i.result(ctx, evaluated)
+}
+
+//SetStdout cambia donde escriben print, printf y las demas funciones del lenguaje.
+func (i *Interpreter) SetStdout(w io.Writer) {
+	i.env.Runtime().IO.Stdout = w
+}
+
+//SetStderr cambia la salida de errores de las funciones del lenguaje.
+func (i *Interpreter) SetStderr(w io.Writer) {
+	i.env.Runtime().IO.Stderr = w
+}
+
+//SetStdin cambia de donde lee la funcion input.
+func (i *Interpreter) SetStdin(r io.Reader) {
+	i.env.Runtime().IO.Stdin = object.NewReader(r)
+}
+
+//SetFileSystem limita los archivos que pueden usar open, create, write, remove y las demas
+//funciones de archivos, y los que se pueden importar fuera de la biblioteca estandar. Si
+//'fs' es nil se pueden usar todos. Lo que no se permite termina con un error
+//PermissionDenied que el programa puede atrapar con try.
+func (i *Interpreter) SetFileSystem(fs *object.FileSystem) {
+	i.env.Runtime().FS = fs
+}
+
+//Call llama a la funcion 'fnName' del programa o del lenguaje con los argumentos 'args'.
+func (i *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	return i.CallContext(context.Background(), fnName, args...)
+}
+
+//CallContext es Call con una ejecucion que termina si se cancela 'ctx'.
+func (i *Interpreter) CallContext(ctx context.Context, fnName string, args ...object.Object) (object.Object, error) {
+	fn, ok := i.GetGlobal(fnName)
+	if !ok {
+		fn, ok = evaluator.Builtin(fnName)
+	}
+	if !ok {
+		return nil, i.newError(RuntimeStage, []*diag.Diagnostic{
+			diag.Errorf(diag.NameNotFound, diag.Span{}, "identifier not found: %s", fnName),
+		})
+	}
+	i.env.Runtime().Limit(ctx, i.maxSteps)
+	return i.result(ctx, evaluator.Apply(i.env, fn, args))
+}
+
+//GetGlobal devuelve el valor de una variable del programa principal, de una variable
+//'global' o de una funcion.
+func (i *Interpreter) GetGlobal(name string) (object.Object, bool) {
+	if obj, ok := i.env.GetGlobal(name); ok {
+		return obj, true
+	}
+	if slot, ok := i.resolver.Slot(name); ok {
+		return i.env.GetAt(0, slot)
+	}
+	return nil, false
+}
+
+//SetGlobal cambia el valor de la variable 'name' del programa principal. Si no existe la
+//crea como una variable 'global', visible tambien desde las funciones.
+func (i *Interpreter) SetGlobal(name string, value object.Object) {
+	if slot, ok := i.resolver.Slot(name); ok {
+		i.env.SetAt(0, slot, value)
+		return
+	}
+	i.define(name, value, typeOf(value))
+}
+
+//RegisterBuiltin agrega la funcion 'fn' con el nombre 'name' a este Interpreter. Puede
+//reemplazar una funcion del lenguaje.
+func (i *Interpreter) RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	i.define(name, &object.Builtin{Fn: fn}, typecheck.Func)
+}
+
+//RegisterFunction agrega la funcion de Go 'fn' con el nombre 'name'. Los argumentos y
+//el resultado se convierten con object.ToGo y object.FromGo.
+func (i *Interpreter) RegisterFunction(name string, fn interface{}) error {
+	builtin, err := object.FromGo(fn)
+	if err != nil {
+		return err
+	}
+	if _, ok := builtin.(*object.Builtin); !ok {
+		return fmt.Errorf("value of type %T is not a function", fn)
+	}
+	i.define(name, builtin, typecheck.Func)
+	return nil
+}
+
+func (i *Interpreter) define(name string, value object.Object, typ typecheck.Type) {
+	i.env.SaveGlobal(name, value)
+	i.checker.Declare(name, typ)
+	i.resolver.Global(name)
+}
+
+func (i *Interpreter) result(ctx context.Context, obj object.Object) (object.Object, error) {
+	err, ok := obj.(*object.Error)
+	if !ok {
+		return obj, nil
+	}
+
+	switch err.Code {
+	case diag.Exit:
+		//un exit sin un entero como codigo, por ejemplo de una funcion de Go, sale con 0.
+		status, _ := err.Value.(*object.Integer)
+		if status == nil {
+			return nil, &ExitError{Status: 0}
+		}
+		return nil, &ExitError{Status: int(status.Value)}
+	case diag.StepLimit:
+		return nil, &LimitError{Err: ErrStepLimit}
+	case diag.Canceled:
+		return nil, &LimitError{Err: ctx.Err()}
+	}
+	return nil, i.newError(RuntimeStage, []*diag.Diagnostic{err.Diagnostic()})
+}
+
+func (i *Interpreter) newError(stage string, diagnostics []*diag.Diagnostic) *Error {
+	return &Error{Stage: stage, Diagnostics: diagnostics, sources: i.sources}
+}
+
+//typeOf devuelve el tipo estatico de un valor creado desde Go.
+func typeOf(obj object.Object) typecheck.Type {
+	switch obj.(type) {
+	case *object.Integer:
+		return typecheck.Int
+	case *object.Double:
+		return typecheck.Double
+	case *object.Boolean:
+		return typecheck.Bool
+	case *object.String:
+		return typecheck.String
+	case *object.List:
+		return typecheck.List
+	case *object.Hash:
+		return typecheck.Map
+	case *object.Function, *object.FunctionClosure, *object.Builtin:
+		return typecheck.Func
+	case *object.Struct:
+		return typecheck.Struct
+	}
+	return typecheck.Any
+}
@@ -0,0 +1,493 @@
+package april
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/object"
+)
+
+func TestRunString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2;", "3"},
+		{"x := 10; x * 2;", "20"},
+		{"fn add(a:int, b:int) int { return a + b; } add(2, 3);", "5"},
+	}
+
+	for i, tt := range tests {
+		result, err := New().RunString(tt.input)
+		if err != nil {
+			t.Errorf("tests[%d] - unexpected error: %s", i, err)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("tests[%d] - result is not %q. got=%q", i, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestRunStringErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		stage string
+	}{
+		{"var x:int = ;", ParseStage},
+		{"var s:string = \"a\"; var x:int = s;", TypeStage},
+		{"x := 1; x := 2;", ResolveStage},
+		{"1 / 0;", RuntimeStage},
+	}
+
+	for i, tt := range tests {
+		_, err := New().RunString(tt.input)
+		aprilErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("tests[%d] - error is not *Error. got=%T (%v)", i, err, err)
+			continue
+		}
+		if aprilErr.Stage != tt.stage {
+			t.Errorf("tests[%d] - stage is not %q. got=%q (%s)", i, tt.stage, aprilErr.Stage, aprilErr)
+		}
+	}
+}
+
+func TestInterpreterKeepsState(t *testing.T) {
+	interp := New()
+	if _, err := interp.RunString("total := 1; fn twice(n:int) int { return n * 2; }"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := interp.RunString("total = twice(total + 1); total;")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Inspect() != "4" {
+		t.Fatalf("result is not 4. got=%q", result.Inspect())
+	}
+
+	total, ok := interp.GetGlobal("total")
+	if !ok || total.Inspect() != "4" {
+		t.Fatalf("GetGlobal(total) is not 4. got=%v (%v)", total, ok)
+	}
+}
+
+func TestInterpreterAfterRuntimeError(t *testing.T) {
+	interp := New()
+	if _, err := interp.RunString("ok := 1; x := 1 / 0;"); err == nil {
+		t.Fatalf("expected a runtime error")
+	}
+
+	result, err := interp.RunString("x := 2; x + ok;")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Inspect() != "3" {
+		t.Fatalf("result is not 3. got=%q", result.Inspect())
+	}
+}
+
+func TestCall(t *testing.T) {
+	interp := New()
+	_, err := interp.RunString("fn greet(name:string) string { return \"hola \" + name; }\nvar twice:func = fn(n:int) int { return n * 2; };")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := interp.Call("greet", &object.String{Value: "april"})
+	if err != nil || result.Inspect() != "'hola april'" {
+		t.Errorf("greet result wrong. got=%v (%v)", result, err)
+	}
+
+	result, err = interp.Call("twice", &object.Integer{Value: 21})
+	if err != nil || result.Inspect() != "42" {
+		t.Errorf("twice result wrong. got=%v (%v)", result, err)
+	}
+
+	if _, err := interp.Call("greet"); err == nil {
+		t.Errorf("expected error calling greet without arguments")
+	}
+	if _, err := interp.Call("missing"); err == nil {
+		t.Errorf("expected error calling an unknown function")
+	}
+}
+
+func TestSetGlobal(t *testing.T) {
+	interp := New()
+	interp.SetGlobal("limit", &object.Integer{Value: 5})
+
+	result, err := interp.RunString("fn over(n:int) bool { return n > limit; } over(7);")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Inspect() != "true" {
+		t.Fatalf("result is not true. got=%q", result.Inspect())
+	}
+
+	if _, err := interp.RunString("x := 1;"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	interp.SetGlobal("x", &object.Integer{Value: 9})
+	result, err = interp.RunString("x + 1;")
+	if err != nil || result.Inspect() != "10" {
+		t.Fatalf("result is not 10. got=%v (%v)", result, err)
+	}
+	//los booleanos creados desde Go funcionan en las condiciones.
+	interp.SetGlobal("enabled", &object.Boolean{Value: false})
+	result, err = interp.RunString("r := 0; if (enabled) { r = 1; } else { r = 2; } if (not enabled) { r += 10; } r;")
+	if err != nil || result.Inspect() != "12" {
+		t.Fatalf("result is not 12. got=%v (%v)", result, err)
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	calls := 0
+	interp := New()
+	interp.RegisterBuiltin("hostSum", func(args ...object.Object) object.Object {
+		calls++
+		var sum int64
+		for _, arg := range args {
+			sum += arg.(*object.Integer).Value
+		}
+		return &object.Integer{Value: sum}
+	})
+
+	result, err := interp.RunString("fn f() int { return hostSum(1, 2, 3); } f();")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Inspect() != "6" || calls != 1 {
+		t.Fatalf("result is not 6 after one call. got=%q, calls=%d", result.Inspect(), calls)
+	}
+
+	//las funciones registradas son de cada Interpreter.
+	if _, err := New().RunString("hostSum(1);"); err == nil {
+		t.Fatalf("expected error using a builtin of other interpreter")
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "main.april")
+	if err := ioutil.WriteFile(path, []byte("x := 2;\nx * 21;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New().RunFile(path)
+	if err != nil || result.Inspect() != "42" {
+		t.Fatalf("result is not 42. got=%v (%v)", result, err)
+	}
+
+	if _, err := New().RunFile(filepath.Join(dir, "missing.april")); err == nil {
+		t.Fatalf("expected error reading a missing file")
+	}
+}
+
+func TestRegisterFunction(t *testing.T) {
+	type Order struct {
+		ID    int64   `april:"id"`
+		Total float64 `april:"total"`
+		Notes string  `april:"-"`
+	}
+
+	interp := New()
+	err := interp.RegisterFunction("discount", func(order Order, percent float64) Order {
+		order.Total -= order.Total * percent / 100
+		return order
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := interp.RegisterFunction("limit", 10); err == nil {
+		t.Fatalf("expected error registering a value that is not a function")
+	}
+
+	interp.SetGlobal("order", mustFromGo(t, Order{ID: 7, Total: 200}))
+	result, err := interp.RunString("discount(order, 25);")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var order Order
+	if err := object.ToGo(result, &order); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if order.ID != 7 || order.Total != 150 {
+		t.Fatalf("order is wrong. got=%+v", order)
+	}
+
+	if _, err := interp.RunString("discount(order, \"a\");"); err == nil {
+		t.Fatalf("expected error converting a string argument to float64")
+	}
+}
+
+func mustFromGo(t *testing.T, value interface{}) object.Object {
+	obj, err := object.FromGo(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return obj
+}
+
+func TestPrograms(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.april"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no programs in testdata: %v", err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(file, ".april")
+		expected, err := ioutil.ReadFile(name + ".out")
+		if err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+
+		var stdout, stderr bytes.Buffer
+		interp := New()
+		interp.SetStdout(&stdout)
+		interp.SetStderr(&stderr)
+		if input, err := os.Open(name + ".in"); err == nil {
+			defer input.Close()
+			interp.SetStdin(input)
+		} else {
+			interp.SetStdin(strings.NewReader(""))
+		}
+
+		if _, err := interp.RunFile(file); err != nil {
+			t.Errorf("%s: unexpected error: %s", file, err)
+			continue
+		}
+		if stdout.String() != string(expected) {
+			t.Errorf("%s: output wrong.\nwant=%q\ngot=%q", file, expected, stdout.String())
+		}
+		if stderr.Len() > 0 {
+			t.Errorf("%s: unexpected stderr output %q", file, stderr.String())
+		}
+	}
+}
+
+func TestOutputIsPerInterpreter(t *testing.T) {
+	var first, second bytes.Buffer
+	a, b := New(), New()
+	a.SetStdout(&first)
+	b.SetStdout(&second)
+
+	if _, err := a.RunString("print(1);"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := b.RunString("fn show() { print(2); } show();"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Call("print", &object.String{Value: "x"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if first.String() != "1\n'x'\n" || second.String() != "2\n" {
+		t.Fatalf("output wrong. first=%q second=%q", first.String(), second.String())
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	tests := []string{
+		"for (true) { 1; }",
+		"x := 0; for (true) { x++; }",
+		"fn loop(n:int) int { return loop(n + 1); } loop(0);",
+		"try { for (true) { 1; } } catch (e) { print(\"caught\"); }",
+	}
+
+	for i, input := range tests {
+		var stdout bytes.Buffer
+		interp := New()
+		interp.SetStdout(&stdout)
+		interp.SetStepLimit(1000)
+
+		_, err := interp.RunString(input)
+		if !errors.Is(err, ErrStepLimit) {
+			t.Errorf("tests[%d] - error is not ErrStepLimit. got=%T (%v)", i, err, err)
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("tests[%d] - the limit must not be caught. got output %q", i, stdout.String())
+		}
+
+		//el limite se cuenta de nuevo en cada ejecucion.
+		if result, err := interp.RunString("1 + 1;"); err != nil || result.Inspect() != "2" {
+			t.Errorf("tests[%d] - interpreter unusable after the limit. got=%v (%v)", i, result, err)
+		}
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	interp := New()
+	_, err := interp.RunStringContext(ctx, "fn spin() { for (true) { 1; } }")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	start := time.Now()
+	_, err = interp.CallContext(ctx, "spin")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error is not DeadlineExceeded. got=%T (%v)", err, err)
+	}
+	if _, ok := err.(*LimitError); !ok {
+		t.Fatalf("error is not *LimitError. got=%T", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cancel took too long: %s", elapsed)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := interp.RunStringContext(canceled, "x := 1;"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error is not Canceled. got=%T (%v)", err, err)
+	}
+}
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		input  string
+		status int
+	}{
+		{"exit();", 0},
+		{"exit(3);", 3},
+		{"fn quit() { exit(4); } quit(); print(\"after\");", 4},
+		{"try { exit(5); } catch (e) { print(\"caught\"); } finally { print(\"finally\"); }", 5},
+	}
+
+	for i, tt := range tests {
+		var stdout bytes.Buffer
+		interp := New()
+		interp.SetStdout(&stdout)
+
+		_, err := interp.RunString(tt.input)
+		exitErr, ok := err.(*ExitError)
+		if !ok {
+			t.Errorf("tests[%d] - error is not *ExitError. got=%T (%v)", i, err, err)
+			continue
+		}
+		if exitErr.Status != tt.status {
+			t.Errorf("tests[%d] - status is not %d. got=%d", i, tt.status, exitErr.Status)
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("tests[%d] - nothing must run after exit. got output %q", i, stdout.String())
+		}
+	}
+
+	for i, value := range []object.Object{nil, &object.String{Value: "3"}} {
+		interp := New()
+		interp.RegisterBuiltin("quit", func(args ...object.Object) object.Object {
+			return &object.Error{Code: diag.Exit, Message: "exit", Value: value}
+		})
+		_, err := interp.RunString("quit();")
+		if exitErr, ok := err.(*ExitError); !ok || exitErr.Status != 0 {
+			t.Errorf("values[%d] - error is not exit status 0. got=%T (%v)", i, err, err)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	interp := New()
+	interp.SetMaxDepth(100)
+
+	_, err := interp.RunString(`
+fn down(n:int) int { if (n == 0) { return 0; } return 1 + down(n - 1); }
+fn loop(n:int) int { if (n == 0) { return 0; } return loop(n - 1); }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result, err := interp.Call("down", &object.Integer{Value: 50}); err != nil || result.Inspect() != "50" {
+		t.Fatalf("down(50) is not 50. got=%v (%v)", result, err)
+	}
+	if result, err := interp.Call("loop", &object.Integer{Value: 100000}); err != nil || result.Inspect() != "0" {
+		t.Fatalf("tail calls must not count for the limit. got=%v (%v)", result, err)
+	}
+
+	_, err = interp.Call("down", &object.Integer{Value: 200})
+	aprilErr, ok := err.(*Error)
+	if !ok || aprilErr.Stage != RuntimeStage {
+		t.Fatalf("error is not a runtime *Error. got=%T (%v)", err, err)
+	}
+	if code := aprilErr.Diagnostics[0].Code; code != diag.StackOverflow {
+		t.Fatalf("code is not %s. got=%s", diag.StackOverflow, code)
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	interp := New()
+	interp.SetStdout(&stdout)
+	interp.SetFileSystem(&object.FileSystem{Root: dir})
+
+	_, err = interp.RunString(`
+f := create("notes.txt");
+write(f, "hola");
+close(f);
+try { create("../escape.txt"); } catch (e) { print(e.kind); }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stdout.String() != "'PermissionDenied'\n" {
+		t.Fatalf("output is not 'PermissionDenied'. got=%q", stdout.String())
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "notes.txt")); err != nil || string(data) != "hola" {
+		t.Fatalf("notes.txt is not written inside the root. got=%q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.txt")); !os.IsNotExist(err) {
+		t.Fatalf("escape.txt was created outside the root")
+	}
+
+	interp.SetFileSystem(&object.FileSystem{Root: dir, ReadOnly: true})
+	_, err = interp.RunString(`remove("notes.txt");`)
+	aprilErr, ok := err.(*Error)
+	if !ok || aprilErr.Diagnostics[0].Code != diag.PermissionDenied {
+		t.Fatalf("remove must be denied in read-only mode. got=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Fatalf("notes.txt was removed: %v", err)
+	}
+}
+
+func TestImportStd(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "std/math"
+Abs(-2.5);`, "2.5"},
+		{`import "std/math" as math
+math.Abs(-1.5);`, "1.5"},
+	}
+
+	for _, tt := range tests {
+		result, err := New().RunString(tt.input)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result.Inspect() != tt.expected {
+			t.Fatalf("result is not %s. got=%s", tt.expected, result.Inspect())
+		}
+	}
+}
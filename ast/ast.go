@@ -12,6 +12,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -30,6 +31,7 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment //comentarios del archivo en el orden en que aparecen
 }
 
 func (p *Program) TokenLiteral() string {
@@ -42,6 +44,13 @@ func (p *Program) TokenLiteral() string {
 	return result
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -52,14 +61,41 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+//Comment es un comentario '//'. No forma parte de las sentencias, el parser los guarda
+//en el Program para que el formateador los vuelva a escribir.
+type Comment struct {
+	Token token.Token
+	Text  string //texto con '//', sin el salto de linea
+}
+
+func (c *Comment) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (c *Comment) Pos() token.Position {
+	return c.Token.Pos
+}
+
+func (c *Comment) String() string {
+	return c.Text
+}
+
 //***************************************************************************************
 //***************************************************************************************
 //***************************************************************************************
 
 type Identifier struct {
-	Token token.Token
-	Name  string
-	Line  int
+	Token   token.Token
+	Name    string
+	Binding *Binding //variable a la que se refiere, la completa el paquete resolver
+}
+
+//Binding indica donde esta la variable de un identificador: Depth entornos hacia afuera
+//en la posicion Slot. Las funciones y las variables 'global' se buscan por nombre.
+type Binding struct {
+	Global bool
+	Depth  int
+	Slot   int
 }
 
 func (i *Identifier) expressionNode() {}
@@ -68,6 +104,10 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i *Identifier) String() string {
 	return i.Token.Literal
 }
@@ -79,7 +119,6 @@ func (i *Identifier) String() string {
 type Nil struct {
 	Token token.Token
 	Name  string
-	Line  int
 }
 
 func (n *Nil) expressionNode() {}
@@ -88,6 +127,10 @@ func (n *Nil) TokenLiteral() string {
 	return n.Token.Literal
 }
 
+func (n *Nil) Pos() token.Position {
+	return n.Token.Pos
+}
+
 func (n *Nil) String() string {
 	return n.Token.Literal
 }
@@ -101,7 +144,6 @@ type VarStatement struct {
 	Name  *Identifier //x
 	Type  *Identifier // int
 	Value Expression  // 5
-	Line  int
 }
 
 func (vs *VarStatement) statementNode() {}
@@ -110,6 +152,10 @@ func (vs *VarStatement) TokenLiteral() string {
 	return vs.Token.Literal
 }
 
+func (vs *VarStatement) Pos() token.Position {
+	return vs.Token.Pos
+}
+
 func (vs *VarStatement) String() string {
 	var out bytes.Buffer
 
@@ -135,7 +181,6 @@ type GlobalStatement struct {
 	Name  *Identifier //x
 	Type  *Identifier // int
 	Value Expression  // 5
-	Line  int
 }
 
 func (gs *GlobalStatement) statementNode() {}
@@ -144,6 +189,10 @@ func (gs *GlobalStatement) TokenLiteral() string {
 	return gs.Token.Literal
 }
 
+func (gs *GlobalStatement) Pos() token.Position {
+	return gs.Token.Pos
+}
+
 func (gs *GlobalStatement) String() string {
 	var out bytes.Buffer
 
@@ -167,7 +216,6 @@ func (gs *GlobalStatement) String() string {
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
-	Line       int
 }
 
 func (es *ExpressionStatement) statementNode() {}
@@ -176,6 +224,10 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos
+}
+
 func (es *ExpressionStatement) String() string {
 	var out bytes.Buffer
 
@@ -191,7 +243,6 @@ func (es *ExpressionStatement) String() string {
 type Integer struct {
 	Token token.Token
 	Value int64
-	Line  int
 }
 
 func (i *Integer) expressionNode() {}
@@ -200,6 +251,10 @@ func (i *Integer) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Integer) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i *Integer) String() string {
 	return i.Token.Literal
 }
@@ -211,7 +266,6 @@ func (i *Integer) String() string {
 type Boolean struct {
 	Token token.Token
 	Value bool
-	Line  int
 }
 
 func (b *Boolean) expressionNode() {}
@@ -220,6 +274,10 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
 func (b *Boolean) String() string {
 	return fmt.Sprintf("%t", b.Value)
 }
@@ -231,7 +289,6 @@ func (b *Boolean) String() string {
 type String struct {
 	Token token.Token
 	Value string
-	Line  int
 }
 
 func (s *String) expressionNode() {}
@@ -240,6 +297,10 @@ func (s *String) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (s *String) Pos() token.Position {
+	return s.Token.Pos
+}
+
 func (s *String) String() string {
 	return s.Value
 }
@@ -251,7 +312,6 @@ func (s *String) String() string {
 type Double struct {
 	Token token.Token
 	Value float64
-	Line  int
 }
 
 func (d *Double) expressionNode() {}
@@ -260,6 +320,10 @@ func (d *Double) TokenLiteral() string {
 	return d.Token.Literal
 }
 
+func (d *Double) Pos() token.Position {
+	return d.Token.Pos
+}
+
 func (d *Double) String() string {
 	return fmt.Sprintf("%g", d.Value)
 }
@@ -272,7 +336,6 @@ type PrefixExpression struct {
 	Token    token.Token
 	Operator string
 	Right    Expression
-	Line     int
 }
 
 func (pe *PrefixExpression) expressionNode() {}
@@ -281,6 +344,10 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -301,7 +368,6 @@ type InfixExpression struct {
 	Left     Expression
 	Operator string
 	Right    Expression
-	Line     int
 }
 
 func (ie *InfixExpression) expressionNode() {}
@@ -310,6 +376,10 @@ func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *InfixExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -330,7 +400,6 @@ type ImplicitDeclarationExpression struct {
 	Token token.Token
 	Left  *Identifier
 	Right Expression
-	Line  int
 }
 
 func (ide *ImplicitDeclarationExpression) expressionNode() {}
@@ -339,6 +408,10 @@ func (ide *ImplicitDeclarationExpression) TokenLiteral() string {
 	return ide.Token.Literal
 }
 
+func (ide *ImplicitDeclarationExpression) Pos() token.Position {
+	return ide.Token.Pos
+}
+
 func (ide *ImplicitDeclarationExpression) String() string {
 	var out bytes.Buffer
 
@@ -357,7 +430,6 @@ type AssignExpression struct {
 	Token token.Token
 	Left  Expression
 	Right Expression
-	Line  int
 }
 
 func (ae *AssignExpression) expressionNode() {}
@@ -366,6 +438,10 @@ func (ae *AssignExpression) TokenLiteral() string {
 	return ae.Token.Literal
 }
 
+func (ae *AssignExpression) Pos() token.Position {
+	return ae.Token.Pos
+}
+
 func (ae *AssignExpression) String() string {
 	var out bytes.Buffer
 
@@ -385,7 +461,6 @@ type AssignOperationExpression struct {
 	Left     *Identifier
 	Operator string
 	Right    Expression
-	Line     int
 }
 
 func (aoe *AssignOperationExpression) expressionNode() {}
@@ -394,6 +469,10 @@ func (aoe *AssignOperationExpression) TokenLiteral() string {
 	return aoe.Token.Literal
 }
 
+func (aoe *AssignOperationExpression) Pos() token.Position {
+	return aoe.Token.Pos
+}
+
 func (aoe *AssignOperationExpression) String() string {
 	var out bytes.Buffer
 
@@ -411,7 +490,6 @@ func (aoe *AssignOperationExpression) String() string {
 type ReturnStatement struct {
 	Token      token.Token
 	Expression Expression
-	Line       int
 }
 
 func (rs *ReturnStatement) statementNode() {}
@@ -420,6 +498,10 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
+
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -434,9 +516,115 @@ func (rs *ReturnStatement) String() string {
 //***************************************************************************************
 //***************************************************************************************
 
+type ThrowStatement struct {
+	Token      token.Token //throw
+	Expression Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *ThrowStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("throw ")
+	if ts.Expression != nil {
+		out.WriteString(ts.Expression.String())
+	}
+
+	return out.String()
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//TryStatement puede no tener Catch o Finally, pero no ambos.
+type TryStatement struct {
+	Token     token.Token //try
+	Block     *BlockStatement
+	Parameter *Identifier //variable que recibe el error en el catch
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (ts *TryStatement) statementNode() {}
+
+func (ts *TryStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *TryStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try {")
+	out.WriteString(ts.Block.String())
+	out.WriteString("}")
+	if ts.Catch != nil {
+		out.WriteString(" catch (" + ts.Parameter.String() + ") {")
+		out.WriteString(ts.Catch.String())
+		out.WriteString("}")
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally {")
+		out.WriteString(ts.Finally.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//ImportStatement es 'import "ruta"', que agrega el codigo del archivo al programa, o
+//'import "ruta" as nombre', que carga el archivo como un modulo en la variable 'nombre'.
+type ImportStatement struct {
+	Token token.Token //import
+	Path  *String
+	Name  *Identifier //nil si no tiene 'as'
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImportStatement) Pos() token.Position {
+	return is.Token.Pos
+}
+
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral())
+	out.WriteString(" \"" + is.Path.Value + "\"")
+	if is.Name != nil {
+		out.WriteString(" as " + is.Name.Name)
+	}
+
+	return out.String()
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
 type BreakStatement struct {
 	Token token.Token
-	Line  int
 }
 
 func (b *BreakStatement) statementNode() {}
@@ -445,6 +633,10 @@ func (b *BreakStatement) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *BreakStatement) Pos() token.Position {
+	return b.Token.Pos
+}
+
 func (b *BreakStatement) String() string {
 	var out bytes.Buffer
 
@@ -462,7 +654,7 @@ type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
 	Type       int
-	Line       int
+	Rbrace     token.Token //'}' que cierra el bloque
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -471,6 +663,10 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -489,7 +685,6 @@ type IfExpression struct {
 	Codition    Expression
 	Consequence *BlockStatement
 	Alternative *BlockStatement
-	Line        int
 }
 
 func (ie *IfExpression) expressionNode() {}
@@ -498,6 +693,10 @@ func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *IfExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -523,7 +722,6 @@ type FunctionClosure struct {
 	Parameters []*FunctionParameters
 	Body       *BlockStatement
 	Type       *Identifier
-	Line       int
 }
 
 func (fc *FunctionClosure) expressionNode() {}
@@ -532,6 +730,10 @@ func (fc *FunctionClosure) TokenLiteral() string {
 	return fc.Token.Literal
 }
 
+func (fc *FunctionClosure) Pos() token.Position {
+	return fc.Token.Pos
+}
+
 func (fc *FunctionClosure) String() string {
 	var out bytes.Buffer
 
@@ -558,7 +760,6 @@ type CallExpression struct {
 	Token     token.Token //'('
 	Function  Expression  //Identifier o FunctionClosure
 	Arguments []Expression
-	Line      int
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -566,6 +767,10 @@ func (ce *CallExpression) expressionNode() {}
 func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
+
+func (ce *CallExpression) Pos() token.Position {
+	return ce.Token.Pos
+}
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -590,7 +795,6 @@ func (ce *CallExpression) String() string {
 type List struct {
 	Token    token.Token
 	Elements []Expression
-	Line     int
 }
 
 func (l *List) expressionNode() {}
@@ -599,6 +803,10 @@ func (l *List) TokenLiteral() string {
 	return l.Token.Literal
 }
 
+func (l *List) Pos() token.Position {
+	return l.Token.Pos
+}
+
 func (l *List) String() string {
 	var out bytes.Buffer
 
@@ -623,7 +831,6 @@ type IndexExpression struct {
 	Token token.Token
 	Left  Expression
 	Index Expression
-	Line  int
 }
 
 func (ie *IndexExpression) expressionNode() {}
@@ -632,6 +839,10 @@ func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *IndexExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -651,7 +862,6 @@ func (ie *IndexExpression) String() string {
 type Hash struct {
 	Token token.Token
 	Pairs map[Expression]Expression
-	Line  int
 }
 
 func (h *Hash) expressionNode() {}
@@ -660,6 +870,10 @@ func (h *Hash) TokenLiteral() string {
 	return h.Token.Literal
 }
 
+func (h *Hash) Pos() token.Position {
+	return h.Token.Pos
+}
+
 func (h *Hash) String() string {
 	var out bytes.Buffer
 
@@ -683,7 +897,6 @@ type PostfixExpression struct {
 	Token    token.Token
 	Left     *Identifier
 	Operator string
-	Line     int
 }
 
 func (pe *PostfixExpression) expressionNode() {}
@@ -692,6 +905,10 @@ func (pe *PostfixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe *PostfixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
 func (pe *PostfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -711,7 +928,6 @@ type ForStatement struct {
 	Condition   Expression
 	Operation   Expression
 	Body        *BlockStatement
-	Line        int
 }
 
 func (fs *ForStatement) statementNode() {}
@@ -720,6 +936,10 @@ func (fs *ForStatement) TokenLiteral() string {
 	return fs.Token.Literal
 }
 
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
 func (fs *ForStatement) String() string {
 	var out bytes.Buffer
 
@@ -742,7 +962,6 @@ type FunctionParameters struct {
 	Token token.Token
 	Name  *Identifier
 	Type  *Identifier
-	Line  int
 }
 
 func (fp *FunctionParameters) expressionNode() {}
@@ -751,6 +970,10 @@ func (fp *FunctionParameters) TokenLiteral() string {
 	return fp.Token.Literal
 }
 
+func (fp *FunctionParameters) Pos() token.Position {
+	return fp.Token.Pos
+}
+
 func (fp *FunctionParameters) String() string {
 	var out bytes.Buffer
 
@@ -771,7 +994,6 @@ type Function struct {
 	Parameters []*FunctionParameters
 	Type       *Identifier
 	Body       *BlockStatement
-	Line       int
 }
 
 func (fn *Function) statementNode() {}
@@ -780,6 +1002,10 @@ func (fn *Function) TokenLiteral() string {
 	return fn.Token.Literal
 }
 
+func (fn *Function) Pos() token.Position {
+	return fn.Token.Pos
+}
+
 func (fn *Function) String() string {
 	var out bytes.Buffer
 
@@ -806,7 +1032,6 @@ func (fn *Function) String() string {
 type Stream struct {
 	Token token.Token
 	FILE  *os.File
-	Line  int
 }
 
 func (s *Stream) expressionNode() {}
@@ -815,6 +1040,10 @@ func (s *Stream) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (s *Stream) Pos() token.Position {
+	return s.Token.Pos
+}
+
 func (s *Stream) String() string {
 	return s.FILE.Name()
 }
@@ -826,7 +1055,6 @@ func (s *Stream) String() string {
 type Struct struct {
 	Token   token.Token
 	Element map[*Identifier]*Identifier
-	Line    int
 }
 
 func (s *Struct) expressionNode() {}
@@ -835,6 +1063,10 @@ func (s *Struct) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (s *Struct) Pos() token.Position {
+	return s.Token.Pos
+}
+
 func (s *Struct) String() string {
 	var out bytes.Buffer
 
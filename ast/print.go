@@ -0,0 +1,96 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kenshindeveloper/april/token"
+)
+
+//Fprint muestra en 'w' el arbol de 'node': cada nodo en una linea con su tipo y su
+//posicion, y debajo sus campos indentados. Los campos nil y vacios no se muestran.
+func Fprint(w io.Writer, node Node) {
+	p := &printer{w: w}
+	p.value("", reflect.ValueOf(node), 0)
+}
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+type printer struct {
+	w io.Writer
+}
+
+func (p *printer) value(label string, v reflect.Value, depth int) {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return
+	}
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	indent := strings.Repeat("  ", depth)
+	if label != "" {
+		label += ": "
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		p.node(indent+label, v, depth)
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return
+		}
+		fmt.Fprintf(p.w, "%s%s\n", indent, strings.TrimSuffix(label, " "))
+		for i := 0; i < v.Len(); i++ {
+			p.value("", v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		if v.Len() == 0 {
+			return
+		}
+		fmt.Fprintf(p.w, "%s%s\n", indent, strings.TrimSuffix(label, " "))
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return position(keys[i]) < position(keys[j])
+		})
+		for _, key := range keys {
+			p.value("Key", key, depth+1)
+			p.value("Value", v.MapIndex(key), depth+1)
+		}
+	case reflect.String:
+		fmt.Fprintf(p.w, "%s%s%q\n", indent, label, v.String())
+	default:
+		fmt.Fprintf(p.w, "%s%s%v\n", indent, label, v.Interface())
+	}
+}
+
+//node muestra un nodo y sus campos, sin los tokens ni el Binding del resolver.
+func (p *printer) node(header string, v reflect.Value, depth int) {
+	header += strings.TrimPrefix(v.Type().String(), "*ast.")
+	if node, ok := v.Interface().(Node); ok && node.Pos().IsValid() {
+		header += fmt.Sprintf(" %d:%d", node.Pos().Line, node.Pos().Column)
+	}
+	fmt.Fprintln(p.w, header)
+
+	if v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	t := v.Elem().Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Type == tokenType || field.Name == "Binding" {
+			continue
+		}
+		p.value(field.Name, v.Elem().Field(i), depth+1)
+	}
+}
+
+//position ordena las claves de un Hash por su lugar en el codigo.
+func position(v reflect.Value) int {
+	if node, ok := v.Interface().(Node); ok {
+		return node.Pos().Offset
+	}
+	return 0
+}
@@ -0,0 +1,335 @@
+//Package cli es la linea de comandos de april: 'april <comando> [argumentos]'. Cada
+//comando devuelve el codigo de salida del proceso.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/file"
+	"github.com/kenshindeveloper/april/format"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+	"github.com/kenshindeveloper/april/repl"
+	"github.com/kenshindeveloper/april/token"
+)
+
+//Codigos de salida de los comandos. Los de 'run' son los de file.Start.
+const (
+	ExitOK      = 0 //el comando termino sin errores
+	ExitFailure = 1 //el programa fallo, un test fallo o un archivo no esta formateado
+	ExitUsage   = 2 //los argumentos son incorrectos o un archivo tiene errores
+)
+
+//CLI son los flujos y la version con los que se ejecutan los comandos.
+type CLI struct {
+	Version string
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Stdin   io.Reader
+}
+
+//command es un comando de april. 'run' define sus opciones en 'flags', las lee con
+//parse y ejecuta el comando.
+type command struct {
+	name  string
+	args  string //argumentos que se muestran en la ayuda
+	short string
+	run   func(c *CLI, flags *flag.FlagSet, args []string) int
+}
+
+var commands = []*command{
+	{name: "run", args: "[opciones] archivo.april [argumentos...]", short: "ejecuta un programa", run: runFile},
+	{name: "repl", short: "inicia la consola interactiva", run: runRepl},
+	{name: "check", args: "archivos...", short: "verifica la sintaxis, los tipos y el ambito sin ejecutar", run: runCheck},
+	{name: "fmt", args: "[-w] [-check] archivos...", short: "da formato al codigo de los archivos", run: runFmt},
+	{name: "test", args: "[archivos o directorios...]", short: "ejecuta las funciones Test de los archivos _test.april", run: runTest},
+	{name: "tokens", args: "archivo.april", short: "muestra los tokens del lexer", run: runTokens},
+	{name: "ast", args: "archivo.april", short: "muestra el arbol del parser", run: runAst},
+	{name: "version", short: "muestra la version de april", run: runVersion},
+}
+
+//Run ejecuta la linea de comandos 'args', sin el nombre del programa, y devuelve el
+//codigo de salida. Sin comando inicia la consola, y 'april archivo.april' o 'april
+//-backend=vm archivo.april' son 'april run ...'.
+func (c *CLI) Run(args []string) int {
+	if len(args) == 0 {
+		return c.Run([]string{"repl"})
+	}
+
+	name := args[0]
+	switch {
+	case name == "-h" || name == "-help" || name == "--help" || name == "help":
+		return c.help(args[1:])
+	case name == "-version" || name == "--version":
+		return runVersion(c, nil, nil)
+	case strings.HasPrefix(name, "-") || strings.HasSuffix(name, ".april"):
+		return c.Run(append([]string{"run"}, args...))
+	}
+
+	cmd := find(name)
+	if cmd == nil {
+		fmt.Fprintf(c.Stderr, "april: unknown command '%s'.\nRun 'april help' for usage.\n", name)
+		return ExitUsage
+	}
+	return cmd.run(c, c.flagSet(cmd, c.Stderr), args[1:])
+}
+
+//flagSet crea las opciones de 'cmd', que muestran la ayuda del comando en 'w'.
+func (c *CLI) flagSet(cmd *command, w io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("april "+cmd.name, flag.ContinueOnError)
+	flags.SetOutput(w)
+	flags.Usage = func() { c.usage(w, cmd, flags) }
+	return flags
+}
+
+//parse lee las opciones de 'args'. Si no se puede seguir con el comando, porque se pidio
+//la ayuda o una opcion es incorrecta, devuelve false y el codigo de salida.
+func parse(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK, false
+		}
+		return ExitUsage, false
+	}
+	return ExitOK, true
+}
+
+func find(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+//help muestra la ayuda general, o la de un comando con 'april help comando'.
+func (c *CLI) help(args []string) int {
+	if len(args) > 0 {
+		cmd := find(args[0])
+		if cmd == nil {
+			fmt.Fprintf(c.Stderr, "april: unknown command '%s'.\n", args[0])
+			return ExitUsage
+		}
+		return cmd.run(c, c.flagSet(cmd, c.Stdout), []string{"-h"})
+	}
+
+	fmt.Fprintf(c.Stdout, "April %s\n\nuso:\n\n\tapril <comando> [argumentos]\n\ncomandos:\n\n", c.Version)
+	for _, cmd := range commands {
+		fmt.Fprintf(c.Stdout, "\t%-8s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(c.Stdout, "\n'april archivo.april' es 'april run archivo.april' y 'april' sin argumentos inicia la consola.\n")
+	fmt.Fprintf(c.Stdout, "Use 'april help <comando>' para ver la ayuda de un comando.\n\n")
+	fmt.Fprintf(c.Stdout, "codigos de salida: %d sin errores, %d si el programa o un test falla, %d si los argumentos\no el codigo son incorrectos; un programa que llama a exit(n) sale con n.\n", ExitOK, ExitFailure, ExitUsage)
+	return ExitOK
+}
+
+func (c *CLI) usage(w io.Writer, cmd *command, flags *flag.FlagSet) {
+	fmt.Fprintf(w, "uso: april %s %s\n\n%s.\n", cmd.name, cmd.args, strings.ToUpper(cmd.short[:1])+cmd.short[1:])
+	hasFlags := false
+	flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(w, "\nopciones:\n")
+		flags.PrintDefaults()
+	}
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+func runFile(c *CLI, flags *flag.FlagSet, args []string) int {
+	backend := flags.String("backend", file.Eval, "backend que ejecuta el archivo: 'eval' o 'vm'")
+	root := flags.String("root", "", "directorio fuera del cual el programa no puede usar archivos")
+	readOnly := flags.Bool("readonly", false, "el programa puede leer archivos pero no crearlos, escribirlos ni borrarlos")
+	noFiles := flags.Bool("nofiles", false, "el programa no puede usar archivos ni importarlos, salvo la biblioteca estandar")
+	if status, ok := parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	if *backend != file.Eval && *backend != file.VM {
+		fmt.Fprintf(c.Stderr, "unknown backend '%s', expected '%s' or '%s'.\n", *backend, file.Eval, file.VM)
+		return ExitUsage
+	}
+	fs := &object.FileSystem{Root: *root, ReadOnly: *readOnly, Disabled: *noFiles}
+	return file.Start(c.Stdout, c.Stdin, flags.Arg(0), file.Options{Backend: *backend, FS: fs, Args: flags.Args()[1:], Stderr: c.Stderr})
+}
+
+//runRepl inicia la consola. Si la entrada no es una terminal ejecuta las lineas que
+//recibe sin prompt y sale con el primer error.
+func runRepl(c *CLI, flags *flag.FlagSet, args []string) int {
+	quiet := flags.Bool("quiet", false, "no limpia la pantalla ni muestra el saludo")
+	if status, ok := parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+	return repl.Start(c.Stdout, c.Stdin, repl.Options{Version: c.Version, Quiet: *quiet, Stderr: c.Stderr})
+}
+
+//runCheck verifica todos los archivos y muestra los errores de cada uno.
+func runCheck(c *CLI, flags *flag.FlagSet, args []string) int {
+	if status, ok := parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	status := ExitOK
+	for _, path := range flags.Args() {
+		sources := diag.Sources{}
+		if _, err := file.Check(path, sources, nil); err != nil {
+			printError(c.Stderr, err, sources)
+			status = ExitUsage
+		}
+	}
+	return status
+}
+
+//runFmt da formato a los archivos. Sin opciones muestra el codigo con formato, con -w lo
+//escribe en cada archivo y con -check solo muestra los archivos que no tienen formato.
+func runFmt(c *CLI, flags *flag.FlagSet, args []string) int {
+	write := flags.Bool("w", false, "escribe el resultado en los archivos en lugar de mostrarlo")
+	check := flags.Bool("check", false, "muestra los archivos sin formato, sin modificarlos, y termina con codigo 1 si hay alguno")
+	if status, ok := parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	status := ExitOK
+	for _, path := range flags.Args() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "error to open file: '%s'\n", path)
+			status = ExitUsage
+			continue
+		}
+		source := string(data)
+		formatted, diagnostics := format.Source(path, source)
+		if len(diagnostics) > 0 {
+			printError(c.Stderr, &file.Error{Stage: file.ParseStage, Diagnostics: diagnostics}, diag.Sources{path: source})
+			status = ExitUsage
+			continue
+		}
+
+		switch {
+		case *check:
+			if formatted != source {
+				fmt.Fprintln(c.Stdout, path)
+				if status == ExitOK {
+					status = ExitFailure
+				}
+			}
+		case *write:
+			if formatted == source {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err == nil {
+				err = ioutil.WriteFile(path, []byte(formatted), info.Mode())
+			}
+			if err != nil {
+				fmt.Fprintf(c.Stderr, "error to write file: '%s'\n", path)
+				status = ExitUsage
+			}
+		default:
+			io.WriteString(c.Stdout, formatted)
+		}
+	}
+	return status
+}
+
+//runTokens muestra un token por linea: posicion, tipo y texto.
+func runTokens(c *CLI, flags *flag.FlagSet, args []string) int {
+	path, source, status, ok := c.readSource(flags, args)
+	if !ok {
+		return status
+	}
+
+	l := lexer.NewFile(path, source)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(c.Stdout, "%d:%d\t%s\t%q\n", tok.Pos.Line, tok.Pos.Column, tok.Type, tok.Literal)
+	}
+	if diagnostics := l.Errors(); len(diagnostics) > 0 {
+		printError(c.Stderr, &file.Error{Stage: file.ParseStage, Diagnostics: diagnostics}, diag.Sources{path: source})
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+func runAst(c *CLI, flags *flag.FlagSet, args []string) int {
+	path, source, status, ok := c.readSource(flags, args)
+	if !ok {
+		return status
+	}
+
+	p := parser.New(lexer.NewFile(path, source))
+	program := p.ParserProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		printError(c.Stderr, &file.Error{Stage: file.ParseStage, Diagnostics: diagnostics}, diag.Sources{path: source})
+		return ExitUsage
+	}
+	ast.Fprint(c.Stdout, program)
+	return ExitOK
+}
+
+func runVersion(c *CLI, flags *flag.FlagSet, args []string) int {
+	if flags != nil {
+		if status, ok := parse(flags, args); !ok {
+			return status
+		}
+	}
+	fmt.Fprintf(c.Stdout, "april version %s\n", c.Version)
+	return ExitOK
+}
+
+//readSource lee las opciones y el unico archivo de 'args'. Si no lo puede leer devuelve
+//false y el codigo de salida.
+func (c *CLI) readSource(flags *flag.FlagSet, args []string) (string, string, int, bool) {
+	if status, ok := parse(flags, args); !ok {
+		return "", "", status, false
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", "", ExitUsage, false
+	}
+	path := flags.Arg(0)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "error to open file: '%s'\n", path)
+		return "", "", ExitUsage, false
+	}
+	return path, string(data), ExitOK, true
+}
+
+//printError muestra en 'w' los errores de un archivo, sin el encabezado de la consola.
+func printError(w io.Writer, err error, sources diag.Sources) {
+	checkErr, ok := err.(*file.Error)
+	if !ok {
+		fmt.Fprintln(w, err)
+		return
+	}
+	for _, d := range checkErr.Diagnostics {
+		diag.Render(w, d, sources)
+		io.WriteString(w, "\n")
+	}
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"args.april":          `print(argv());`,
+		"exit.april":          `exit(3);`,
+		"runtime.april":       `print(1 / 0);`,
+		"type.april":          `var x:int = "a";`,
+		"parse.april":         `var x:int = ;`,
+		"sum.april":           `var x:int = 1 + 2;`,
+		"messy.april":         "x:=1+2 // suma",
+		"write.april":         "fn f( ){return 1}",
+		"formatted.april":     "var x:int = 1;\n",
+		"tests/ok_test.april": `fn TestOk() { } fn TestAlsoOk() { }`,
+		"tests/fail_test.april": `fn TestOk() { }
+fn TestFail() { throw "fail"; }
+fn TestArgs(x:int) { throw "not a test"; }`,
+		"tests/helper.april":   `fn TestNotRun() { throw "not a test file"; }`,
+		"secret.april":         `global Secret:int = 42;`,
+		"sandbox/import.april": "import \"../secret.april\" as s\nprint(s.Secret);",
+		"sandbox/legacy.april": "import \"../secret.april\"\nprint(Secret);",
+		"sandbox/local.april":  "import \"helper.april\" as h\nimport \"std/math\" as m\nprint(h.Value);",
+		"sandbox/helper.april": `global Value:int = 7;`,
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	tests := []struct {
+		args     []string
+		status   int
+		stdout   string //texto que debe estar en la salida
+		stderr   string //texto que debe estar en la salida de errores
+		notFound string //texto que no debe estar en la salida
+	}{
+		{[]string{"--help"}, ExitOK, "comandos:", "", ""},
+		{[]string{"help", "run"}, ExitOK, "-backend", "", ""},
+		{[]string{"run", "-h"}, ExitOK, "", "uso: april run", ""},
+		{[]string{"version"}, ExitOK, "april version 1.2.3", "", ""},
+		{[]string{"--version"}, ExitOK, "april version 1.2.3", "", ""},
+		{[]string{"nope"}, ExitUsage, "", "unknown command 'nope'", ""},
+		{[]string{"run"}, ExitUsage, "", "uso: april run", ""},
+		{[]string{"run", "-bogus", path("sum.april")}, ExitUsage, "", "-bogus", ""},
+		{[]string{"run", "-backend=jit", path("sum.april")}, ExitUsage, "", "unknown backend 'jit'", ""},
+		{[]string{"run", path("args.april"), "a", "b c"}, ExitOK, "['a', 'b c']", "", ""},
+		{[]string{"run", "-backend=vm", path("args.april"), "a"}, ExitOK, "['a']", "", ""},
+		{[]string{path("args.april")}, ExitOK, "[]", "", ""},
+		{[]string{"-backend=vm", path("args.april"), "x"}, ExitOK, "['x']", "", ""},
+		{[]string{"run", path("exit.april")}, 3, "", "", ""},
+		{[]string{"run", path("runtime.april")}, ExitFailure, "", "division by zero", "division by zero"},
+		{[]string{"run", path("type.april")}, ExitUsage, "", "declaration error", "declaration error"},
+		{[]string{"run", path("missing.april")}, ExitUsage, "", "error to open file", "error to open file"},
+		{[]string{"run", path("sandbox/import.april")}, ExitOK, "42", "", ""},
+		{[]string{"run", "-root=" + path("sandbox"), path("sandbox/import.april")}, ExitFailure, "", "module can not be imported: '../secret.april'", ""},
+		{[]string{"run", "-backend=vm", "-root=" + path("sandbox"), "-nofiles", path("sandbox/import.april")}, ExitFailure, "", "module can not be imported: '../secret.april'", ""},
+		{[]string{"run", "-root=" + path("sandbox"), path("sandbox/legacy.april")}, ExitUsage, "", "module can not be imported: '../secret.april'", ""},
+		{[]string{"run", "-root=" + path("sandbox"), path("sandbox/local.april")}, ExitOK, "7", "", ""},
+		{[]string{"check", path("sum.april")}, ExitOK, "", "", ""},
+		{[]string{"check", path("sum.april"), path("type.april")}, ExitUsage, "", "declaration error", ""},
+		{[]string{"check", path("parse.april")}, ExitUsage, "", "parse.april:1:13", ""},
+		{[]string{"tokens", path("sum.april")}, ExitOK, "1:13\tINT\t\"1\"", "", ""},
+		{[]string{"ast", path("sum.april")}, ExitOK, "VarStatement 1:1\n      Name: Identifier 1:5", "", ""},
+		{[]string{"ast", path("parse.april")}, ExitUsage, "", "parse.april:1:13", ""},
+		{[]string{"fmt", path("messy.april")}, ExitOK, "x := 1 + 2; // suma\n", "", ""},
+		{[]string{"fmt", "-check", path("formatted.april"), path("messy.april")}, ExitFailure, path("messy.april"), "", "formatted"},
+		{[]string{"fmt", "--check", path("formatted.april")}, ExitOK, "", "", "formatted"},
+		{[]string{"fmt", "-w", path("write.april")}, ExitOK, "", "", "fn"},
+		{[]string{"fmt", "-check", path("write.april")}, ExitOK, "", "", "write"},
+		{[]string{"fmt", path("parse.april")}, ExitUsage, "", "parse.april:1:13", ""},
+		{[]string{"fmt"}, ExitUsage, "", "uso: april fmt", ""},
+		{[]string{"test", path("tests/ok_test.april")}, ExitOK, "ok\t" + path("tests/ok_test.april") + "\t2 tests", "", ""},
+		{[]string{"test", path("tests")}, ExitFailure, "--- FAIL: TestFail", "", "TestNotRun"},
+		{[]string{"test", "-v", path("tests")}, ExitFailure, "--- PASS: TestAlsoOk", "", "not a test"},
+		{[]string{"test", path("tests/fail_test.april")}, ExitFailure, "in function 'TestFail' called at " + path("tests/fail_test.april") + ":2:4", "", "called at -"},
+		{[]string{"test", path("tests/missing")}, ExitUsage, "", "error to open file", ""},
+		{[]string{"repl", "-quiet"}, ExitOK, "", "", "Hello"},
+		{[]string{"repl", path("sum.april")}, ExitUsage, "", "uso: april repl", ""},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		c := &CLI{Version: "1.2.3", Stdout: &stdout, Stderr: &stderr, Stdin: strings.NewReader("")}
+		status := c.Run(tt.args)
+		if status != tt.status {
+			t.Fatalf("april %v: status is not %d. got=%d\nstdout: %s\nstderr: %s", tt.args, tt.status, status, stdout.String(), stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tt.stdout) {
+			t.Fatalf("april %v: stdout does not contain %q. got=%q", tt.args, tt.stdout, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) {
+			t.Fatalf("april %v: stderr does not contain %q. got=%q", tt.args, tt.stderr, stderr.String())
+		}
+		if tt.notFound != "" && strings.Contains(stdout.String(), tt.notFound) {
+			t.Fatalf("april %v: stdout contains %q. got=%q", tt.args, tt.notFound, stdout.String())
+		}
+	}
+}
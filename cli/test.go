@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/file"
+	"github.com/kenshindeveloper/april/module"
+	"github.com/kenshindeveloper/april/object"
+)
+
+//runTest ejecuta los archivos _test.april de los directorios de 'args', o del directorio
+//actual, y los archivos .april que se nombran. De cada archivo ejecuta el programa y despues
+//sus funciones sin parametros cuyo nombre empieza con Test. Un test falla si termina con un
+//error, por ejemplo un throw que no se atrapa.
+func runTest(c *CLI, flags *flag.FlagSet, args []string) int {
+	verbose := flags.Bool("v", false, "muestra tambien los tests que pasan")
+	if status, ok := parse(flags, args); !ok {
+		return status
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := testFiles(paths)
+	if err != nil {
+		fmt.Fprintln(c.Stderr, err)
+		return ExitUsage
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(c.Stdout, "no test files")
+		return ExitOK
+	}
+
+	status := ExitOK
+	for _, path := range files {
+		if !c.testFile(path, *verbose) {
+			status = ExitFailure
+		}
+	}
+	return status
+}
+
+//testFiles devuelve los archivos que se nombran en 'paths' y los _test.april que estan
+//dentro de los directorios, en orden alfabetico.
+func testFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error to open file: '%s'", path)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(name, "_test.april") {
+				files = append(files, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+//testFile ejecuta los tests del archivo 'path' y devuelve false si alguno fallo.
+func (c *CLI) testFile(path string, verbose bool) bool {
+	sources := diag.Sources{}
+	program, err := file.Check(path, sources, nil)
+	if err != nil {
+		fmt.Fprintf(c.Stdout, "FAIL\t%s\n", path)
+		printError(c.Stdout, err, sources)
+		return false
+	}
+
+	loader := module.New(sources)
+	loader.Main(path)
+	env := object.NewEnvironment()
+	env.Runtime().IO = object.NewIO(c.Stdout, c.Stderr, c.Stdin)
+	env.Runtime().Importer = loader
+	if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		fmt.Fprintf(c.Stdout, "FAIL\t%s\n", path)
+		diag.Render(c.Stdout, err.Diagnostic(), sources)
+		return false
+	}
+
+	tests, failed := 0, 0
+	for _, stmt := range program.Statements {
+		fn, ok := stmt.(*ast.Function)
+		if !ok || !strings.HasPrefix(fn.Name.Name, "Test") || len(fn.Parameters) > 0 {
+			continue
+		}
+		value, _ := env.GetGlobal(fn.Name.Name)
+
+		tests++
+		//la traza de un test que falla termina en su declaracion.
+		if err, ok := evaluator.Call(env.Runtime(), value, nil, fn.Name.Pos()).(*object.Error); ok {
+			failed++
+			fmt.Fprintf(c.Stdout, "--- FAIL: %s\n", fn.Name.Name)
+			diag.Render(c.Stdout, err.Diagnostic(), sources)
+		} else if verbose {
+			fmt.Fprintf(c.Stdout, "--- PASS: %s\n", fn.Name.Name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(c.Stdout, "FAIL\t%s\t%d of %d tests failed\n", path, failed, tests)
+		return false
+	}
+	fmt.Fprintf(c.Stdout, "ok\t%s\t%d tests\n", path, tests)
+	return true
+}
@@ -0,0 +1,280 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/kenshindeveloper/april/diag"
+)
+
+//Instructions es una secuencia de instrucciones codificadas: un byte con el Opcode
+//seguido de sus operandos en big endian.
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpPop
+	OpDup
+	OpTrue
+	OpFalse
+	OpNil
+
+	OpInfix
+	OpPrefix
+	OpAnd
+	OpIncrement
+
+	OpJump
+	OpJumpNotTruthy
+
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetOuter
+	OpSetOuter
+	OpGetBuiltin
+	OpNotFound
+
+	OpDeclare
+	OpImplicit
+	OpAssign
+	OpCheckList
+	OpLen
+
+	OpList
+	OpHash
+	OpIndex
+	OpSetIndex
+	OpStruct
+	OpCheckStruct
+	OpGetField
+	OpSetField
+
+	OpCall
+	OpReturnValue
+	OpReturn
+	OpClosure
+	OpEnterScope
+	OpLeaveScope
+
+	OpTry
+	OpEndTry
+	OpCaught
+	OpThrow
+
+	OpImport
+)
+
+//Definition describe una instruccion: su nombre y el ancho en bytes de cada operando.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpPop:      {"OpPop", []int{}},
+	OpDup:      {"OpDup", []int{}},
+	OpTrue:     {"OpTrue", []int{}},
+	OpFalse:    {"OpFalse", []int{}},
+	OpNil:      {"OpNil", []int{}},
+
+	OpInfix:     {"OpInfix", []int{1}},
+	OpPrefix:    {"OpPrefix", []int{1}},
+	OpAnd:       {"OpAnd", []int{2}},
+	OpIncrement: {"OpIncrement", []int{1}},
+
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+
+	OpGetGlobal:  {"OpGetGlobal", []int{2}},
+	OpSetGlobal:  {"OpSetGlobal", []int{2}},
+	OpGetLocal:   {"OpGetLocal", []int{1}},
+	OpSetLocal:   {"OpSetLocal", []int{1}},
+	OpGetOuter:   {"OpGetOuter", []int{1, 1}},
+	OpSetOuter:   {"OpSetOuter", []int{1, 1}},
+	OpGetBuiltin: {"OpGetBuiltin", []int{2}},
+	OpNotFound:   {"OpNotFound", []int{2}},
+
+	OpDeclare:   {"OpDeclare", []int{2, 2}},
+	OpImplicit:  {"OpImplicit", []int{2}},
+	OpAssign:    {"OpAssign", []int{}},
+	OpCheckList: {"OpCheckList", []int{}},
+	OpLen:       {"OpLen", []int{}},
+
+	OpList:        {"OpList", []int{2}},
+	OpHash:        {"OpHash", []int{2}},
+	OpIndex:       {"OpIndex", []int{}},
+	OpSetIndex:    {"OpSetIndex", []int{}},
+	OpStruct:      {"OpStruct", []int{2}},
+	OpCheckStruct: {"OpCheckStruct", []int{2}},
+	OpGetField:    {"OpGetField", []int{2}},
+	OpSetField:    {"OpSetField", []int{2}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+	OpClosure:     {"OpClosure", []int{2}},
+	OpEnterScope:  {"OpEnterScope", []int{2}},
+	OpLeaveScope:  {"OpLeaveScope", []int{}},
+
+	OpTry:    {"OpTry", []int{2}},
+	OpEndTry: {"OpEndTry", []int{}},
+	OpCaught: {"OpCaught", []int{}},
+	OpThrow:  {"OpThrow", []int{}},
+
+	OpImport: {"OpImport", []int{2, 2}},
+}
+
+//Operators son los operadores que reciben OpInfix y OpPrefix, el operando de la
+//instruccion es la posicion del operador en la lista.
+var Operators = []string{"+", "-", "*", "/", "%", "<", ">", "<=", ">=", "==", "!=", "and", "or", "not"}
+
+//OperatorIndex devuelve la posicion de 'operator' en Operators o -1 si no existe.
+func OperatorIndex(operator string) int {
+	for i, op := range Operators {
+		if op == operator {
+			return i
+		}
+	}
+	return -1
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+//Make codifica la instruccion 'op' con sus operandos.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += def.OperandWidths[i]
+	}
+
+	return instruction
+}
+
+//ReadOperands decodifica los operandos de una instruccion 'def' y devuelve la cantidad
+//de bytes leidos.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
+//String devuelve las instrucciones desensambladas, una por linea.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), len(def.OperandWidths))
+	}
+
+	switch len(operands) {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operand count for %s\n", def.Name)
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//Mapping relaciona la instruccion que empieza en Offset con el codigo fuente que la produjo.
+type Mapping struct {
+	Offset int
+	Span   diag.Span
+}
+
+//SourceMap guarda las posiciones de las instrucciones ordenadas por Offset.
+type SourceMap []Mapping
+
+//Add registra la posicion 'span' para la instruccion en 'offset'.
+func (sm *SourceMap) Add(offset int, span diag.Span) {
+	if n := len(*sm); n > 0 && (*sm)[n-1].Offset == offset {
+		(*sm)[n-1].Span = span
+		return
+	}
+	*sm = append(*sm, Mapping{Offset: offset, Span: span})
+}
+
+//Lookup devuelve la posicion de la instruccion que contiene 'offset'.
+func (sm SourceMap) Lookup(offset int) diag.Span {
+	i := sort.Search(len(sm), func(i int) bool { return sm[i].Offset > offset })
+	if i == 0 {
+		return diag.Span{}
+	}
+	return sm[i-1].Span
+}
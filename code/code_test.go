@@ -0,0 +1,65 @@
+package code
+
+import (
+	"testing"
+
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/token"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpGetOuter, []int{1, 2}, []byte{byte(OpGetOuter), 1, 2}},
+		{OpPop, []int{}, []byte{byte(OpPop)}},
+	}
+
+	for i, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+		if string(instruction) != string(tt.expected) {
+			t.Errorf("tests[%d] - instruction is not %v. got=%v", i, tt.expected, instruction)
+		}
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := Instructions{}
+	for _, ins := range [][]byte{Make(OpConstant, 1), Make(OpGetOuter, 1, 3), Make(OpInfix, OperatorIndex("+")), Make(OpPop)} {
+		instructions = append(instructions, ins...)
+	}
+
+	expected := "0000 OpConstant 1\n0003 OpGetOuter 1 3\n0006 OpInfix 0\n0008 OpPop\n"
+	if instructions.String() != expected {
+		t.Fatalf("instructions are not %q. got=%q", expected, instructions.String())
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	first := diag.SpanAt(token.Position{Line: 1, Column: 1}, 1)
+	second := diag.SpanAt(token.Position{Line: 2, Column: 5}, 1)
+
+	var sm SourceMap
+	sm.Add(0, first)
+	sm.Add(3, second)
+
+	tests := []struct {
+		offset   int
+		expected diag.Span
+	}{
+		{0, first},
+		{2, first},
+		{3, second},
+		{10, second},
+	}
+
+	for i, tt := range tests {
+		if span := sm.Lookup(tt.offset); span != tt.expected {
+			t.Errorf("tests[%d] - Lookup(%d) is not %v. got=%v", i, tt.offset, tt.expected, span)
+		}
+	}
+}
@@ -0,0 +1,822 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/code"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/object"
+)
+
+//Bytecode es el resultado de compilar un programa: la funcion principal, la tabla de
+//constantes y los nombres de las variables globales.
+type Bytecode struct {
+	Main        *object.CompiledFunction
+	Constants   []object.Object
+	GlobalNames []string
+}
+
+//StructLiteral es la constante de una expresion struct, la vm crea un struct nuevo
+//cada vez que la ejecuta.
+type StructLiteral struct {
+	Node *ast.Struct
+}
+
+func (sl *StructLiteral) Type() object.ObjectType {
+	return "STRUCT_LITERAL"
+}
+
+func (sl *StructLiteral) Inspect() string {
+	return sl.Node.String()
+}
+
+//LoopScope es la constante de un OpEnterScope: los nombres de las variables del cuerpo
+//de un for, la vm crea un Scope nuevo para ellas en cada vuelta.
+type LoopScope struct {
+	Names []string
+}
+
+func (ls *LoopScope) Type() object.ObjectType {
+	return "LOOP_SCOPE"
+}
+
+func (ls *LoopScope) Inspect() string {
+	return fmt.Sprintf("scope(%d)", len(ls.Names))
+}
+
+//tryBlock es un bloque protegido por un OpTry. Un break o un return que sale del bloque
+//quita el manejador con OpEndTry y ejecuta el finally antes de saltar.
+type tryBlock struct {
+	handler bool
+	finally *ast.BlockStatement
+}
+
+//loop guarda los saltos de los break de un for, que se corrigen al terminar el for.
+//Un break cierra los Scope abiertos desde 'level'.
+type loop struct {
+	breaks []int
+	tries  int
+	level  int
+}
+
+//scope es la funcion que se esta compilando.
+type scope struct {
+	instructions code.Instructions
+	sourceMap    code.SourceMap
+	loops        []*loop
+	tries        []tryBlock
+}
+
+type Compiler struct {
+	constants []object.Object
+	symbols   *SymbolTable
+	scopes    []*scope
+	span      diag.Span
+	topLevel  bool
+	errors    []*diag.Diagnostic
+}
+
+func New() *Compiler {
+	return &Compiler{symbols: NewSymbolTable(), scopes: []*scope{{}}}
+}
+
+//Compile traduce 'program' a bytecode y devuelve los errores encontrados.
+func (c *Compiler) Compile(program *ast.Program) []*diag.Diagnostic {
+	c.hoist(program.Statements)
+	c.predeclare(program.Statements)
+
+	for _, stmt := range program.Statements {
+		c.topLevel = true
+		c.statement(stmt)
+	}
+	c.topLevel = false
+
+	//el resultado del programa es el de la ultima sentencia, como en el evaluador.
+	if n := len(program.Statements); n == 0 {
+		c.emit(code.OpNil)
+		c.emit(code.OpPop)
+	} else if _, ok := program.Statements[n-1].(*ast.ExpressionStatement); !ok {
+		c.emit(code.OpNil)
+		c.emit(code.OpPop)
+	}
+	c.emit(code.OpReturn)
+
+	return c.errors
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	main := &object.CompiledFunction{
+		Name:         "main",
+		Instructions: c.currentScope().instructions,
+		SourceMap:    c.currentScope().sourceMap,
+	}
+	return &Bytecode{Main: main, Constants: c.constants, GlobalNames: c.symbols.globals.names}
+}
+
+//hoist declara las funciones y las variables 'global' antes de compilar, una funcion
+//puede llamar a otra que se declara despues.
+func (c *Compiler) hoist(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.Function:
+			c.symbols.DefineShared(stmt.Name.Name)
+			c.hoist(stmt.Body.Statements)
+		case *ast.GlobalStatement:
+			c.symbols.DefineShared(stmt.Name.Name)
+		case *ast.ImportStatement:
+			if stmt.Name != nil {
+				c.symbols.DefineShared(stmt.Name.Name)
+			}
+		case *ast.ForStatement:
+			c.hoist(stmt.Body.Statements)
+		case *ast.TryStatement:
+			for _, block := range []*ast.BlockStatement{stmt.Block, stmt.Catch, stmt.Finally} {
+				if block != nil {
+					c.hoist(block.Statements)
+				}
+			}
+		case *ast.ExpressionStatement:
+			if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+				c.hoist(ie.Consequence.Statements)
+				if ie.Alternative != nil {
+					c.hoist(ie.Alternative.Statements)
+				}
+			}
+		}
+	}
+}
+
+//predeclare reserva las variables que se declaran en el bloque actual.
+func (c *Compiler) predeclare(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.VarStatement:
+			c.symbols.Predeclare(stmt.Name.Name)
+		case *ast.ExpressionStatement:
+			if decl, ok := stmt.Expression.(*ast.ImplicitDeclarationExpression); ok {
+				c.symbols.Predeclare(decl.Left.Name)
+			}
+		}
+	}
+}
+
+//***************************************************************************************
+//***************************************STATEMENTS**************************************
+//***************************************************************************************
+
+func (c *Compiler) statement(stmt ast.Statement) {
+	prev := c.span
+	c.span = spanOf(stmt)
+	defer func() { c.span = prev }()
+
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		c.expression(stmt.Expression)
+		c.emit(code.OpPop)
+
+	case *ast.VarStatement:
+		c.expression(stmt.Value)
+		c.emit(code.OpDeclare, c.addConstant(&object.String{Value: stmt.Name.Name}), c.addConstant(&object.String{Value: stmt.Type.Name}))
+		if symbol, ok := c.define(stmt, stmt.Name.Name); ok {
+			c.storeSymbol(symbol)
+		}
+
+	case *ast.GlobalStatement:
+		c.expression(stmt.Value)
+		c.emit(code.OpDeclare, c.addConstant(&object.String{Value: stmt.Name.Name}), c.addConstant(&object.String{Value: stmt.Type.Name}))
+		c.storeSymbol(c.symbols.DefineShared(stmt.Name.Name))
+
+	case *ast.ReturnStatement:
+		c.expression(stmt.Expression)
+		c.leaveTries(0)
+		c.emit(code.OpReturnValue)
+
+	case *ast.BreakStatement:
+		//fuera de un for el break no tiene efecto, igual que en el evaluador.
+		if loops := c.currentScope().loops; len(loops) > 0 {
+			loop := loops[len(loops)-1]
+			c.leaveTries(loop.tries)
+			for i := c.symbols.Level(); i > loop.level; i-- {
+				c.emit(code.OpLeaveScope)
+			}
+			loop.breaks = append(loop.breaks, c.emit(code.OpJump, 9999))
+		}
+
+	case *ast.ThrowStatement:
+		c.expression(stmt.Expression)
+		c.emit(code.OpThrow)
+
+	case *ast.TryStatement:
+		c.tryStatement(stmt)
+
+	case *ast.ForStatement:
+		c.forStatement(stmt)
+
+	case *ast.Function:
+		c.functionStatement(stmt)
+
+	case *ast.ImportStatement:
+		//sin 'as' el lexer ya incorporo el codigo del archivo importado.
+		if stmt.Name != nil {
+			path := c.addConstant(&object.String{Value: stmt.Path.Value})
+			from := c.addConstant(&object.String{Value: stmt.Token.Pos.File})
+			c.span = spanOf(stmt.Path)
+			c.emit(code.OpImport, path, from)
+			c.storeSymbol(c.symbols.DefineShared(stmt.Name.Name))
+		}
+
+	default:
+		c.errorf(stmt, diag.RuntimeError, "statement %T is not supported by the compiler", stmt)
+	}
+}
+
+func (c *Compiler) functionStatement(node *ast.Function) {
+	if !c.topLevel {
+		c.errorf(node, diag.AlreadyDeclared, "name function '%s' cannot declared in this scope.", node.Name.Name)
+		return
+	}
+
+	if _, inBuilt := evaluator.Builtin(node.Name.Name); inBuilt || c.symbols.declaredVariable(node.Name.Name) {
+		c.errorf(node, diag.AlreadyDeclared, "name function '%s' already exist.", node.Name.Name)
+		return
+	}
+
+	for _, param := range node.Parameters {
+		_, inBuilt := evaluator.Builtin(param.Name.Name)
+		if inBuilt || c.symbols.Declared(param.Name.Name) {
+			c.errorf(node, diag.AlreadyDeclared, "name variable '%s' already exist.", param.Name.Name)
+			return
+		}
+	}
+
+	fn := c.function(node.Name.Name, node.Parameters, node.Type, node.Body, false)
+	fn.Span = spanOf(node.Name)
+	c.emit(code.OpClosure, c.addConstant(fn))
+	c.storeSymbol(c.symbols.DefineShared(node.Name.Name))
+}
+
+//block compila las sentencias de 'block' en un ambito nuevo.
+func (c *Compiler) block(block *ast.BlockStatement) {
+	c.symbols.EnterBlock()
+	c.predeclare(block.Statements)
+	c.statements(block.Statements)
+	c.symbols.LeaveBlock()
+}
+
+func (c *Compiler) statements(statements []ast.Statement) {
+	topLevel := c.topLevel
+	c.topLevel = false
+	for _, stmt := range statements {
+		c.statement(stmt)
+	}
+	c.topLevel = topLevel
+}
+
+//forStatement compila los dos tipos de for: 'for (x := lista)' recorre una lista con
+//dos variables ocultas para la lista y la posicion, el resto es un while con declaracion
+//y operacion opcionales.
+func (c *Compiler) forStatement(node *ast.ForStatement) {
+	c.symbols.EnterBlock()
+	defer c.symbols.LeaveBlock()
+
+	var start, exit int
+	var index Symbol
+
+	if impl, ok := node.Condition.(*ast.ImplicitDeclarationExpression); ok {
+		if node.Declaration != nil || node.Operation != nil {
+			c.errorf(node, diag.RuntimeError, "for declaration is incorrect.")
+			return
+		}
+
+		switch impl.Right.(type) {
+		case *ast.CallExpression, *ast.List, *ast.Identifier:
+		default:
+			c.errorf(node, diag.TypeMismatch, "expression incompatible with for, expression must be type list. %T", impl.Right)
+			return
+		}
+
+		list, _ := c.symbols.Define("(list)")
+		index, _ = c.symbols.Define("(index)")
+		element, _ := c.symbols.Define(impl.Left.Name)
+
+		c.expression(impl.Right)
+		c.emit(code.OpCheckList)
+		c.storeSymbol(list)
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 0}))
+		c.storeSymbol(index)
+
+		start = len(c.currentScope().instructions)
+		c.loadSymbol(index)
+		c.loadSymbol(list)
+		c.emit(code.OpLen)
+		c.emit(code.OpInfix, code.OperatorIndex("<"))
+		exit = c.emit(code.OpJumpNotTruthy, 9999)
+
+		c.loadSymbol(list)
+		c.loadSymbol(index)
+		c.emit(code.OpIndex)
+		c.storeSymbol(element)
+	} else {
+		if node.Declaration != nil {
+			c.expression(node.Declaration)
+			c.emit(code.OpPop)
+		}
+
+		start = len(c.currentScope().instructions)
+		c.expression(node.Condition)
+		exit = c.emit(code.OpJumpNotTruthy, 9999)
+	}
+
+	current := c.currentScope()
+	current.loops = append(current.loops, &loop{tries: len(current.tries), level: c.symbols.Level()})
+	c.loopBody(node.Body)
+	loop := current.loops[len(current.loops)-1]
+	current.loops = current.loops[:len(current.loops)-1]
+
+	if node.Operation != nil {
+		c.expression(node.Operation)
+		c.emit(code.OpPop)
+	} else if index.Name != "" {
+		c.loadSymbol(index)
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 1}))
+		c.emit(code.OpInfix, code.OperatorIndex("+"))
+		c.storeSymbol(index)
+	}
+	c.emit(code.OpJump, start)
+
+	end := len(current.instructions)
+	c.changeOperand(exit, end)
+	for _, pos := range loop.breaks {
+		c.changeOperand(pos, end)
+	}
+}
+
+//loopBody compila el cuerpo de un for. Como el evaluador usa un ambiente nuevo en cada
+//vuelta, las variables del cuerpo se guardan en un Scope que se crea en cada vuelta y
+//un closure creado en una vuelta no ve las variables de la siguiente.
+func (c *Compiler) loopBody(body *ast.BlockStatement) {
+	scope := &LoopScope{}
+	c.emit(code.OpEnterScope, c.addConstant(scope))
+	c.symbols.EnterScope()
+	c.predeclare(body.Statements)
+	c.statements(body.Statements)
+	scope.Names = c.symbols.LeaveScope()
+	c.emit(code.OpLeaveScope)
+
+	if len(scope.Names) > 256 {
+		c.errorf(body, diag.RuntimeError, "too many local variables in for, max 256")
+	}
+}
+
+//tryStatement compila try/catch/finally. Si el try falla la vm salta al catch con el
+//error en la pila. El finally se copia en cada salida: al terminar normalmente, antes
+//de volver a lanzar un error que no se atrapo y en cada break o return del bloque.
+func (c *Compiler) tryStatement(node *ast.TryStatement) {
+	current := c.currentScope()
+	var exits []int
+
+	handler := c.emit(code.OpTry, 9999)
+	current.tries = append(current.tries, tryBlock{handler: true, finally: node.Finally})
+	c.block(node.Block)
+	current.tries = current.tries[:len(current.tries)-1]
+	c.emit(code.OpEndTry)
+	exits = append(exits, c.emit(code.OpJump, 9999))
+
+	c.changeOperand(handler, len(current.instructions))
+	if node.Catch != nil {
+		c.symbols.EnterBlock()
+		param, _ := c.symbols.Define(node.Parameter.Name)
+		c.emit(code.OpCaught)
+		c.storeSymbol(param)
+
+		if node.Finally != nil {
+			handler = c.emit(code.OpTry, 9999)
+			current.tries = append(current.tries, tryBlock{handler: true, finally: node.Finally})
+		}
+		c.block(node.Catch)
+		if node.Finally != nil {
+			current.tries = current.tries[:len(current.tries)-1]
+			c.emit(code.OpEndTry)
+		}
+		c.symbols.LeaveBlock()
+		exits = append(exits, c.emit(code.OpJump, 9999))
+
+		if node.Finally != nil {
+			c.changeOperand(handler, len(current.instructions))
+		}
+	}
+
+	if node.Finally != nil {
+		c.symbols.EnterBlock()
+		err, _ := c.symbols.Define("(error)")
+		c.storeSymbol(err)
+		c.block(node.Finally)
+		c.loadSymbol(err)
+		c.emit(code.OpThrow)
+		c.symbols.LeaveBlock()
+	} else {
+		//sin catch ni finally el parser ya informo el error.
+		c.emit(code.OpThrow)
+	}
+
+	for _, pos := range exits {
+		c.changeOperand(pos, len(current.instructions))
+	}
+	if node.Finally != nil {
+		c.block(node.Finally)
+	}
+}
+
+//leaveTries sale de los bloques try abiertos desde 'depth', quitando sus manejadores y
+//ejecutando sus finally del mas interno al mas externo.
+func (c *Compiler) leaveTries(depth int) {
+	current := c.currentScope()
+	tries := current.tries
+
+	for i := len(tries) - 1; i >= depth; i-- {
+		if tries[i].handler {
+			c.emit(code.OpEndTry)
+		}
+		if tries[i].finally != nil {
+			current.tries = tries[:i]
+			c.block(tries[i].finally)
+		}
+	}
+	current.tries = tries
+}
+
+//***************************************************************************************
+//************************************EXPRESSIONS****************************************
+//***************************************************************************************
+
+func (c *Compiler) expression(node ast.Expression) {
+	prev := c.span
+	c.span = spanOf(node)
+	defer func() { c.span = prev }()
+
+	switch node := node.(type) {
+	case *ast.Integer:
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: node.Value}))
+
+	case *ast.Double:
+		c.emit(code.OpConstant, c.addConstant(&object.Double{Value: node.Value}))
+
+	case *ast.String:
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: node.Value}))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.Nil:
+		c.emit(code.OpConstant, c.addConstant(&object.Nil{}))
+
+	case *ast.Identifier:
+		c.identifier(node.Name, "identifier not found: %s")
+
+	case *ast.PrefixExpression:
+		c.expression(node.Right)
+		c.operator(node, node.Operator, code.OpPrefix)
+
+	case *ast.InfixExpression:
+		c.infixExpression(node)
+
+	case *ast.PostfixExpression:
+		symbol, ok := c.resolve(node.Left.Name, "variable '%s' not exist.")
+		if !ok {
+			return
+		}
+		c.loadSymbol(symbol)
+		if node.Operator == "++" {
+			c.emit(code.OpIncrement, 0)
+		} else {
+			c.emit(code.OpIncrement, 1)
+		}
+		c.emit(code.OpDup)
+		c.storeSymbol(symbol)
+
+	case *ast.ImplicitDeclarationExpression:
+		c.expression(node.Right)
+		c.emit(code.OpImplicit, c.addConstant(&object.String{Value: node.Left.Name}))
+		if symbol, ok := c.define(node, node.Left.Name); ok {
+			c.storeSymbol(symbol)
+		}
+		c.emit(code.OpNil)
+
+	case *ast.AssignExpression:
+		c.assignExpression(node)
+
+	case *ast.AssignOperationExpression:
+		symbol, ok := c.resolve(node.Left.Name, "variable '%s' not exist.")
+		if !ok {
+			return
+		}
+		c.loadSymbol(symbol)
+		c.expression(node.Right)
+		c.operator(node, string(node.Operator[0]), code.OpInfix)
+		c.emit(code.OpDup)
+		c.storeSymbol(symbol)
+
+	case *ast.IfExpression:
+		c.expression(node.Codition)
+		jumpNotTruthy := c.emit(code.OpJumpNotTruthy, 9999)
+		c.blockValue(node.Consequence)
+		jump := c.emit(code.OpJump, 9999)
+		c.changeOperand(jumpNotTruthy, len(c.currentScope().instructions))
+		if node.Alternative != nil {
+			c.blockValue(node.Alternative)
+		} else {
+			c.emit(code.OpNil)
+		}
+		c.changeOperand(jump, len(c.currentScope().instructions))
+
+	case *ast.List:
+		for _, element := range node.Elements {
+			c.expression(element)
+		}
+		c.emit(code.OpList, len(node.Elements))
+
+	case *ast.Hash:
+		keys := []ast.Expression{}
+		for key := range node.Pairs {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Pos().Offset < keys[j].Pos().Offset })
+
+		for _, key := range keys {
+			c.expression(key)
+			c.expression(node.Pairs[key])
+		}
+		c.emit(code.OpHash, len(keys)*2)
+
+	case *ast.IndexExpression:
+		c.expression(node.Left)
+		c.expression(node.Index)
+		c.emit(code.OpIndex)
+
+	case *ast.Struct:
+		c.emit(code.OpStruct, c.addConstant(&StructLiteral{Node: node}))
+
+	case *ast.FunctionClosure:
+		fn := c.function("<closure>", node.Parameters, node.Type, node.Body, true)
+		fn.Span = spanOf(node.Body)
+		c.emit(code.OpClosure, c.addConstant(fn))
+
+	case *ast.CallExpression:
+		if len(node.Arguments) > 255 {
+			c.errorf(node, diag.WrongArgumentNum, "too many arguments in call, max 255")
+			return
+		}
+		c.expression(node.Function)
+		for _, arg := range node.Arguments {
+			c.expression(arg)
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+
+	default:
+		c.errorf(node, diag.RuntimeError, "expression %T is not supported by the compiler", node)
+	}
+}
+
+func (c *Compiler) infixExpression(node *ast.InfixExpression) {
+	if node.Operator == "." {
+		ident, ok := node.Right.(*ast.Identifier)
+		if !ok {
+			c.errorf(node, diag.TypeMismatch, "the variables is not identifier. 1")
+			return
+		}
+
+		c.expression(node.Left)
+		c.span = spanOf(node.Left)
+		c.emit(code.OpCheckStruct, c.addConstant(&object.String{Value: node.Left.String()}))
+		c.span = spanOf(ident)
+		c.emit(code.OpGetField, c.addConstant(&object.String{Value: ident.Name}))
+		return
+	}
+
+	c.expression(node.Left)
+
+	//el evaluador no evalua la derecha de 'false and ...' cuando es un booleano o
+	//una expresion binaria.
+	var shortCircuit int = -1
+	if node.Operator == "and" {
+		switch node.Right.(type) {
+		case *ast.Boolean, *ast.InfixExpression:
+			shortCircuit = c.emit(code.OpAnd, 9999)
+		}
+	}
+
+	c.expression(node.Right)
+	c.operator(node, node.Operator, code.OpInfix)
+
+	if shortCircuit >= 0 {
+		c.changeOperand(shortCircuit, len(c.currentScope().instructions))
+	}
+}
+
+func (c *Compiler) assignExpression(node *ast.AssignExpression) {
+	switch left := node.Left.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.resolve(left.Name, "variable '%s' not exist.")
+		if !ok {
+			return
+		}
+		c.expression(node.Right)
+		c.loadSymbol(symbol)
+		c.emit(code.OpAssign)
+		c.storeSymbol(symbol)
+		c.emit(code.OpNil)
+
+	case *ast.IndexExpression:
+		c.expression(left.Left)
+		c.expression(left.Index)
+		c.expression(node.Right)
+		c.emit(code.OpSetIndex)
+
+	case *ast.InfixExpression:
+		ident, ok := left.Right.(*ast.Identifier)
+		if left.Operator != "." || !ok {
+			c.errorf(node, diag.RuntimeError, "expression assignment is not possible.")
+			return
+		}
+		c.expression(left.Left)
+		c.expression(node.Right)
+		c.emit(code.OpSetField, c.addConstant(&object.String{Value: ident.Name}))
+
+	default:
+		c.errorf(node, diag.RuntimeError, "expression assignment is not possible.")
+	}
+}
+
+func (c *Compiler) operator(node ast.Node, operator string, op code.Opcode) {
+	index := code.OperatorIndex(operator)
+	if index < 0 {
+		c.errorf(node, diag.TypeMismatch, "unknown operator: %s", operator)
+		return
+	}
+	c.emit(op, index)
+}
+
+//blockValue compila un bloque de un if, que no abre un ambito nuevo. Deja en la pila
+//el valor de la ultima expresion o nil.
+func (c *Compiler) blockValue(block *ast.BlockStatement) {
+	statements := block.Statements
+	var last *ast.ExpressionStatement
+	if n := len(statements); n > 0 {
+		if stmt, ok := statements[n-1].(*ast.ExpressionStatement); ok {
+			last = stmt
+			statements = statements[:n-1]
+		}
+	}
+
+	c.predeclare(block.Statements)
+	c.statements(statements)
+	if last != nil {
+		c.expression(last.Expression)
+	} else {
+		c.emit(code.OpNil)
+	}
+}
+
+//function compila el cuerpo de una funcion en una tabla de simbolos nueva.
+func (c *Compiler) function(name string, params []*ast.FunctionParameters, ret *ast.Identifier, body *ast.BlockStatement, closure bool) *object.CompiledFunction {
+	c.scopes = append(c.scopes, &scope{})
+	c.symbols = c.symbols.Enclose(closure)
+	topLevel := c.topLevel
+	c.topLevel = false
+
+	for _, param := range params {
+		c.symbols.Define(param.Name.Name)
+	}
+	c.predeclare(body.Statements)
+	c.statements(body.Statements)
+	c.span = spanOf(body)
+	c.emit(code.OpReturn)
+
+	if c.symbols.NumLocals() > 256 {
+		c.errorf(body, diag.RuntimeError, "too many local variables in function '%s', max 256", name)
+	}
+
+	fn := &object.CompiledFunction{
+		Name:         name,
+		Parameters:   params,
+		Return:       ret,
+		Body:         body,
+		Instructions: c.currentScope().instructions,
+		SourceMap:    c.currentScope().sourceMap,
+		NumLocals:    c.symbols.NumLocals(),
+		LocalNames:   c.symbols.names,
+	}
+
+	c.topLevel = topLevel
+	c.symbols = c.symbols.parent
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	return fn
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//define declara la variable 'name' de la declaracion 'node'. Como en el evaluador, no
+//se puede declarar una variable que ya existe en un ambito visible.
+func (c *Compiler) define(node ast.Node, name string) (Symbol, bool) {
+	_, inBuilt := evaluator.Builtin(name)
+	if inBuilt || c.symbols.Declared(name) {
+		c.errorf(node, diag.AlreadyDeclared, "variable '%s' already exist.", name)
+		return Symbol{}, false
+	}
+
+	symbol, ok := c.symbols.Define(name)
+	if !ok {
+		c.errorf(node, diag.AlreadyDeclared, "variable '%s' already exist.", name)
+	}
+	return symbol, ok
+}
+
+//resolve busca la variable 'name', si no existe la vm produce el error 'format' al
+//ejecutar la instruccion.
+func (c *Compiler) resolve(name string, format string) (Symbol, bool) {
+	symbol, ok := c.symbols.Resolve(name)
+	if !ok {
+		c.emit(code.OpNotFound, c.addConstant(&object.String{Value: fmt.Sprintf(format, name)}))
+	}
+	return symbol, ok
+}
+
+func (c *Compiler) identifier(name string, format string) {
+	if symbol, ok := c.symbols.Resolve(name); ok {
+		c.loadSymbol(symbol)
+		return
+	}
+	if _, ok := evaluator.Builtin(name); ok {
+		c.emit(code.OpGetBuiltin, c.addConstant(&object.String{Value: name}))
+		return
+	}
+	c.emit(code.OpNotFound, c.addConstant(&object.String{Value: fmt.Sprintf(format, name)}))
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case OuterScope:
+		c.emit(code.OpGetOuter, s.Depth, s.Index)
+	}
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case OuterScope:
+		c.emit(code.OpSetOuter, s.Depth, s.Index)
+	}
+}
+
+func (c *Compiler) currentScope() *scope {
+	return c.scopes[len(c.scopes)-1]
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+//emit agrega la instruccion al final de la funcion actual con la posicion del nodo que
+//se esta compilando y devuelve su posicion.
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	current := c.currentScope()
+	pos := len(current.instructions)
+	current.instructions = append(current.instructions, code.Make(op, operands...)...)
+	current.sourceMap.Add(pos, c.span)
+	return pos
+}
+
+func (c *Compiler) changeOperand(pos int, operand int) {
+	current := c.currentScope()
+	op := code.Opcode(current.instructions[pos])
+	copy(current.instructions[pos:], code.Make(op, operand))
+}
+
+func (c *Compiler) errorf(node ast.Node, code string, format string, a ...interface{}) {
+	c.errors = append(c.errors, diag.Errorf(code, spanOf(node), format, a...))
+}
+
+func spanOf(node ast.Node) diag.Span {
+	//el token de una llamada es '(', se marca el nombre de la funcion.
+	if call, ok := node.(*ast.CallExpression); ok && call.Function != nil {
+		return spanOf(call.Function)
+	}
+	return diag.SpanAt(node.Pos(), len(node.TokenLiteral()))
+}
@@ -0,0 +1,78 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/parser"
+)
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"var x:int = 1; var x:int = 2;", []string{"1:16 - variable 'x' already exist."}},
+		{"if (true) { fn f() { } }", []string{"1:13 - name function 'f' cannot declared in this scope."}},
+		{"fn len(x:int) { }", []string{"1:1 - name function 'len' already exist."}},
+		{"fn f(len:int) { }", []string{"1:1 - name variable 'len' already exist."}},
+		{"fn f() { } var l:list = [f(), 2];", []string{}},
+	}
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParserProgram()
+		if len(p.Error()) > 0 {
+			t.Fatalf("tests[%d] - parser has errors: %q", i, p.Error())
+		}
+
+		errors := New().Compile(program)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("tests[%d] - len(errors) is not %d. got=%d", i, len(tt.expected), len(errors))
+			continue
+		}
+
+		for j, msg := range tt.expected {
+			if errors[j].Error() != msg {
+				t.Errorf("tests[%d] - errors[%d] is not %q. got=%q", i, j, msg, errors[j].Error())
+			}
+		}
+	}
+}
+
+func TestSymbolTable(t *testing.T) {
+	main := NewSymbolTable()
+	main.DefineShared("fnGlobal")
+	a, _ := main.Define("a")
+
+	fn := main.Enclose(false)
+	x, _ := fn.Define("x")
+	closure := fn.Enclose(true)
+	closure.EnterBlock()
+	y, _ := closure.Define("y")
+
+	tests := []struct {
+		table    *SymbolTable
+		name     string
+		expected Symbol
+		ok       bool
+	}{
+		{main, "a", Symbol{Name: "a", Scope: GlobalScope, Index: a.Index}, true},
+		{fn, "a", Symbol{}, false},
+		{fn, "fnGlobal", Symbol{Name: "fnGlobal", Scope: GlobalScope, Index: 0}, true},
+		{closure, "x", Symbol{Name: "x", Scope: OuterScope, Index: x.Index, Depth: 1}, true},
+		{closure, "y", Symbol{Name: "y", Scope: LocalScope, Index: y.Index}, true},
+	}
+
+	for i, tt := range tests {
+		symbol, ok := tt.table.Resolve(tt.name)
+		if ok != tt.ok {
+			t.Errorf("tests[%d] - Resolve(%q) ok is not %t.", i, tt.name, tt.ok)
+			continue
+		}
+		symbol.declared = false
+		if ok && symbol != tt.expected {
+			t.Errorf("tests[%d] - Resolve(%q) is not %+v. got=%+v", i, tt.name, tt.expected, symbol)
+		}
+	}
+}
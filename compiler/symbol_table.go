@@ -0,0 +1,199 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope  SymbolScope = "LOCAL"
+	OuterScope  SymbolScope = "OUTER"
+)
+
+//Symbol es una variable resuelta: donde se guarda y en que posicion. Depth indica cuantos
+//Scope hay que subir para llegar a una variable OuterScope.
+type Symbol struct {
+	Name     string
+	Scope    SymbolScope
+	Index    int
+	Depth    int
+	level    int
+	declared bool
+}
+
+//globals son las posiciones de las variables globales del programa. Shared guarda las
+//funciones y las variables 'global', que se ven desde cualquier funcion.
+type globals struct {
+	shared map[string]*Symbol
+	names  []string
+}
+
+//SymbolTable resuelve los nombres de una funcion. Cada bloque abre un ambito nuevo, las
+//variables del programa principal se guardan como globales. Solo los closures tienen
+//Outer: una funcion con nombre ve sus variables y las globales compartidas, igual que
+//en el evaluador. El cuerpo de un for guarda sus variables en un Scope propio que se
+//crea en cada vuelta, 'scopes' tiene los nombres de los cuerpos abiertos.
+type SymbolTable struct {
+	Outer   *SymbolTable
+	parent  *SymbolTable
+	globals *globals
+	blocks  []map[string]*Symbol
+	names   []string
+	scopes  [][]string
+	main    bool
+}
+
+//NewSymbolTable crea la tabla del programa principal.
+func NewSymbolTable() *SymbolTable {
+	g := &globals{shared: make(map[string]*Symbol)}
+	return &SymbolTable{globals: g, blocks: []map[string]*Symbol{{}}, main: true}
+}
+
+//Enclose crea la tabla de una funcion declarada dentro de 's'. Solo un closure puede
+//ver las variables de 's'.
+func (s *SymbolTable) Enclose(closure bool) *SymbolTable {
+	table := &SymbolTable{parent: s, globals: s.globals, blocks: []map[string]*Symbol{{}}}
+	if closure {
+		table.Outer = s
+	}
+	return table
+}
+
+func (s *SymbolTable) EnterBlock() {
+	s.blocks = append(s.blocks, map[string]*Symbol{})
+}
+
+func (s *SymbolTable) LeaveBlock() {
+	s.blocks = s.blocks[:len(s.blocks)-1]
+}
+
+//EnterScope abre un bloque cuyas variables, y las de sus bloques internos, se guardan en
+//un Scope nuevo y no en las locales de la funcion.
+func (s *SymbolTable) EnterScope() {
+	s.EnterBlock()
+	s.scopes = append(s.scopes, []string{})
+}
+
+//LeaveScope cierra el bloque abierto por EnterScope y devuelve los nombres de sus
+//variables.
+func (s *SymbolTable) LeaveScope() []string {
+	s.LeaveBlock()
+	names := s.scopes[len(s.scopes)-1]
+	s.scopes = s.scopes[:len(s.scopes)-1]
+	return names
+}
+
+//Level devuelve la cantidad de Scope abiertos con EnterScope.
+func (s *SymbolTable) Level() int {
+	return len(s.scopes)
+}
+
+//NumLocals devuelve la cantidad de variables locales de la funcion.
+func (s *SymbolTable) NumLocals() int {
+	return len(s.names)
+}
+
+//Predeclare reserva 'name' en el bloque actual sin declararla, asi un closure puede usar
+//una variable que se declara despues en su ambito.
+func (s *SymbolTable) Predeclare(name string) *Symbol {
+	block := s.blocks[len(s.blocks)-1]
+	if symbol, ok := block[name]; ok {
+		return symbol
+	}
+
+	symbol := &Symbol{Name: name, level: s.Level()}
+	if level := s.Level(); level > 0 {
+		symbol.Scope = LocalScope
+		symbol.Index = len(s.scopes[level-1])
+		s.scopes[level-1] = append(s.scopes[level-1], name)
+	} else if s.main {
+		symbol.Scope = GlobalScope
+		symbol.Index = len(s.globals.names)
+		s.globals.names = append(s.globals.names, name)
+	} else {
+		symbol.Scope = LocalScope
+		symbol.Index = len(s.names)
+		s.names = append(s.names, name)
+	}
+	block[name] = symbol
+	return symbol
+}
+
+//Define declara 'name' en el bloque actual. Devuelve false si ya estaba declarada en
+//el mismo bloque.
+func (s *SymbolTable) Define(name string) (Symbol, bool) {
+	symbol := s.Predeclare(name)
+	if symbol.declared {
+		return *symbol, false
+	}
+	symbol.declared = true
+	return *symbol, true
+}
+
+//DefineShared declara una funcion o una variable 'global'.
+func (s *SymbolTable) DefineShared(name string) Symbol {
+	if symbol, ok := s.globals.shared[name]; ok {
+		return *symbol
+	}
+
+	symbol := &Symbol{Name: name, Scope: GlobalScope, Index: len(s.globals.names), declared: true}
+	s.globals.names = append(s.globals.names, name)
+	s.globals.shared[name] = symbol
+	return *symbol
+}
+
+//Resolve busca 'name' con las mismas reglas de Environment.Get: primero las globales
+//compartidas y despues los bloques, de adentro hacia afuera. Cada Scope de un for y
+//cada closure que hay entre el uso y la variable suma uno a Depth.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.resolve(name, false)
+	return symbol, ok
+}
+
+//Declared indica si 'name' ya esta declarada en un ambito visible.
+func (s *SymbolTable) Declared(name string) bool {
+	_, ok := s.resolve(name, true)
+	return ok
+}
+
+//declaredVariable indica si 'name' es una variable ya declarada, sin contar las globales
+//compartidas.
+func (s *SymbolTable) declaredVariable(name string) bool {
+	shared := s.globals.shared[name]
+	delete(s.globals.shared, name)
+	_, ok := s.resolve(name, true)
+	if shared != nil {
+		s.globals.shared[name] = shared
+	}
+	return ok
+}
+
+func (s *SymbolTable) resolve(name string, declared bool) (Symbol, bool) {
+	if symbol, ok := s.globals.shared[name]; ok {
+		return *symbol, true
+	}
+
+	depth := 0
+	for table := s; table != nil; table = table.Outer {
+		for i := len(table.blocks) - 1; i >= 0; i-- {
+			symbol, ok := table.blocks[i][name]
+			if !ok || declared && !symbol.declared {
+				continue
+			}
+
+			resolved := *symbol
+			if resolved.Scope == LocalScope {
+				resolved.Depth = depth + table.Level() - symbol.level
+				if resolved.Depth > 0 {
+					resolved.Scope = OuterScope
+				}
+			}
+			return resolved, true
+		}
+
+		if table.main {
+			break
+		}
+		depth += table.Level() + 1
+	}
+
+	return Symbol{}, false
+}
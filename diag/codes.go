@@ -0,0 +1,62 @@
+package diag
+
+//Codigos de error. La primera cifra indica la etapa que produce el diagnostico:
+//0 lexer, 1 parser, 2 evaluador y verificacion de tipos.
+const (
+	IllegalCharacter   = "E0001"
+	UnterminatedString = "E0002"
+
+	UnexpectedToken    = "E0100"
+	MissingExpression  = "E0101"
+	InvalidDeclaration = "E0102"
+	InvalidLiteral     = "E0103"
+	ImportNotFound     = "E0104"
+	InvalidStatement   = "E0105"
+	ImportCycle        = "E0106"
+	UnexpectedEOF      = "E0107"
+
+	RuntimeError     = "E0200"
+	NameNotFound     = "E0201"
+	TypeMismatch     = "E0202"
+	DivisionByZero   = "E0203"
+	IndexOutOfRange  = "E0204"
+	IOError          = "E0205"
+	AlreadyDeclared  = "E0206"
+	WrongArgumentNum = "E0207"
+	KeyNotFound      = "E0208"
+	Thrown           = "E0209"
+	MissingReturn    = "E0210"
+	StepLimit        = "E0211"
+	Canceled         = "E0212"
+	Exit             = "E0213"
+	StackOverflow    = "E0214"
+	PermissionDenied = "E0215"
+)
+
+var kinds = map[string]string{
+	RuntimeError:     "RuntimeError",
+	NameNotFound:     "NameNotFound",
+	TypeMismatch:     "TypeMismatch",
+	DivisionByZero:   "DivisionByZero",
+	IndexOutOfRange:  "IndexOutOfRange",
+	IOError:          "IOError",
+	AlreadyDeclared:  "AlreadyDeclared",
+	WrongArgumentNum: "WrongArgumentNum",
+	KeyNotFound:      "KeyNotFound",
+	Thrown:           "Error",
+	MissingReturn:    "MissingReturn",
+	StepLimit:        "StepLimit",
+	Canceled:         "Canceled",
+	Exit:             "Exit",
+	StackOverflow:    "StackOverflow",
+	PermissionDenied: "PermissionDenied",
+}
+
+//Kind devuelve el nombre del error de ejecucion con codigo 'code'. Es el valor del campo
+//'kind' que recibe un catch.
+func Kind(code string) string {
+	if kind, ok := kinds[code]; ok {
+		return kind
+	}
+	return kinds[RuntimeError]
+}
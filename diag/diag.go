@@ -0,0 +1,200 @@
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kenshindeveloper/april/token"
+)
+
+//Severity indica la gravedad de un diagnostico.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+//Span es el rango del codigo fuente al que se refiere un diagnostico. End puede ser
+//invalido, en ese caso solo se marca la posicion Start.
+type Span struct {
+	Start token.Position
+	End   token.Position
+}
+
+//SpanOf devuelve el rango que ocupa el token 'tok' en el codigo fuente.
+func SpanOf(tok token.Token) Span {
+	length := len(tok.Literal)
+	switch tok.Type {
+	case token.EOF:
+		length = 0
+	case token.STRING:
+		length += 2
+	}
+	return SpanAt(tok.Pos, length)
+}
+
+//SpanAt devuelve un rango de 'length' bytes que comienza en 'pos'.
+func SpanAt(pos token.Position, length int) Span {
+	end := pos
+	if length > 0 {
+		end.Column += length
+		end.Offset += length
+	}
+	return Span{Start: pos, End: end}
+}
+
+//Diagnostic es un error o aviso producido por el lexer, el parser o el evaluador.
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Span     Span
+	Message  string
+	Notes    []string
+}
+
+//Errorf crea un diagnostico de severidad Error.
+func Errorf(code string, span Span, format string, a ...interface{}) *Diagnostic {
+	return &Diagnostic{Severity: Error, Code: code, Span: span, Message: fmt.Sprintf(format, a...)}
+}
+
+//Pos devuelve la posicion donde comienza el diagnostico.
+func (d *Diagnostic) Pos() token.Position {
+	return d.Span.Start
+}
+
+//Error devuelve el diagnostico en una sola linea: 'archivo:linea:columna - mensaje'.
+func (d *Diagnostic) Error() string {
+	if !d.Span.Start.IsValid() {
+		return d.Message
+	}
+	return fmt.Sprintf("%s - %s", d.Span.Start, d.Message)
+}
+
+//Sort ordena los diagnosticos de cada archivo por posicion. Los archivos quedan en el
+//orden en que aparece su primer diagnostico.
+func Sort(diagnostics []*Diagnostic) {
+	files := map[string]int{}
+	for _, d := range diagnostics {
+		if _, ok := files[d.Pos().File]; !ok {
+			files[d.Pos().File] = len(files)
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Pos(), diagnostics[j].Pos()
+		if a.File != b.File {
+			return files[a.File] < files[b.File]
+		}
+		return a.Offset < b.Offset
+	})
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//Sources guarda el texto de cada archivo por nombre, el codigo de la consola usa el
+//nombre "". Los archivos que no estan se leen del disco la primera vez que se necesitan.
+type Sources map[string]string
+
+func (s Sources) line(file string, number int) (string, bool) {
+	text, ok := s[file]
+	if !ok {
+		if file == "" {
+			return "", false
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", false
+		}
+		text = string(data)
+		s[file] = text
+	}
+
+	lines := strings.Split(text, "\n")
+	if number < 1 || number > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[number-1], "\r"), true
+}
+
+//Render escribe el diagnostico 'd' con un extracto del codigo y el rango subrayado:
+//
+//	error[E0100]: expected next token to be ':', got='int' instead
+//	 --> test.april:2:7
+//	  |
+//	2 | var b int;
+//	  |       ^^^
+func Render(w io.Writer, d *Diagnostic, sources Sources) {
+	var out bytes.Buffer
+
+	out.WriteString(d.Severity.String())
+	if d.Code != "" {
+		out.WriteString("[" + d.Code + "]")
+	}
+	out.WriteString(": " + d.Message + "\n")
+
+	start := d.Span.Start
+	if start.IsValid() {
+		number := strconv.Itoa(start.Line)
+		margin := strings.Repeat(" ", len(number))
+		out.WriteString(margin + "--> " + start.String() + "\n")
+
+		if text, ok := sources.line(start.File, start.Line); ok {
+			out.WriteString(margin + " |\n")
+			out.WriteString(number + " | " + text + "\n")
+			out.WriteString(margin + " | " + underline(text, d.Span) + "\n")
+		}
+
+		for _, note := range d.Notes {
+			out.WriteString(margin + " = note: " + note + "\n")
+		}
+	} else {
+		for _, note := range d.Notes {
+			out.WriteString(" = note: " + note + "\n")
+		}
+	}
+
+	io.WriteString(w, out.String())
+}
+
+//underline devuelve la linea de '^' bajo el rango 'span', respeta los tabuladores de
+//'text' para que las marcas queden alineadas.
+func underline(text string, span Span) string {
+	var out bytes.Buffer
+
+	column := span.Start.Column
+	for i := 0; i < column-1 && i < len(text); i++ {
+		if text[i] == '\t' {
+			out.WriteByte('\t')
+		} else {
+			out.WriteByte(' ')
+		}
+	}
+
+	length := 1
+	if span.End.IsValid() && span.End.Line == span.Start.Line && span.End.Column > column {
+		length = span.End.Column - column
+	}
+	out.WriteString(strings.Repeat("^", length))
+
+	return out.String()
+}
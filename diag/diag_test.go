@@ -0,0 +1,69 @@
+package diag
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kenshindeveloper/april/token"
+)
+
+func TestRender(t *testing.T) {
+	sources := Sources{"test.april": "var a:int = 1;\nvar b int;\n\tx := @;"}
+
+	tests := []struct {
+		diagnostic *Diagnostic
+		expected   string
+	}{
+		{
+			Errorf(UnexpectedToken, SpanOf(token.Token{Type: token.OPEINT, Literal: "int", Pos: token.Position{File: "test.april", Line: 2, Column: 7, Offset: 21}}), "expected next token to be ':', got='int' instead"),
+			"error[E0100]: expected next token to be ':', got='int' instead\n" +
+				" --> test.april:2:7\n" +
+				"  |\n" +
+				"2 | var b int;\n" +
+				"  |       ^^^\n",
+		},
+		{
+			&Diagnostic{Severity: Error, Code: IllegalCharacter, Span: SpanAt(token.Position{File: "test.april", Line: 3, Column: 7, Offset: 32}, 1), Message: "illegal character '@'", Notes: []string{"remove it"}},
+			"error[E0001]: illegal character '@'\n" +
+				" --> test.april:3:7\n" +
+				"  |\n" +
+				"3 | \tx := @;\n" +
+				"  | \t     ^\n" +
+				"  = note: remove it\n",
+		},
+		{
+			Errorf(DivisionByZero, Span{}, "division by zero"),
+			"error[E0203]: division by zero\n",
+		},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		Render(&out, tt.diagnostic, sources)
+		if out.String() != tt.expected {
+			t.Errorf("tests[%d] - Render is not equal.\nwant=%q\ngot =%q", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestDiagnosticError(t *testing.T) {
+	d := Errorf(NameNotFound, SpanAt(token.Position{File: "test.april", Line: 4, Column: 2}, 3), "identifier not found: %s", "tam")
+	if d.Error() != "test.april:4:2 - identifier not found: tam" {
+		t.Fatalf("d.Error() is not equal. got=%q", d.Error())
+	}
+}
+
+func TestSort(t *testing.T) {
+	at := func(file string, offset int) *Diagnostic {
+		return Errorf(NameNotFound, SpanAt(token.Position{File: file, Line: 1, Column: offset + 1, Offset: offset}, 1), "%s:%d", file, offset)
+	}
+	diagnostics := []*Diagnostic{at("b.april", 9), at("a.april", 5), at("b.april", 2), at("a.april", 1), at("b.april", 4)}
+	Sort(diagnostics)
+
+	expected := []string{"b.april:2", "b.april:4", "b.april:9", "a.april:1", "a.april:5"}
+	for i, d := range diagnostics {
+		if d.Message != expected[i] {
+			t.Fatalf("diagnostics[%d] is not equal. got=%q, expected=%q", i, d.Message, expected[i])
+		}
+	}
+}
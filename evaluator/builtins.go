@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/object"
 )
 
@@ -39,7 +40,7 @@ var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			switch arg := args[0].(type) {
@@ -48,7 +49,7 @@ var builtins = map[string]*object.Builtin{
 			case *object.List:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			default:
-				return newError("argument to 'len' not supported, got='%s'", args[0].Type())
+				return newError(diag.TypeMismatch, "argument to 'len' not supported, got='%s'", args[0].Type())
 			}
 		},
 	},
@@ -58,9 +59,9 @@ var builtins = map[string]*object.Builtin{
 			if len(args) != 1 || args[0].Type() != object.LIST_OBJ {
 				switch length := len(args); length {
 				case 0:
-					return newError("wrong number of arguments. got'%d', want='1'", len(args))
+					return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 				default:
-					return newError("argument to 'front' must be LIST, got='%s'", args[0].Type())
+					return newError(diag.TypeMismatch, "argument to 'front' must be LIST, got='%s'", args[0].Type())
 				}
 			}
 
@@ -76,9 +77,9 @@ var builtins = map[string]*object.Builtin{
 			if len(args) != 1 || args[0].Type() != object.LIST_OBJ {
 				switch length := len(args); length {
 				case 0:
-					return newError("wrong number of arguments. got'%d', want='1'", len(args))
+					return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 				default:
-					return newError("argument to 'back' must be LIST, got='%s'", args[0].Type())
+					return newError(diag.TypeMismatch, "argument to 'back' must be LIST, got='%s'", args[0].Type())
 				}
 			}
 
@@ -95,9 +96,9 @@ var builtins = map[string]*object.Builtin{
 			if len(args) == 0 || len(args) >= 3 || args[0].Type() != object.LIST_OBJ {
 				switch length := len(args); length {
 				case 0:
-					return newError("wrong number of arguments. got'%d', want='1 or 2'", len(args))
+					return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1 or 2'", len(args))
 				default:
-					return newError("argument to 'pop' must be LIST, got='%s'", args[0].Type())
+					return newError(diag.TypeMismatch, "argument to 'pop' must be LIST, got='%s'", args[0].Type())
 				}
 			}
 
@@ -115,11 +116,11 @@ var builtins = map[string]*object.Builtin{
 			default:
 				objInteger, ok := args[1].(*object.Integer)
 				if !ok {
-					return newError("expression is not interger.")
+					return newError(diag.TypeMismatch, "expression is not interger.")
 				}
 
 				if objInteger.Value >= int64(length) || objInteger.Value < 0 {
-					return newError("index out range.")
+					return newError(diag.IndexOutOfRange, "index out range.")
 				}
 
 				obj := l.Elements[objInteger.Value]
@@ -132,9 +133,9 @@ var builtins = map[string]*object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 || args[0].Type() != object.LIST_OBJ {
 				if len(args) != 2 {
-					return newError("wrong number of arguments. got'%d', want='2'", len(args))
+					return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='2'", len(args))
 				}
-				return newError("argument to 'push' must be LIST, got='%s'", args[0].Type())
+				return newError(diag.TypeMismatch, "argument to 'push' must be LIST, got='%s'", args[0].Type())
 			}
 			l := args[0].(*object.List)
 			l.Elements = append(l.Elements, args[1])
@@ -145,14 +146,14 @@ var builtins = map[string]*object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 || args[0].Type() != object.LIST_OBJ {
 				if len(args) != 2 {
-					return newError("wrong number of arguments. got'%d', want='2'", len(args))
+					return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='2'", len(args))
 				}
-				return newError("argument to 'push' must be LIST, got='%s'", args[0].Type())
+				return newError(diag.TypeMismatch, "argument to 'push' must be LIST, got='%s'", args[0].Type())
 			}
 
 			_, ok := args[1].(object.Hashable)
 			if !ok {
-				return newError("unusable as hash key: %s", args[1].Type())
+				return newError(diag.TypeMismatch, "unusable as hash key: %s", args[1].Type())
 			}
 
 			l := args[0].(*object.List)
@@ -185,18 +186,18 @@ var builtins = map[string]*object.Builtin{
 	"range": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) == 0 || len(args) > 2 {
-				return newError("wrong number of arguments. got'%d', want='1 or 2'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1 or 2'", len(args))
 			}
 
 			intObj, ok := args[0].(*object.Integer)
 			if !ok {
-				return newError("variable must be a integer.")
+				return newError(diag.TypeMismatch, "variable must be a integer.")
 			}
 
 			switch len(args) {
 			case 1:
 				if intObj.Value < 0 {
-					return newError("integer must be >= 0.")
+					return newError(diag.TypeMismatch, "integer must be >= 0.")
 				}
 				list := &object.List{Elements: []object.Object{}}
 				for i := 0; int64(i) < intObj.Value; i++ {
@@ -207,11 +208,11 @@ var builtins = map[string]*object.Builtin{
 			default:
 				intObj1, ok := args[1].(*object.Integer)
 				if !ok {
-					return newError("variable must be a integer.")
+					return newError(diag.TypeMismatch, "variable must be a integer.")
 				}
 
 				if intObj.Value > intObj1.Value {
-					return newError("left variable must be >= right variable.")
+					return newError(diag.TypeMismatch, "left variable must be >= right variable.")
 				}
 
 				list := &object.List{Elements: []object.Object{}}
@@ -229,7 +230,7 @@ var builtins = map[string]*object.Builtin{
 	"str": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			switch args[0].(type) {
@@ -242,7 +243,7 @@ var builtins = map[string]*object.Builtin{
 			case *object.Boolean:
 				return &object.String{Value: strconv.FormatBool(args[0].(*object.Boolean).Value)}
 			default:
-				return newError("function 'str' not supported to '%s'", args[0].Type())
+				return newError(diag.TypeMismatch, "function 'str' not supported to '%s'", args[0].Type())
 			}
 		},
 	},
@@ -250,7 +251,7 @@ var builtins = map[string]*object.Builtin{
 	"int": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			switch args[0].(type) {
@@ -261,13 +262,13 @@ var builtins = map[string]*object.Builtin{
 			case *object.String:
 				value, err := strconv.ParseInt(args[0].(*object.String).Value, 10, 64)
 				if err != nil {
-					return newError("error to convert '%s' to integer.", args[0].(*object.String).Value)
+					return newError(diag.TypeMismatch, "error to convert '%s' to integer.", args[0].(*object.String).Value)
 				}
 
 				return &object.Integer{Value: value}
 
 			default:
-				return newError("function 'int' not supported to '%s'", args[0].Type())
+				return newError(diag.TypeMismatch, "function 'int' not supported to '%s'", args[0].Type())
 			}
 		},
 	},
@@ -275,7 +276,7 @@ var builtins = map[string]*object.Builtin{
 	"double": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			switch args[0].(type) {
@@ -288,13 +289,13 @@ var builtins = map[string]*object.Builtin{
 			case *object.String:
 				value, err := strconv.ParseFloat(args[0].(*object.String).Value, 64)
 				if err != nil {
-					return newError("error to convert '%s' to double.", args[0].(*object.String).Value)
+					return newError(diag.TypeMismatch, "error to convert '%s' to double.", args[0].(*object.String).Value)
 				}
 
 				return &object.Double{Value: value}
 
 			default:
-				return newError("function 'double' not supported to '%s'", args[0].Type())
+				return newError(diag.TypeMismatch, "function 'double' not supported to '%s'", args[0].Type())
 			}
 		},
 	},
@@ -304,24 +305,24 @@ var builtins = map[string]*object.Builtin{
 	"delete": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='2'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='2'", len(args))
 			}
 
 			hash, ok := args[0].(*object.Hash)
 			if !ok {
-				return newError("is not a map.")
+				return newError(diag.TypeMismatch, "is not a map.")
 			}
 
 			key, ok := args[1].(object.Hashable)
 			if !ok {
-				return newError("unusable as hash key: %s", args[1].Type())
+				return newError(diag.TypeMismatch, "unusable as hash key: %s", args[1].Type())
 			}
 
 			pairs, ok := hash.Pairs[key.HashKey()]
 			if ok {
 				delete(hash.Pairs, key.HashKey())
 			} else {
-				return newError("key error: %s", args[1].Type())
+				return newError(diag.KeyNotFound, "key error: %s", args[1].Type())
 			}
 
 			return pairs.Value
@@ -330,17 +331,17 @@ var builtins = map[string]*object.Builtin{
 	"find": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='2'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='2'", len(args))
 			}
 
 			hash, ok := args[0].(*object.Hash)
 			if !ok {
-				return newError("is not a map.")
+				return newError(diag.TypeMismatch, "is not a map.")
 			}
 
 			key, ok := args[1].(object.Hashable)
 			if !ok {
-				return newError("unusable as hash key: %s", args[1].Type())
+				return newError(diag.TypeMismatch, "unusable as hash key: %s", args[1].Type())
 			}
 
 			if _, ok := hash.Pairs[key.HashKey()]; ok {
@@ -357,58 +358,97 @@ var builtins = map[string]*object.Builtin{
 	"type": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 			return &object.String{Value: object.GetType(args[0].Type())}
 		},
 	},
 
 	"print": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			for _, arg := range args {
-				fmt.Println(getFormat(arg.Inspect()))
+				fmt.Fprintln(runtime.IO.Stdout, getFormat(arg.Inspect()))
 			}
 			return NIL
 		},
 	},
 	"printf": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			str := args[0].Inspect()
-			fmt.Printf(getFormat(str) + "\n")
+			fmt.Fprintln(runtime.IO.Stdout, getFormat(str))
 			return NIL
 		},
 	},
+	"input": &object.Builtin{
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='0'", len(args))
+			}
+
+			line, err := runtime.IO.Stdin.ReadString('\n')
+			if err != nil && line == "" {
+				return NIL
+			}
+			return &object.String{Value: strings.TrimRight(line, "\r\n")}
+		},
+	},
+	"argv": &object.Builtin{
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='0'", len(args))
+			}
+
+			elements := make([]object.Object, len(runtime.Args))
+			for i, arg := range runtime.Args {
+				elements[i] = &object.String{Value: arg}
+			}
+			return &object.List{Elements: elements}
+		},
+	},
 	"exit": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 0 {
-				return newError("wrong number of arguments. got'%d', want='0'", len(args))
+			if len(args) > 1 {
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='0' or '1'", len(args))
 			}
-			os.Exit(0)
 
-			return NIL
+			//el programa termina con el codigo 'status', quien lo ejecuta decide que hacer.
+			status := &object.Integer{Value: 0}
+			if len(args) == 1 {
+				code, ok := args[0].(*object.Integer)
+				if !ok {
+					return newError(diag.TypeMismatch, "argument to 'exit' must be INTEGER, got='%s'", args[0].Type())
+				}
+				status = code
+			}
+			return &object.Error{Code: diag.Exit, Message: fmt.Sprintf("exit status %d", status.Value), Value: status}
 		},
 	},
 	//***************************************************************************************
 	//***************************************************************************************
 	//***************************************************************************************
 	"open": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			path, ok := args[0].(*object.String)
 			if !ok {
-				return newError("argument is not type string.")
+				return newError(diag.TypeMismatch, "argument is not type string.")
+			}
+
+			name, denied := runtime.FS.Read(path.Value)
+			if denied != nil {
+				return denied
 			}
 
-			file, err := os.Open(path.Value)
+			file, err := os.Open(name)
 			if err != nil {
-				return newError("error to open file: '%s'", path.Value) //manejo de la variable NIL
+				return newError(diag.IOError, "error to open file: '%s'", path.Value) //manejo de la variable NIL
 			}
 
 			return &object.Stream{FILE: file}
@@ -416,17 +456,22 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"isExist": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			path, ok := args[0].(*object.String)
 			if !ok {
-				return newError("argument is not type stream.")
+				return newError(diag.TypeMismatch, "argument is not type stream.")
+			}
+
+			name, denied := runtime.FS.Read(path.Value)
+			if denied != nil {
+				return denied
 			}
 
-			_, err := os.Stat(path.Value)
+			_, err := os.Stat(name)
 			if err != nil {
 				if os.IsNotExist(err) {
 					return FALSE
@@ -440,12 +485,12 @@ var builtins = map[string]*object.Builtin{
 	"isOpen": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			stream, ok := args[0].(*object.Stream)
 			if !ok {
-				return newError("argument is not type stream.")
+				return newError(diag.TypeMismatch, "argument is not type stream.")
 			}
 
 			if stream.FILE == nil {
@@ -457,43 +502,57 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"create": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			path, ok := args[0].(*object.String)
 			if !ok {
-				return newError("argument is not type string.")
+				return newError(diag.TypeMismatch, "argument is not type string.")
+			}
+
+			name, denied := runtime.FS.Write(path.Value)
+			if denied != nil {
+				return denied
 			}
 
-			file, err := os.Create(path.Value)
+			file, err := os.Create(name)
 			if err != nil {
-				return newError("error to create file: '%s'", path.Value)
+				return newError(diag.IOError, "error to create file: '%s'", path.Value)
 			}
 
 			return &object.Stream{FILE: file}
 		},
 	},
 	"rename": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			originalName, ok := args[0].(*object.String)
 			if !ok {
-				return newError("argument is not type string.")
+				return newError(diag.TypeMismatch, "argument is not type string.")
 			}
 
-			newName, ok := args[0].(*object.String)
+			newName, ok := args[1].(*object.String)
 			if !ok {
-				return newError("argument is not type string.")
+				return newError(diag.TypeMismatch, "argument is not type string.")
 			}
 
-			err := os.Rename(originalName.Value, newName.Value)
+			from, denied := runtime.FS.Write(originalName.Value)
+			if denied != nil {
+				return denied
+			}
+			to, denied := runtime.FS.Write(newName.Value)
+			if denied != nil {
+				return denied
+			}
+
+			err := os.Rename(from, to)
 			if err != nil {
-				return newError("file could not be renamed")
+				return newError(diag.IOError, "file could not be renamed")
 			}
 
 			return TRUE
@@ -501,24 +560,33 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"move": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			originalPath, ok := args[0].(*object.String)
 			if !ok {
-				return newError("argument is not type string.")
+				return newError(diag.TypeMismatch, "argument is not type string.")
 			}
 
-			newPath, ok := args[0].(*object.String)
+			newPath, ok := args[1].(*object.String)
 			if !ok {
-				return newError("argument is not type string.")
+				return newError(diag.TypeMismatch, "argument is not type string.")
+			}
+
+			from, denied := runtime.FS.Write(originalPath.Value)
+			if denied != nil {
+				return denied
+			}
+			to, denied := runtime.FS.Write(newPath.Value)
+			if denied != nil {
+				return denied
 			}
 
-			err := os.Rename(originalPath.Value, newPath.Value)
+			err := os.Rename(from, to)
 			if err != nil {
-				return newError("file could not be renamed")
+				return newError(diag.IOError, "file could not be renamed")
 			}
 
 			return TRUE
@@ -526,24 +594,28 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"write": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='2'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='2'", len(args))
 			}
 
 			stream, ok := args[0].(*object.Stream)
 			if !ok {
-				return newError("firs argument is not type stream.")
+				return newError(diag.TypeMismatch, "firs argument is not type stream.")
 			}
 
 			str, ok := args[1].(*object.String)
 			if !ok {
-				return newError("second argument is not type string.")
+				return newError(diag.TypeMismatch, "second argument is not type string.")
 			}
 			str.Value = getFormat(str.Value)
 
 			if stream.FILE == nil {
-				return newError("variable file is equal to null.")
+				return newError(diag.IOError, "variable file is equal to null.")
+			}
+
+			if denied := runtime.FS.CanWrite(); denied != nil {
+				return denied
 			}
 
 			w := bufio.NewWriter(stream.FILE)
@@ -555,23 +627,28 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"read": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			stream, ok := args[0].(*object.Stream)
 			if !ok {
-				return newError("firs argument is not type stream.")
+				return newError(diag.TypeMismatch, "firs argument is not type stream.")
 			}
 
 			if stream.FILE == nil {
-				return newError("variable file is equal to null.")
+				return newError(diag.IOError, "variable file is equal to null.")
 			}
 
-			data, err := ioutil.ReadFile(stream.FILE.Name())
+			name, denied := runtime.FS.Read(stream.FILE.Name())
+			if denied != nil {
+				return denied
+			}
+
+			data, err := ioutil.ReadFile(name)
 			if err != nil {
-				return newError("error to read file.")
+				return newError(diag.IOError, "error to read file.")
 			}
 
 			return &object.String{Value: getFormat(string(data))}
@@ -581,16 +658,16 @@ var builtins = map[string]*object.Builtin{
 	"close": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			stream, ok := args[0].(*object.Stream)
 			if !ok {
-				return newError("argument is not type stream.")
+				return newError(diag.TypeMismatch, "argument is not type stream.")
 			}
 
 			if stream.FILE == nil {
-				return newError("variable file is equal to null.")
+				return newError(diag.IOError, "variable file is equal to null.")
 			}
 
 			stream.FILE.Close()
@@ -600,19 +677,24 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"remove": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			str, ok := args[0].(*object.String)
 			if !ok {
-				return newError("argument is not type string.")
+				return newError(diag.TypeMismatch, "argument is not type string.")
 			}
 
-			err := os.Remove(str.Value)
+			name, denied := runtime.FS.Write(str.Value)
+			if denied != nil {
+				return denied
+			}
+
+			err := os.Remove(name)
 			if err != nil {
-				return newError("file could not be deleted.")
+				return newError(diag.IOError, "file could not be deleted.")
 			}
 
 			return TRUE
@@ -624,27 +706,27 @@ var builtins = map[string]*object.Builtin{
 	"GoCeil": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Ceil(x.Value)}
 		},
 	},
 
-	"GoFoor": &object.Builtin{
+	"GoFloor": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Floor(x.Value)}
@@ -654,12 +736,12 @@ var builtins = map[string]*object.Builtin{
 	"GoLog": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Log(x.Value)}
@@ -669,12 +751,12 @@ var builtins = map[string]*object.Builtin{
 	"GoLog10": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Log10(x.Value)}
@@ -684,17 +766,17 @@ var builtins = map[string]*object.Builtin{
 	"GoPow": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			y, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Pow(x.Value, y.Value)}
@@ -704,12 +786,12 @@ var builtins = map[string]*object.Builtin{
 	"GoPow10": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Integer)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Pow10(int(x.Value))}
@@ -719,12 +801,12 @@ var builtins = map[string]*object.Builtin{
 	"GoRound": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Round(x.Value)}
@@ -734,12 +816,12 @@ var builtins = map[string]*object.Builtin{
 	"GoTrunc": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x, ok := args[0].(*object.Double)
 			if !ok {
-				return newError("argument is not type Double.")
+				return newError(diag.TypeMismatch, "argument is not type Double.")
 			}
 
 			return &object.Double{Value: math.Trunc(x.Value)}
@@ -749,14 +831,14 @@ var builtins = map[string]*object.Builtin{
 	"GoMax": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x1, ok1 := args[0].(*object.Integer)
 			y1, ok2 := args[1].(*object.Integer)
 			if ok1 {
 				if !ok2 {
-					return newError("argument is not type Integer.")
+					return newError(diag.TypeMismatch, "argument is not type Integer.")
 				} else {
 					return &object.Double{Value: math.Max(float64(x1.Value), float64(y1.Value))}
 				}
@@ -766,12 +848,12 @@ var builtins = map[string]*object.Builtin{
 			y2, ok2 := args[1].(*object.Double)
 			if ok1 {
 				if !ok2 {
-					return newError("argument is not type Integer o Double.")
+					return newError(diag.TypeMismatch, "argument is not type Integer o Double.")
 				} else {
 					return &object.Double{Value: math.Max(x2.Value, y2.Value)}
 				}
 			} else {
-				return newError("argument is not type Integer o Double.")
+				return newError(diag.TypeMismatch, "argument is not type Integer o Double.")
 			}
 		},
 	},
@@ -779,14 +861,14 @@ var builtins = map[string]*object.Builtin{
 	"GoMin": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			x1, ok1 := args[0].(*object.Integer)
 			y1, ok2 := args[1].(*object.Integer)
 			if ok1 {
 				if !ok2 {
-					return newError("argument is not type Integer.")
+					return newError(diag.TypeMismatch, "argument is not type Integer.")
 				} else {
 					return &object.Double{Value: math.Max(float64(x1.Value), float64(y1.Value))}
 				}
@@ -796,12 +878,12 @@ var builtins = map[string]*object.Builtin{
 			y2, ok2 := args[1].(*object.Double)
 			if ok1 {
 				if !ok2 {
-					return newError("argument is not type Integer o Double.")
+					return newError(diag.TypeMismatch, "argument is not type Integer o Double.")
 				} else {
 					return &object.Double{Value: math.Min(x2.Value, y2.Value)}
 				}
 			} else {
-				return newError("argument is not type Integer o Double.")
+				return newError(diag.TypeMismatch, "argument is not type Integer o Double.")
 			}
 		},
 	},
@@ -809,52 +891,52 @@ var builtins = map[string]*object.Builtin{
 	//***************************************************************************************
 	//***************************************************************************************
 	"GoNow": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 0 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			now := time.Now()
-			println(now.String())
+			fmt.Fprintln(runtime.IO.Stderr, now.String())
 			return NIL
 		},
 	},
 
 	"GoYear": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 0 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			now := time.Now()
 			Year := now.Year()
-			fmt.Printf("%v", Year)
+			fmt.Fprintf(runtime.IO.Stdout, "%v", Year)
 			return NIL
 		},
 	},
 
 	"GoMonth": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 0 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			now := time.Now()
 			month := now.Month()
-			fmt.Printf("%v", month)
+			fmt.Fprintf(runtime.IO.Stdout, "%v", month)
 			return NIL
 		},
 	},
 
 	"GoDay": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 0 {
-				return newError("wrong number of arguments. got'%d', want='1'", len(args))
+				return newError(diag.WrongArgumentNum, "wrong number of arguments. got'%d', want='1'", len(args))
 			}
 
 			now := time.Now()
 			day := now.Day()
-			fmt.Printf("%v", day)
+			fmt.Fprintf(runtime.IO.Stdout, "%v", day)
 			return NIL
 		},
 	},
@@ -2,10 +2,11 @@ package evaluator
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/token"
 )
 
 var (
@@ -14,7 +15,17 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+//Eval evalua el nodo 'node' en el entorno 'env'. Los errores que todavia no tienen
+//posicion toman la del nodo, asi cada error apunta a la expresion mas interna que lo produjo.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+	if err, ok := result.(*object.Error); ok && !err.Span.Start.IsValid() && node != nil {
+		err.Span = spanOf(node)
+	}
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	//Statements
 	case *ast.Program:
@@ -35,6 +46,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.BreakStatement:
 		return evalBreakStatement(node, env)
 
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 
@@ -44,6 +61,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Function:
 		return evalFunctionStatement(node, env)
 
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+
 		//Expressions
 	case *ast.Struct:
 		return evalStructExpression(node, env)
@@ -113,20 +133,20 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalFunctionStatement(node *ast.Function, env *object.Environment) object.Object {
 
 	if !env.Scope {
-		return newError("Line: %d - name function '%s' cannot declared in this scope. ", node.Line, node.Name.Name)
+		return newErrorAt(node, diag.AlreadyDeclared, "name function '%s' cannot declared in this scope.", node.Name.Name)
 	}
 
 	_, inEnv := env.Get(node.Name.Name)
 	_, inBuilt := builtins[node.Name.Name]
 	if inEnv || inBuilt {
-		return newError("Line: %d - name function '%s' already exist. ", node.Line, node.Name.Name)
+		return newErrorAt(node, diag.AlreadyDeclared, "name function '%s' already exist.", node.Name.Name)
 	}
 
 	for _, param := range node.Parameters {
 		_, inEnv = env.Get(param.Name.Name)
 		_, inBuilt = builtins[param.Name.Name]
 		if inEnv || inBuilt {
-			return newError("Line: %d - name variable '%s' already exist. ", node.Line, param.Name.Name)
+			return newErrorAt(node, diag.AlreadyDeclared, "name variable '%s' already exist.", param.Name.Name)
 		}
 	}
 
@@ -142,48 +162,18 @@ func evalVarStatement(node *ast.VarStatement, env *object.Environment) object.Ob
 		return val
 	}
 
-	_, inEnv := env.Get(node.Name.Name)
+	inEnv := exists(node.Name, env)
 	_, inBuilt := builtins[node.Name.Name]
 
 	if !inEnv && !inBuilt {
-		switch val.(type) {
-		case *object.Integer:
-			if node.Type.Name != "int" {
-				return newError("Line: %d - declaration error: var %s:%s = INTEGER", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Double:
-			if node.Type.Name != "double" {
-				return newError("Line: %d - declaration error: var %s:%s = DOUBLE", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Boolean:
-			if node.Type.Name != "bool" {
-				return newError("Line: %d - declaration error: var %s:%s = BOOL", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.String:
-			if node.Type.Name != "string" {
-				return newError("Line: %d - declaration error: var %s:%s = STRING", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.List:
-			if node.Type.Name != "list" {
-				return newError("Line: %d - declaration error: var %s:%s = LIST", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Hash:
-			if node.Type.Name != "map" {
-				return newError("Line: %d - declaration error: var %s:%s = MAP", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Stream:
-			if node.Type.Name != "stream" {
-				return newError("Line: %d - declaration error: var %s:%s = STREAM", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.FunctionClosure:
-			if node.Type.Name != "func" {
-				return newError("Line: %d - declaration error: var %s:%s = FUNC", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
+		if err := checkDeclaration(node.Name.Name, node.Type.Name, val); err != nil {
+			err.Span = spanOf(node)
+			return err
 		}
 
-		env.Save(node.Name.Name, val)
+		declare(node.Name, env, val)
 	} else {
-		return newError("Line: %d - variable '%s' already exist. ", node.Line, node.Name.Name)
+		return newErrorAt(node, diag.AlreadyDeclared, "variable '%s' already exist.", node.Name.Name)
 	}
 	return NIL
 }
@@ -198,61 +188,176 @@ func evalGlobalStatement(node *ast.GlobalStatement, env *object.Environment) obj
 	_, inBuilt := builtins[node.Name.Name]
 
 	if !inEnv && !inBuilt {
-		switch val.(type) {
-		case *object.Integer:
-			if node.Type.Name != "int" {
-				return newError("Line: %d - declaration error: var %s:%s = INTEGER", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Double:
-			if node.Type.Name != "double" {
-				return newError("Line: %d - declaration error: var %s:%s = DOUBLE", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Boolean:
-			if node.Type.Name != "bool" {
-				return newError("Line: %d - declaration error: var %s:%s = BOOL", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.String:
-			if node.Type.Name != "string" {
-				return newError("Line: %d - declaration error: var %s:%s = STRING", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.List:
-			if node.Type.Name != "list" {
-				return newError("Line: %d - declaration error: var %s:%s = LIST", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Hash:
-			if node.Type.Name != "map" {
-				return newError("Line: %d - declaration error: var %s:%s = MAP", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.Stream:
-			if node.Type.Name != "stream" {
-				return newError("Line: %d - declaration error: var %s:%s = STREAM", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
-		case *object.FunctionClosure:
-			if node.Type.Name != "func" {
-				return newError("Line: %d - declaration error: var %s:%s = FUNC", node.Line, node.Name.Name, strings.ToUpper(node.Type.Name))
-			}
+		if err := checkDeclaration(node.Name.Name, node.Type.Name, val); err != nil {
+			err.Span = spanOf(node)
+			return err
 		}
 
 		env.SaveGlobal(node.Name.Name, val)
 	} else {
-		return newError("Line: %d - variable '%s' already exist. ", node.Line, node.Name.Name)
+		return newErrorAt(node, diag.AlreadyDeclared, "variable '%s' already exist.", node.Name.Name)
 	}
 	return NIL
 }
 
+//evalImportStatement carga el modulo de 'import "ruta" as nombre' y lo guarda como una
+//variable 'global'. Sin 'as' el lexer ya incorporo el codigo del archivo importado.
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	if node.Name == nil {
+		return NIL
+	}
+
+	module := Import(env.Runtime(), node)
+	if isError(module) {
+		return module
+	}
+
+	_, inEnv := env.Get(node.Name.Name)
+	_, inBuilt := builtins[node.Name.Name]
+	if inEnv || inBuilt {
+		return newErrorAt(node.Name, diag.AlreadyDeclared, "variable '%s' already exist.", node.Name.Name)
+	}
+	env.SaveGlobal(node.Name.Name, module)
+	return NIL
+}
+
 func evalReturnStatement(node *ast.ReturnStatement, env *object.Environment) object.Object {
+	if call, ok := node.Expression.(*ast.CallExpression); ok && env.Runtime().TailPosition() {
+		return evalTailCall(call, node, env)
+	}
+
 	expr := Eval(node.Expression, env)
 	if isError(expr) {
 		return expr
 	}
 
-	return &object.ReturnStatement{Value: expr}
+	return &object.ReturnStatement{Value: expr, Span: spanOf(node)}
+}
+
+//evalTailCall evalua 'return f(...)' sin llamar a f si es una funcion del programa: el
+//return devuelve la llamada pendiente y evalFunctionBody la ejecuta en lugar de la
+//llamada en curso, asi una recursion en posicion de cola no crece la pila.
+func evalTailCall(call *ast.CallExpression, node *ast.ReturnStatement, env *object.Environment) object.Object {
+	function, args, err := evalCall(call, env)
+	if err != nil {
+		return err
+	}
+
+	switch function.(type) {
+	case *object.Function, *object.FunctionClosure:
+		pending := &tailCall{function: function, args: args, call: spanOf(call)}
+		return &object.ReturnStatement{Value: pending, Span: spanOf(node)}
+	}
+
+	result := applyFunction(function, args, spanOf(call).Start, env.Runtime())
+	if err, ok := result.(*object.Error); ok {
+		if !err.Span.Start.IsValid() {
+			err.Span = spanOf(call)
+		}
+		return err
+	}
+	return &object.ReturnStatement{Value: result, Span: spanOf(node)}
 }
 
 func evalBreakStatement(node *ast.BreakStatement, env *object.Environment) object.Object {
 	return &object.BreakStatement{}
 }
 
+func evalThrowStatement(node *ast.ThrowStatement, env *object.Environment) object.Object {
+	value := Eval(node.Expression, env)
+	if isError(value) {
+		return value
+	}
+
+	err := thrownError(value, env)
+	err.Span = spanOf(node)
+	return err
+}
+
+//thrownError crea el error que produce 'throw value'. El catch recibe el mismo struct
+//lanzado o uno nuevo con el mensaje.
+func thrownError(value object.Object, env *object.Environment) *object.Error {
+	err := newError(diag.Thrown, "%s", errorMessage(value))
+	if _, ok := value.(*object.Struct); ok {
+		err.Value = value
+	} else {
+		err.Value = newErrorStruct(env, err.Message, diag.Kind(diag.Thrown))
+	}
+	return err
+}
+
+//evalTryStatement evalua el bloque try, si produce un error lo atrapa el catch. El bloque
+//finally se ejecuta siempre y su resultado reemplaza al anterior si es un error, un
+//return o un break.
+func evalTryStatement(node *ast.TryStatement, env *object.Environment) object.Object {
+	runtime := env.Runtime()
+	runtime.EnterTry()
+	defer runtime.LeaveTry()
+
+	result := Eval(node.Block, object.NewEncloseEnvironment(env))
+	if uncatchable(result) {
+		return result
+	}
+
+	if err, ok := result.(*object.Error); ok && node.Catch != nil {
+		catchEnv := object.NewEncloseEnvironment(env)
+		declare(node.Parameter, catchEnv, caughtValue(err, env))
+		result = Eval(node.Catch, catchEnv)
+		if uncatchable(result) {
+			return result
+		}
+	}
+
+	if node.Finally != nil {
+		finally := Eval(node.Finally, object.NewEncloseEnvironment(env))
+		if finally != nil {
+			switch finally.Type() {
+			case object.ERROR_OBJ, object.RETURN_OBJ, object.BREAK_OBJ:
+				return finally
+			}
+		}
+	}
+
+	return result
+}
+
+//uncatchable indica si 'obj' es un error que ningun try puede atrapar, como exit o los
+//limites de la ejecucion.
+func uncatchable(obj object.Object) bool {
+	err, ok := obj.(*object.Error)
+	return ok && !err.Catchable()
+}
+
+//caughtValue devuelve el valor que recibe el catch: el struct lanzado con throw o un
+//struct con los campos 'message' y 'kind' para los errores del lenguaje.
+func caughtValue(err *object.Error, env *object.Environment) object.Object {
+	if err.Value != nil {
+		return err.Value
+	}
+	return newErrorStruct(env, err.Message, diag.Kind(err.Code))
+}
+
+func newErrorStruct(env *object.Environment, message, kind string) *object.Struct {
+	strucObj := &object.Struct{Env: object.NewEnvironmentFn(env)}
+	strucObj.Env.Save("message", &object.String{Value: message})
+	strucObj.Env.Save("kind", &object.String{Value: kind})
+	return strucObj
+}
+
+//errorMessage devuelve el mensaje del valor lanzado con throw, de un struct se usa el
+//campo 'message'.
+func errorMessage(value object.Object) string {
+	switch value := value.(type) {
+	case *object.String:
+		return value.Value
+	case *object.Struct:
+		if message, ok := value.Env.Get("message"); ok {
+			return errorMessage(message)
+		}
+	}
+	return value.Inspect()
+}
+
 func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
 	extendEnv := object.NewEncloseEnvironment(env)
 	var condition object.Object
@@ -260,7 +365,7 @@ func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Ob
 	if impl, ok := node.Condition.(*ast.ImplicitDeclarationExpression); ok {
 
 		if node.Declaration != nil || node.Operation != nil {
-			return newError("Line: %d - for declaration is incorrect.", node.Line)
+			return newErrorAt(node, diag.RuntimeError, "for declaration is incorrect.")
 		}
 
 		var listExpr object.Object
@@ -270,16 +375,16 @@ func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Ob
 		case *ast.List:
 			listExpr = Eval(impl.Right, extendEnv)
 		case *ast.Identifier:
-			obj, ok := env.Get(impl.Right.(*ast.Identifier).Name)
+			obj, ok := lookup(impl.Right.(*ast.Identifier), extendEnv)
 			if ok {
 				_, ok = obj.(*object.List)
 				if !ok {
-					return newError("Line: %d - expression incompatible with for, expression must be type list. %T", node.Line, impl.Right)
+					return newErrorAt(node, diag.TypeMismatch, "expression incompatible with for, expression must be type list. %T", impl.Right)
 				}
 				listExpr = Eval(impl.Right, extendEnv)
 			}
 		default:
-			return newError("Line: %d - expression incompatible with for, expression must be type list. %T", node.Line, impl.Right)
+			return newErrorAt(node, diag.TypeMismatch, "expression incompatible with for, expression must be type list. %T", impl.Right)
 		}
 
 		if isError(listExpr) {
@@ -287,22 +392,26 @@ func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Ob
 		}
 
 		if list, ok := listExpr.(*object.List); ok {
-			extendEnv.Save(impl.Left.Name, &object.Integer{})
+			declare(impl.Left, extendEnv, &object.Integer{})
 			for _, obj := range list.Elements {
+				if err := env.Runtime().Step(); err != nil {
+					return err
+				}
 				newExtendEnv := object.NewEncloseEnvironment(extendEnv)
-				extendEnv.Set(impl.Left.Name, obj)
+				assign(impl.Left, extendEnv, obj)
 				body := Eval(node.Body, newExtendEnv)
 				if isError(body) {
 					return body
-				} else if body == nil {
+				} else if body == nil || body.Type() == object.BREAK_OBJ {
+					//el break termina solo este for, no los que lo contienen.
 					break
-				} else if body != nil && body.Type() == object.RETURN_OBJ || body.Type() == object.BREAK_OBJ {
+				} else if body.Type() == object.RETURN_OBJ {
 					return body
 				}
 			}
 			return NIL
 		}
-		return newError("Line: %d - expression is not type list.", node.Line)
+		return newErrorAt(node, diag.TypeMismatch, "expression is not type list.")
 	}
 	//---------------------------------
 	var declaration object.Object
@@ -321,17 +430,21 @@ func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Ob
 
 	value, ok := condition.(*object.Boolean)
 	if !ok {
-		return newError("Line: %d - the expression is not type boolean.", node.Line)
+		return newErrorAt(node, diag.TypeMismatch, "the expression is not type boolean.")
 	}
 	run := value.Value
 	for run {
+		if err := env.Runtime().Step(); err != nil {
+			return err
+		}
 		newExtendEnv := object.NewEncloseEnvironment(extendEnv)
 		body := Eval(node.Body, newExtendEnv)
 		if isError(body) {
 			return body
-		} else if body == nil {
+		} else if body == nil || body.Type() == object.BREAK_OBJ {
+			//el break termina solo este for, no los que lo contienen.
 			break
-		} else if body != nil && body.Type() == object.RETURN_OBJ || body.Type() == object.BREAK_OBJ {
+		} else if body.Type() == object.RETURN_OBJ {
 			return body
 		}
 
@@ -373,10 +486,10 @@ func evalStructExpression(node *ast.Struct, env *object.Environment) object.Obje
 			case "struct":
 				strucObj.Env.Save(data.Name, &object.Struct{})
 			default:
-				return newError("Line: %d - data type incompatible in struct", node.Line)
+				return newErrorAt(node, diag.TypeMismatch, "data type incompatible in struct")
 			}
 		} else {
-			return newError("Line: %d - name '%s' exist outside or insede of struct", node.Line, data.Name)
+			return newErrorAt(node, diag.AlreadyDeclared, "name '%s' exist outside or insede of struct", data.Name)
 		}
 	}
 	return strucObj
@@ -406,29 +519,39 @@ func evalListExpression(node *ast.List, env *object.Environment) object.Object {
 }
 
 func evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
+	function, args, err := evalCall(node, env)
+	if err != nil {
+		return err
+	}
+
+	return applyFunction(function, args, spanOf(node).Start, env.Runtime())
+}
+
+//evalCall evalua la funcion y los argumentos de la llamada 'node' sin llamarla.
+func evalCall(node *ast.CallExpression, env *object.Environment) (object.Object, []object.Object, object.Object) {
 	function := Eval(node.Function, env)
 	if isError(function) {
-		return function
+		return nil, nil, function
 	}
 	args := evalExpressions(node.Arguments, env)
 	if len(args) == 1 && isError(args[0]) {
-		return args[0]
+		return nil, nil, args[0]
 	}
 
 	switch function.(type) {
 	case *object.Function:
 		if len(function.(*object.Function).Parameters) != len(args) {
-			return newError("Line: %d - count parameters not match.", node.Line)
+			return nil, nil, newErrorAt(node, diag.WrongArgumentNum, "count parameters not match.")
 		}
 	case *object.FunctionClosure:
 		if len(function.(*object.FunctionClosure).Parameters) != len(args) {
-			return newError("Line: %d - count parameters not match.", node.Line)
+			return nil, nil, newErrorAt(node, diag.WrongArgumentNum, "count parameters not match.")
 		}
 		// default:
-		// 	return newError("data type error in call function.")
+		// 	return newError(diag.RuntimeError, "data type error in call function.")
 	}
 
-	return applyFunction(function, args)
+	return function, args, nil
 }
 
 func evalFunctionClosureExpression(node *ast.FunctionClosure, env *object.Environment) object.Object {
@@ -443,18 +566,18 @@ func evalImplicitDeclarationExpression(node *ast.ImplicitDeclarationExpression,
 		return right
 	}
 
-	_, inEnv := env.Get(node.Left.Name)
+	inEnv := exists(node.Left, env)
 	_, inBuilt := builtins[node.Left.Name]
 
 	if !inEnv && !inBuilt {
 
 		if !isBasicDataType(right) {
-			return newError("Line: %d - declaration not compatible '%s' := '%s'. ", node.Line, node.Left.Name, right.Type())
+			return newErrorAt(node, diag.TypeMismatch, "declaration not compatible '%s' := '%s'.", node.Left.Name, right.Type())
 		}
 
-		env.Save(node.Left.Name, right)
+		declare(node.Left, env, right)
 	} else {
-		return newError("Line: %d - variable '%s' already exist. ", node.Line, node.Left.Name)
+		return newErrorAt(node, diag.AlreadyDeclared, "variable '%s' already exist.", node.Left.Name)
 	}
 
 	return NIL
@@ -474,7 +597,7 @@ func isBasicDataType(obj object.Object) bool {
 		return true
 	case *object.Hash:
 		return true
-	case *object.FunctionClosure:
+	case *object.FunctionClosure, *object.Closure:
 		return true
 	case *object.Stream:
 		return true
@@ -494,19 +617,14 @@ func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) o
 	switch node.Left.(type) {
 	case *ast.Identifier:
 		ident := node.Left.(*ast.Identifier)
-		if value, ok := env.Get(ident.Name); ok {
-			if right.Type() != value.Type() {
-				switch {
-				case value.Type() == object.DOUBLE_OBJ && right.Type() == object.INTEGER_OBJ:
-					env.Set(ident.Name, &object.Double{Value: float64(right.(*object.Integer).Value)})
-				default:
-					return newError("Line: %d - assign not compatible '%s' : '%s'. ", node.Line, right.Type(), value.Type())
-				}
-			} else {
-				env.Set(ident.Name, right)
+		if value, ok := lookup(ident, env); ok {
+			assigned := assignValue(value, right)
+			if isError(assigned) {
+				return assigned
 			}
+			assign(ident, env, assigned)
 		} else {
-			return newError("Line: %d - variable '%s' not exist. ", node.Line, ident.Name)
+			return newErrorAt(node, diag.NameNotFound, "variable '%s' not exist.", ident.Name)
 		}
 
 		return NIL
@@ -532,25 +650,28 @@ func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) o
 	case *ast.InfixExpression:
 		nodeDot, ok := node.Left.(*ast.InfixExpression)
 		if !ok {
-			return newError("error 0.")
+			return newError(diag.RuntimeError, "error 0.")
 		}
 		if nodeDot.Operator != "." {
-			return newError("error 1.")
+			return newError(diag.RuntimeError, "error 1.")
 		}
 
 		struc := Eval(nodeDot.Left, env)
 		if isError(struc) {
 			return struc
 		}
+		if _, ok := struc.(*object.Module); ok {
+			return SetField(struc, nodeDot.Right.(*ast.Identifier).Name, nil)
+		}
 
 		dataStruct, ok := struc.(*object.Struct)
 		if !ok {
-			return newError("error is not a struct type.")
+			return newError(diag.TypeMismatch, "error is not a struct type.")
 		}
 
 		objStr, ok := dataStruct.Env.Get(nodeDot.Right.(*ast.Identifier).Name)
 		if !ok {
-			return newError("Line: %d - var '%s' is not define.", nodeDot.Right.(*ast.Identifier).Line, nodeDot.Right.(*ast.Identifier).Name)
+			return newErrorAt(nodeDot.Right.(*ast.Identifier), diag.NameNotFound, "var '%s' is not define.", nodeDot.Right.(*ast.Identifier).Name)
 		}
 
 		// value := Eval(node.Right, dataStruct.Env)
@@ -558,18 +679,9 @@ func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) o
 		if isError(value) {
 			return value
 		}
-		if objStr.Type() == object.DOUBLE_OBJ && value.Type() == object.INTEGER_OBJ {
-			dataStruct.Env.Set(nodeDot.Right.(*ast.Identifier).Name, &object.Double{Value: float64(value.(*object.Integer).Value)})
-			return NIL
-
-		} else if objStr.Type() != value.Type() {
-			return newError("Line: %d - assignment is not compatible.", node.Line)
-		}
-
-		dataStruct.Env.Set(nodeDot.Right.(*ast.Identifier).Name, value)
-		return NIL
+		return setField(dataStruct, nodeDot.Right.(*ast.Identifier).Name, objStr, value)
 	default:
-		return newError("Line: %d - expression assignment is not possible. ", node.Line)
+		return newErrorAt(node, diag.RuntimeError, "expression assignment is not possible.")
 	}
 }
 
@@ -579,16 +691,16 @@ func evalAssignOperationExpression(node *ast.AssignOperationExpression, env *obj
 		return right
 	}
 
-	if value, ok := env.Get(node.Left.Name); ok {
+	if value, ok := lookup(node.Left, env); ok {
 		v := evalInfixExpression(string(node.Operator[0]), value, right)
 		if isError(v) {
 			return v
 		}
-		env.Set(node.Left.Name, v)
+		assign(node.Left, env, v)
 		return v
 	}
 
-	return newError("Line: %d - variable '%s' not exist. ", node.Line, node.Left.Name)
+	return newErrorAt(node, diag.NameNotFound, "variable '%s' not exist.", node.Left.Name)
 }
 
 func evalPrefixExpressions(node *ast.PrefixExpression, env *object.Environment) object.Object {
@@ -619,14 +731,14 @@ func evalInfixExpressions(node *ast.InfixExpression, env *object.Environment) ob
 		}
 	}
 
-	if left.Type() != object.STRUCT_OBJ && node.Operator == "." {
-		return newError("Line: %d - the variable '%s' is not type struct.", node.Left.(*ast.Identifier).Line, node.Left.(*ast.Identifier).Name)
+	if left.Type() != object.STRUCT_OBJ && left.Type() != object.MODULE_OBJ && node.Operator == "." {
+		return newErrorAt(node.Left, diag.TypeMismatch, "the variable '%s' is not type struct.", node.Left.String())
 	}
 
-	if left.Type() == object.STRUCT_OBJ && node.Operator == "." {
+	if node.Operator == "." {
 		ident, ok := node.Right.(*ast.Identifier)
 		if !ok {
-			return newError("Line: %d - the variables is not identifier. 1", node.Line)
+			return newErrorAt(node, diag.TypeMismatch, "the variables is not identifier. 1")
 		}
 		return evalStruct(left, ident)
 	}
@@ -643,35 +755,29 @@ func evalInfixExpressions(node *ast.InfixExpression, env *object.Environment) ob
 }
 
 func evalStruct(left object.Object, right *ast.Identifier) object.Object {
-	dataStruct, ok := left.(*object.Struct)
-	if !ok {
-		return newError("error is not a struct type.")
-	}
-
-	obj, ok := dataStruct.Env.Get(right.Name)
-	if !ok {
-		return newError("Line: %d - var '%s' is not define.", right.Line, right.Name)
+	obj := Field(left, right.Name)
+	if err, ok := obj.(*object.Error); ok && err.Code == diag.NameNotFound {
+		err.Span = spanOf(right)
 	}
-
 	return obj
 }
 
 func evalPostfixExpressions(node *ast.PostfixExpression, env *object.Environment) object.Object {
-	value, ok := env.Get(node.Left.Name)
+	value, ok := lookup(node.Left, env)
 	if ok {
 		_, isInteger := value.(*object.Integer)
 		if !isInteger {
-			return newError("Line: %d - operator '%s' must be integer. got='%T'", node.Line, node.Operator, value)
+			return newErrorAt(node, diag.TypeMismatch, "operator '%s' must be integer. got='%T'", node.Operator, value)
 		}
 
 		v := evalInfixExpression(string(node.Operator[0]), value, &object.Integer{Value: 1})
 		if isError(v) {
 			return v
 		}
-		env.Set(node.Left.Name, v)
+		assign(node.Left, env, v)
 		return v
 	}
-	return newError("Line: %d - variable '%s' not exist. ", node.Line, node.Left.Name)
+	return newErrorAt(node, diag.NameNotFound, "variable '%s' not exist.", node.Left.Name)
 }
 
 //***************************************************************************************
@@ -682,6 +788,11 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, stmt := range block.Statements {
+		if err := env.Runtime().Step(); err != nil {
+			err.Span = spanOf(stmt)
+			return err
+		}
+
 		result = Eval(stmt, env)
 		if result != nil {
 			if result.Type() == object.RETURN_OBJ || result.Type() == object.ERROR_OBJ || block.Type > 0 && result.Type() == object.BREAK_OBJ {
@@ -727,15 +838,23 @@ func isTruthy(obj object.Object) bool {
 		return true
 	case FALSE:
 		return false
-	default:
-		return true
 	}
+	//los booleanos creados fuera del evaluador, por ejemplo con object.FromGo.
+	if boolean, ok := obj.(*object.Boolean); ok {
+		return boolean.Value
+	}
+	return true
 }
 
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
+		if err := env.Runtime().Step(); err != nil {
+			err.Span = spanOf(statement)
+			return err
+		}
+
 		result = Eval(statement, env)
 
 		switch result := result.(type) {
@@ -750,7 +869,7 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Name); ok {
+	if val, ok := lookup(node, env); ok {
 		return val
 	}
 
@@ -758,7 +877,7 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
-	return newError("Line: %d - identifier not found: %s", node.Line, node.Name)
+	return newErrorAt(node, diag.NameNotFound, "identifier not found: %s", node.Name)
 }
 
 func evalPrefixExpression(operator string, right object.Object) object.Object {
@@ -768,7 +887,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(diag.TypeMismatch, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -779,7 +898,7 @@ func evalMinOperatorExpression(right object.Object) object.Object {
 	case object.DOUBLE_OBJ:
 		return &object.Double{Value: -right.(*object.Double).Value}
 	default:
-		return newError("unkown operator: -%s", right.Type())
+		return newError(diag.TypeMismatch, "unkown operator: -%s", right.Type())
 	}
 }
 
@@ -802,9 +921,9 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	case left.Type() == object.NIL_OBJ || right.Type() == object.NIL_OBJ:
 		return evalNilInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newError(diag.TypeMismatch, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(diag.TypeMismatch, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -815,7 +934,7 @@ func evalNilInfixExpression(operator string, left object.Object, right object.Ob
 	case "==":
 		return boolToBooleanObject(left.Type() == right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(diag.TypeMismatch, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -829,7 +948,7 @@ func evalDoubleInfixExpression(operator string, leftVar float64, rightVar float6
 		return &object.Double{Value: (leftVar * rightVar)}
 	case "/":
 		if rightVar == 0 {
-			return newError("division by zero")
+			return newError(diag.DivisionByZero, "division by zero")
 		}
 		return &object.Double{Value: (leftVar / rightVar)}
 	case "<":
@@ -845,7 +964,7 @@ func evalDoubleInfixExpression(operator string, leftVar float64, rightVar float6
 	case ">=":
 		return boolToBooleanObject(leftVar >= rightVar)
 	default:
-		return newError("unknown operator: DOUBLE %s DOUBLE", operator)
+		return newError(diag.TypeMismatch, "unknown operator: DOUBLE %s DOUBLE", operator)
 	}
 }
 
@@ -863,7 +982,7 @@ func evalBooleanInfixExpression(operator string, left object.Object, right objec
 	case "or":
 		return boolToBooleanObject(leftVar || rightVar)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(diag.TypeMismatch, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -887,7 +1006,7 @@ func evalStringInfixExpression(operator string, left object.Object, right object
 	case ">":
 		return boolToBooleanObject(leftVar > rightVar)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(diag.TypeMismatch, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -904,7 +1023,7 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 		return &object.Integer{Value: (leftVar * rightVar)}
 	case "/":
 		if rightVar == 0 {
-			return newError("division by zero")
+			return newError(diag.DivisionByZero, "division by zero")
 		}
 		return &object.Integer{Value: (leftVar / rightVar)}
 	case "%":
@@ -922,7 +1041,7 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case ">=":
 		return boolToBooleanObject(leftVar >= rightVar)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(diag.TypeMismatch, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -934,6 +1053,10 @@ func boolToBooleanObject(value bool) object.Object {
 }
 
 func evalNotOperatorExpression(right object.Object) object.Object {
+	if boolean, ok := right.(*object.Boolean); ok {
+		return boolToBooleanObject(!boolean.Value)
+	}
+
 	switch right {
 	case TRUE:
 		return FALSE
@@ -960,160 +1083,229 @@ func evalExpressions(exprs []ast.Expression, env *object.Environment) []object.O
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func applyFunction(fn object.Object, args []object.Object, call token.Position, runtime *object.Runtime) object.Object {
+	if err := runtime.Step(); err != nil {
+		return err
+	}
+
+	if builtin, ok := fn.(*object.Builtin); ok {
+		return builtin.Call(runtime, args...)
+	}
+	current, err := invoke(fn, args, call)
+	if err != nil {
+		return err
+	}
+	return evalFunctionBody(current, runtime)
+}
+
+//invocation es una llamada a una funcion del programa lista para evaluar: el entorno
+//con los argumentos, el cuerpo y como se comprueba el resultado.
+type invocation struct {
+	function object.Object
+	env      *object.Environment
+	body     *ast.BlockStatement
+	frame    object.Frame
+	unwrap   func(object.Object) object.Object
+}
+
+//invoke prepara la llamada a la funcion 'fn' con los argumentos 'args'.
+func invoke(fn object.Object, args []object.Object, call token.Position) (*invocation, *object.Error) {
 	switch fn := fn.(type) {
 	case *object.FunctionClosure:
 		if extendedEnv := extendFunctionClosureEnv(fn, args); extendedEnv != nil {
-			evaluated := Eval(fn.Body, extendedEnv)
-			if isError(evaluated) {
-				return evaluated
-			}
-			return unwrapReturnValue(fn, evaluated)
+			return &invocation{
+				function: fn,
+				env:      extendedEnv,
+				body:     fn.Body,
+				frame:    object.Frame{Function: "<closure>", Call: call},
+				unwrap: func(obj object.Object) object.Object {
+					return unwrapReturnValue("<closure>", fn.Return, fn.Body, obj)
+				},
+			}, nil
 		}
-		return newError("data type mismatch into function closure.")
+		return nil, newError(diag.TypeMismatch, "data type mismatch into function closure.")
 
 	case *object.Function:
 		if extendedEnv := extendFunctionEnv(fn, args); extendedEnv != nil {
-			evaluated := Eval(fn.Body, extendedEnv)
-			if isError(evaluated) {
-				return evaluated
-			}
-			return unwrapReturnFunctionValue(fn, evaluated)
+			return &invocation{
+				function: fn,
+				env:      extendedEnv,
+				body:     fn.Body,
+				frame:    object.Frame{Function: fn.Name.Name, Call: call},
+				unwrap: func(obj object.Object) object.Object {
+					return unwrapReturnValue(fn.Name.Name, fn.Return, fn.Name, obj)
+				},
+			}, nil
 		}
-		return newError("data type mismatch into function '%s'", fn.Name.Name)
-	case *object.Builtin:
-		return fn.Fn(args...)
+		return nil, newError(diag.TypeMismatch, "data type mismatch into function '%s'", fn.Name.Name)
 	default:
-		return newError("not a function: %s", fn.Type())
+		return nil, newError(diag.TypeMismatch, "not a function: %s", fn.Type())
 	}
 }
 
-func unwrapReturnFunctionValue(fn *object.Function, obj object.Object) object.Object {
-	if fn.Return == nil {
-		if _, ok := obj.(*object.ReturnStatement); ok {
-			return newError("return value not mismatch, expected 'null'.")
+//tailCall es la llamada pendiente de un 'return f(...)', la devuelve evalTailCall.
+type tailCall struct {
+	function object.Object
+	args     []object.Object
+	call     diag.Span
+}
+
+func (tc *tailCall) Type() object.ObjectType {
+	return "TAIL_CALL"
+}
+
+func (tc *tailCall) Inspect() string {
+	return "tail call"
+}
+
+//evalFunctionBody evalua el cuerpo de la llamada 'current' con la llamada en la pila de
+//llamadas y convierte el resultado con su 'unwrap'. Si el cuerpo termina con una llamada
+//en posicion de cola se evalua en su lugar, sin crecer la pila de Go. La llamada actual
+//queda pendiente para la traza y su 'unwrap' se aplica despues al resultado, salvo que
+//ya haya una pendiente del mismo cuerpo: ahi la nueva llamada la reemplaza, asi una
+//recursion en cola, directa o mutua, no crece la pila de llamadas. Si se produce un
+//error se le agrega la traza de llamadas en curso.
+func evalFunctionBody(current *invocation, runtime *object.Runtime) object.Object {
+	runtime.Push(current.frame)
+	defer runtime.Pop()
+
+	pending := []*invocation{}
+	spans := []diag.Span{}
+	defer func() {
+		for range pending {
+			runtime.Pop()
 		}
-		return NIL
-	}
-	if returnValue, ok := obj.(*object.ReturnStatement); ok {
-		if fn.Return.Name == "int" && returnValue.Value.Type() == object.INTEGER_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "double" && (returnValue.Value.Type() == object.DOUBLE_OBJ || returnValue.Value.Type() == object.INTEGER_OBJ) {
-			if returnValue.Value.Type() == object.INTEGER_OBJ {
-				return &object.Double{Value: float64(returnValue.Value.(*object.Integer).Value)}
-			}
-			return returnValue.Value
-		} else if fn.Return.Name == "bool" && returnValue.Value.Type() == object.BOOLEAN_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "string" && returnValue.Value.Type() == object.STRING_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "list" && returnValue.Value.Type() == object.LIST_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "map" && returnValue.Value.Type() == object.HASH_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "func" && returnValue.Value.Type() == object.CLOSURE_OBJ {
-			return returnValue.Value
+	}()
+
+	var evaluated object.Object
+	for {
+		if err := runtime.Overflow(); err != nil {
+			evaluated = err
+			break
 		}
-	}
-	return newError("return value not mismatch, expected '%s'.", fn.Return.Name)
-}
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnvironmentFn(fn.Env)
-	save := false
+		evaluated = Eval(current.body, current.env)
+		next, ok := nextCall(evaluated)
+		if !ok {
+			break
+		}
 
-	for pos, param := range fn.Parameters {
-		if param.Type.Name == "int" && args[pos].Type() == object.INTEGER_OBJ {
-			save = true
-		} else if param.Type.Name == "double" && (args[pos].Type() == object.DOUBLE_OBJ || args[pos].Type() == object.INTEGER_OBJ) {
-			if args[pos].Type() == object.INTEGER_OBJ {
-				args[pos] = &object.Double{Value: float64(args[pos].(*object.Integer).Value)}
+		if err := runtime.Step(); err != nil {
+			evaluated = err
+			break
+		}
+		nextInvocation, err := invoke(next.function, next.args, next.call.Start)
+		if err != nil {
+			if !err.Span.Start.IsValid() {
+				err.Span = next.call
 			}
-			save = true
-		} else if param.Type.Name == "bool" && args[pos].Type() == object.BOOLEAN_OBJ {
-			save = true
-		} else if param.Type.Name == "string" && args[pos].Type() == object.STRING_OBJ {
-			save = true
-		} else if param.Type.Name == "list" && args[pos].Type() == object.LIST_OBJ {
-			save = true
-		} else if param.Type.Name == "map" && args[pos].Type() == object.HASH_OBJ {
-			save = true
-		} else if param.Type.Name == "func" && args[pos].Type() == object.CLOSURE_OBJ {
-			save = true
+			evaluated = err
+			break
 		}
 
-		if save {
-			env.Save(param.Name.Name, args[pos])
-			save = false
+		if current.body == nextInvocation.body || pendingBody(pending, current.body) {
+			runtime.Replace(nextInvocation.frame)
 		} else {
-			return nil
+			pending = append(pending, current)
+			spans = append(spans, evaluated.(*object.ReturnStatement).Span)
+			runtime.Push(nextInvocation.frame)
 		}
+		current = nextInvocation
 	}
 
-	return env
+	if !isError(evaluated) {
+		evaluated = current.unwrap(evaluated)
+	}
+	for i := len(pending) - 1; i >= 0 && !isError(evaluated); i-- {
+		evaluated = pending[i].unwrap(&object.ReturnStatement{Value: evaluated, Span: spans[i]})
+	}
+	if err, ok := evaluated.(*object.Error); ok && err.Trace == nil {
+		err.Trace = runtime.Trace()
+	}
+	return evaluated
 }
 
-func extendFunctionClosureEnv(fn *object.FunctionClosure, args []object.Object) *object.Environment {
-	env := object.NewEncloseEnvironment(fn.Env)
-	save := false
+//pendingBody indica si una llamada de 'pending' ya es del cuerpo 'body', su 'unwrap'
+//comprueba lo mismo.
+func pendingBody(pending []*invocation, body *ast.BlockStatement) bool {
+	for _, p := range pending {
+		if p.body == body {
+			return true
+		}
+	}
+	return false
+}
 
-	for pos, param := range fn.Parameters {
-		if param.Type.Name == "int" && args[pos].Type() == object.INTEGER_OBJ {
-			save = true
-		} else if param.Type.Name == "double" && (args[pos].Type() == object.DOUBLE_OBJ || args[pos].Type() == object.INTEGER_OBJ) {
-			if args[pos].Type() == object.INTEGER_OBJ {
-				args[pos] = &object.Double{Value: float64(args[pos].(*object.Integer).Value)}
-			}
-			save = true
-		} else if param.Type.Name == "bool" && args[pos].Type() == object.BOOLEAN_OBJ {
-			save = true
-		} else if param.Type.Name == "string" && args[pos].Type() == object.STRING_OBJ {
-			save = true
-		} else if param.Type.Name == "list" && args[pos].Type() == object.LIST_OBJ {
-			save = true
-		} else if param.Type.Name == "map" && args[pos].Type() == object.HASH_OBJ {
-			save = true
-		} else if param.Type.Name == "func" && args[pos].Type() == object.CLOSURE_OBJ {
-			save = true
+//nextCall devuelve la llamada pendiente si 'obj' es el resultado de un 'return f(...)'.
+func nextCall(obj object.Object) (*tailCall, bool) {
+	returnValue, ok := obj.(*object.ReturnStatement)
+	if !ok {
+		return nil, false
+	}
+	next, ok := returnValue.Value.(*tailCall)
+	return next, ok
+}
+
+//returnTypes relaciona los tipos que se declaran en una funcion con los objetos que
+//puede devolver.
+var returnTypes = map[string][]object.ObjectType{
+	"int":    {object.INTEGER_OBJ},
+	"double": {object.DOUBLE_OBJ, object.INTEGER_OBJ},
+	"bool":   {object.BOOLEAN_OBJ},
+	"string": {object.STRING_OBJ},
+	"list":   {object.LIST_OBJ},
+	"map":    {object.HASH_OBJ},
+	"func":   {object.CLOSURE_OBJ, object.FUNCTION_OBJ},
+	"stream": {object.STREAM_OBJ},
+	"struct": {object.STRUCT_OBJ},
+}
+
+//unwrapReturnValue comprueba que el resultado 'obj' del cuerpo de la funcion 'name' sea
+//del tipo declarado 'declared' y devuelve el valor. Un entero se convierte en double si
+//la funcion devuelve double. Si el cuerpo termina sin return el error apunta a 'node'.
+func unwrapReturnValue(name string, declared *ast.Identifier, node ast.Node, obj object.Object) object.Object {
+	returnValue, ok := obj.(*object.ReturnStatement)
+
+	if !ok {
+		if declared == nil {
+			return NIL
 		}
+		err := MissingReturn(name, declared)
+		err.Span = spanOf(node)
+		return err
+	}
 
-		if save {
-			env.Save(param.Name.Name, args[pos])
-			save = false
-		} else {
+	result := ReturnValue(name, declared, returnValue.Value)
+	if err, ok := result.(*object.Error); ok {
+		err.Span = returnValue.Span
+	}
+	return result
+}
+
+func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	env := object.NewEnvironmentFn(fn.Env)
+	for pos, param := range fn.Parameters {
+		arg, ok := convertArgument(param.Type.Name, args[pos])
+		if !ok {
 			return nil
 		}
+		declare(param.Name, env, arg)
 	}
 
 	return env
 }
 
-func unwrapReturnValue(fn *object.FunctionClosure, obj object.Object) object.Object {
-	if fn.Return == nil {
-		if _, ok := obj.(*object.ReturnStatement); ok {
-			return newError("return value not mismatch, expected 'null'.")
-		}
-		return NIL
-	}
-	if returnValue, ok := obj.(*object.ReturnStatement); ok {
-		if fn.Return.Name == "int" && returnValue.Value.Type() == object.INTEGER_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "double" && (returnValue.Value.Type() == object.DOUBLE_OBJ || returnValue.Value.Type() == object.INTEGER_OBJ) {
-			if returnValue.Value.Type() == object.INTEGER_OBJ {
-				return &object.Double{Value: float64(returnValue.Value.(*object.Integer).Value)}
-			}
-			return returnValue.Value
-		} else if fn.Return.Name == "bool" && returnValue.Value.Type() == object.BOOLEAN_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "string" && returnValue.Value.Type() == object.STRING_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "list" && returnValue.Value.Type() == object.LIST_OBJ {
-			return returnValue.Value
-		} else if fn.Return.Name == "map" && returnValue.Value.Type() == object.HASH_OBJ {
-			return returnValue.Value
+func extendFunctionClosureEnv(fn *object.FunctionClosure, args []object.Object) *object.Environment {
+	env := object.NewEncloseEnvironment(fn.Env)
+	for pos, param := range fn.Parameters {
+		arg, ok := convertArgument(param.Type.Name, args[pos])
+		if !ok {
+			return nil
 		}
+		declare(param.Name, env, arg)
 	}
-	return newError("return value not mismatch, expected '%s'.", fn.Return.Name)
+
+	return env
 }
 
 func evalSetIndexExpression(left, index, value object.Object) object.Object {
@@ -1123,7 +1315,7 @@ func evalSetIndexExpression(left, index, value object.Object) object.Object {
 	case left.Type() == object.HASH_OBJ:
 		return evalSetHashIndexExpression(left, index, value)
 	default:
-		return newError("Index operator not supported %s", left.Inspect())
+		return newError(diag.TypeMismatch, "Index operator not supported %s", left.Inspect())
 	}
 }
 
@@ -1131,7 +1323,7 @@ func evalSetHashIndexExpression(hash, index, value object.Object) object.Object
 	hashObj := hash.(*object.Hash)
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(diag.TypeMismatch, "unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObj.Pairs[key.HashKey()]
@@ -1149,7 +1341,7 @@ func evalSetListIndexExpression(left, index, value object.Object) object.Object
 	max := int64(len(listObject.Elements) - 1)
 
 	if pos < 0 || pos > max {
-		return newError("list index out of range.")
+		return newError(diag.IndexOutOfRange, "list index out of range.")
 		// return NIL
 	}
 
@@ -1166,7 +1358,7 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalStringIndexExpression(left, index)
 	default:
-		return newError("Index operator not supported %s", left.Inspect())
+		return newError(diag.TypeMismatch, "Index operator not supported %s", left.Inspect())
 	}
 }
 
@@ -1176,7 +1368,7 @@ func evalStringIndexExpression(left, index object.Object) object.Object {
 	max := int64(len(stringObject.Value) - 1)
 
 	if pos < 0 || pos > max {
-		return newError("string index out of range.")
+		return newError(diag.IndexOutOfRange, "string index out of range.")
 		// return NIL
 	}
 
@@ -1189,7 +1381,7 @@ func evalListIndexExpression(left, index object.Object) object.Object {
 	max := int64(len(listObject.Elements) - 1)
 
 	if pos < 0 || pos > max {
-		return newError("list index out of range.")
+		return newError(diag.IndexOutOfRange, "list index out of range.")
 		// return NIL
 	}
 
@@ -1207,7 +1399,7 @@ func evalHashExpression(node *ast.Hash, env *object.Environment) object.Object {
 
 		hashkey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newError(diag.TypeMismatch, "unusable as hash key: %s", key.Type())
 		}
 
 		value := Eval(valueNode, env)
@@ -1227,12 +1419,12 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(diag.TypeMismatch, "unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObj.Pairs[key.HashKey()]
 	if !ok {
-		return newError("key error: %s", index.Inspect())
+		return newError(diag.KeyNotFound, "key error: %s", index.Inspect())
 	}
 
 	return pair.Value
@@ -1242,8 +1434,22 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 //***************************************************************************************
 //***************************************************************************************
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+//newError crea un error sin posicion, Eval le asigna la del nodo que lo devuelve.
+func newError(code string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+//newErrorAt crea un error que apunta al nodo 'node'.
+func newErrorAt(node ast.Node, code string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Code: code, Span: spanOf(node), Message: fmt.Sprintf(format, a...)}
+}
+
+func spanOf(node ast.Node) diag.Span {
+	//el token de una llamada es '(', se marca el nombre de la funcion.
+	if call, ok := node.(*ast.CallExpression); ok && call.Function != nil {
+		return spanOf(call.Function)
+	}
+	return diag.SpanAt(node.Pos(), len(node.TokenLiteral()))
 }
 
 func isError(obj object.Object) bool {
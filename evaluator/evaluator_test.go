@@ -3,6 +3,7 @@ package evaluator
 import (
 	"testing"
 
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/lexer"
 	"github.com/kenshindeveloper/april/object"
 	"github.com/kenshindeveloper/april/parser"
@@ -26,7 +27,19 @@ func TestFunctionApplication(t *testing.T) {
 }
 
 func TestForExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"var n:int = 0; for (i := 0; i < 10; i++) { if (i == 4) { break; } n = n + i; } n;", 6},
+		{"var n:int = 0; for (i := 0; i < 3; i++) { for (j := 0; j < 3; j++) { if (j == 1) { break; } n = n + 1; } n = n + 10; } n;", 33},
+		{"var n:int = 0; for (x := [1, 2, 3]) { for (y := [1, 2, 3]) { if (y == 2) { break; } n = n + x; } } n;", 6},
+		{"fn f() int { for (i := 0; i < 3; i++) { for (j := 0; j < 3; j++) { if (j == 1) { return i * 10 + j; } } } return -1; } f();", 1},
+	}
 
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
 }
 
 func TestListIndexExpresssion(t *testing.T) {
@@ -288,3 +301,234 @@ func TestEvalIntegerExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     string
+		expected string
+	}{
+		{"var x:int = 1;\nx + y;", diag.NameNotFound, "2:5 - identifier not found: y"},
+		{"var x:int = 1;\n  x / 0;", diag.DivisionByZero, "2:5 - division by zero"},
+		{"var l:list = [1, 2];\nlen(l, l);", diag.WrongArgumentNum, "2:1 - wrong number of arguments. got'2', want='1'"},
+		{"[1, 2][5];", diag.IndexOutOfRange, "1:7 - list index out of range."},
+	}
+
+	for i, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("tests[%d] - evaluated is not '*object.Error'.", i)
+		}
+
+		if err.Code != tt.code {
+			t.Errorf("tests[%d] - err.Code is not '%s'. got='%s'", i, tt.code, err.Code)
+		}
+
+		if err.Diagnostic().Error() != tt.expected {
+			t.Errorf("tests[%d] - err is not %q. got=%q", i, tt.expected, err.Diagnostic().Error())
+		}
+	}
+}
+
+func TestErrorTrace(t *testing.T) {
+	input := `fn div(a:int, b:int) int {
+	return a / b;
+}
+fn half(x:int) int {
+	return div(x, 0);
+}
+var f:func = fn(x:int) int { return half(x); };
+f(4);`
+
+	err, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("evaluated is not '*object.Error'.")
+	}
+
+	if err.Diagnostic().Error() != "2:11 - division by zero" {
+		t.Fatalf("err is not %q. got=%q", "2:11 - division by zero", err.Diagnostic().Error())
+	}
+
+	expected := []struct {
+		function string
+		call     string
+	}{
+		{"div", "5:9"},
+		{"half", "7:37"},
+		{"<closure>", "8:1"},
+	}
+
+	if len(err.Trace) != len(expected) {
+		t.Fatalf("len(err.Trace) is not %d. got=%d", len(expected), len(err.Trace))
+	}
+
+	for i, tt := range expected {
+		if err.Trace[i].Function != tt.function || err.Trace[i].Call.String() != tt.call {
+			t.Errorf("err.Trace[%d] is not '%s %s'. got='%s %s'", i, tt.function, tt.call, err.Trace[i].Function, err.Trace[i].Call)
+		}
+	}
+
+	if err := testEval("fn id(x:int) int { return x; } id(1); 1 / 0;").(*object.Error); len(err.Trace) != 0 {
+		t.Fatalf("err.Trace is not empty outside functions. got=%d", len(err.Trace))
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var r:string = ""; try { 1 / 0; } catch (e) { r = e.kind + ": " + e.message; } r;`, "DivisionByZero: division by zero"},
+		{`var r:string = ""; try { [1, 2][5]; } catch (e) { r = e.kind; } r;`, "IndexOutOfRange"},
+		{`var r:string = ""; try { open("no/existe.april"); } catch (e) { r = e.kind; } r;`, "IOError"},
+		{`var r:string = ""; try { throw "fallo"; } catch (e) { r = e.kind + ": " + e.message; } r;`, "Error: fallo"},
+		{`fn check(x:int) int { if (x < 0) { throw "negative"; } return x; } var r:string = ""; try { check(-1); r = "no"; } catch (e) { r = e.message; } r;`, "negative"},
+		{`var r:string = ""; try { r = "try"; } catch (e) { r = "catch"; } finally { r = r + " finally"; } r;`, "try finally"},
+		{`var r:string = ""; try { 1 / 0; } catch (e) { r = "catch"; } finally { r = r + " finally"; } r;`, "catch finally"},
+		{`var r:string = ""; for (i := 0; i < 5; i++) { try { if (i == 2) { break; } } finally { r = r + str(i); } } r;`, "012"},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("tests[%d] - evaluated is not '*object.String'. got='%T' (%s)", i, evaluated, evaluated.Inspect())
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("tests[%d] - str.Value is not '%s'. got='%s'", i, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "fallo";`, "1:1 - fallo"},
+		{`try { throw "fallo"; } finally { }`, "1:7 - fallo"},
+		{`try { 1 / 0; } catch (e) { throw e; }`, "1:28 - division by zero"},
+	}
+
+	for i, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("tests[%d] - evaluated is not '*object.Error'.", i)
+		}
+
+		if err.Diagnostic().Error() != tt.expected {
+			t.Errorf("tests[%d] - err is not %q. got=%q", i, tt.expected, err.Diagnostic().Error())
+		}
+	}
+}
+
+func TestReturnType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn f() int { return 5; } f();", int64(5)},
+		{"fn f() double { return 5; } f();", 5.0},
+		{"var f:func = fn() double { return 2; }; f();", 2.0},
+		{"fn f() func { return fn() int { return 1; }; } var g:func = f(); g();", int64(1)},
+		{"fn f() int { return \"5\"; } f();", "1:14 - function 'f' must return 'int', got 'STRING'"},
+		{"var f:func = fn() string { return 1; };\nf();", "1:28 - function '<closure>' must return 'string', got 'INTEGER'"},
+		{"fn f(x:int) int {\n\tif (x > 0) { return x; }\n}\nf(-1);", "1:4 - missing return in function 'f', expected 'int'"},
+		{"var f:func = fn(x:int) bool { x; };\nf(1);", "1:29 - missing return in function '<closure>', expected 'bool'"},
+		{"fn f() { return 1; } f();", "1:10 - function 'f' has no return type, got 'INTEGER'"},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case float64:
+			double, ok := evaluated.(*object.Double)
+			if !ok || double.Value != expected {
+				t.Errorf("tests[%d] - evaluated is not Double %f. got=%T (%s)", i, expected, evaluated, evaluated.Inspect())
+			}
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("tests[%d] - evaluated is not '*object.Error'. got=%T (%s)", i, evaluated, evaluated.Inspect())
+				continue
+			}
+			if err.Diagnostic().Error() != expected {
+				t.Errorf("tests[%d] - err is not %q. got=%q", i, expected, err.Diagnostic().Error())
+			}
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn loop(n:int, acc:int) int { if (n == 0) { return acc; } return loop(n - 1, acc + 1); } loop(100000, 0);", int64(100000)},
+		{"var count:func = fn(n:int) int { if (n == 0) { return 7; } return count(n - 1); }; count(100000);", int64(7)},
+		{"fn even(n:int) bool { if (n == 0) { return true; } return odd(n - 1); }\nfn odd(n:int) bool { if (n == 0) { return false; } return even(n - 1); }\neven(3001);", false},
+		{"fn even(n:int) bool { if (n == 0) { return true; } return odd(n - 1); }\nfn odd(n:int) bool { if (n == 0) { return false; } return even(n - 1); }\neven(100001);", false},
+		{"fn a(n:int) string { if (n == 0) { return \"x\"; } return b(n - 1); } fn b(n:int) int { return a(n); } b(100000);", "1:87 - function 'b' must return 'int', got 'STRING'"},
+		{"fn half(n:int) double { return twice(n); } fn twice(n:int) int { return n * 2; } half(3);", 6.0},
+		{"fn fail() int { return 1 / 0; } fn f() int { try { return fail(); } catch (e) { return 2; } } f();", int64(2)},
+		{"global s:string = \"\"; fn g() int { s = s + \"g\"; return 1; } fn f() int { try { return g(); } finally { s = s + \"f\"; } } f(); s;", "gf"},
+		{"fn f(n:int) string { return g(n); } fn g(n:int) int { return n; } f(1);", "1:22 - function 'f' must return 'string', got 'INTEGER'"},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case float64:
+			double, ok := evaluated.(*object.Double)
+			if !ok || double.Value != expected {
+				t.Errorf("tests[%d] - evaluated is not Double %f. got=%T (%s)", i, expected, evaluated, evaluated.Inspect())
+			}
+		case string:
+			if err, ok := evaluated.(*object.Error); ok {
+				if err.Diagnostic().Error() != expected {
+					t.Errorf("tests[%d] - err is not %q. got=%q", i, expected, err.Diagnostic().Error())
+				}
+				continue
+			}
+			if str, ok := evaluated.(*object.String); !ok || str.Value != expected {
+				t.Errorf("tests[%d] - evaluated is not String %q. got=%T (%s)", i, expected, evaluated, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	input := "fn down(n:int) int { if (n == 0) { return 0; } return 1 + down(n - 1); }\n"
+
+	testIntegerObject(t, testEval(input+"down(1000);"), 1000)
+
+	err, ok := testEval(input + "down(1000000);").(*object.Error)
+	if !ok {
+		t.Fatalf("evaluated is not '*object.Error'.")
+	}
+	if err.Code != diag.StackOverflow {
+		t.Fatalf("err.Code is not %s. got=%s (%s)", diag.StackOverflow, err.Code, err.Message)
+	}
+	if len(err.Trace) != object.DefaultMaxDepth+1 {
+		t.Fatalf("len(err.Trace) is not %d. got=%d", object.DefaultMaxDepth+1, len(err.Trace))
+	}
+
+	caught := testEval(input + "var kind:string = \"\"; try { down(1000000); } catch (e) { kind = e.kind; } kind;")
+	if str, ok := caught.(*object.String); !ok || str.Value != "StackOverflow" {
+		t.Fatalf("caught kind is not 'StackOverflow'. got=%s", caught.Inspect())
+	}
+
+	env := object.NewEnvironment()
+	env.Runtime().SetMaxDepth(10)
+	program := parser.New(lexer.New(input + "down(20);")).ParserProgram()
+	if err, ok := Eval(program, env).(*object.Error); !ok || err.Code != diag.StackOverflow {
+		t.Fatalf("SetMaxDepth(10) does not limit down(20).")
+	}
+}
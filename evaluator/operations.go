@@ -0,0 +1,286 @@
+package evaluator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/token"
+)
+
+//Las operaciones de este archivo no dependen del arbol ni del entorno, las usa tambien
+//el paquete vm para que los dos backends tengan la misma semantica. Los errores que
+//devuelven no tienen posicion, la asigna quien las llama.
+
+//Infix aplica el operador binario 'operator' a 'left' y 'right'.
+func Infix(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+//Prefix aplica el operador unario 'operator' ('-' o 'not') a 'right'.
+func Prefix(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+//Index devuelve el elemento 'index' de una lista, un map o un string.
+func Index(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+//SetIndex guarda 'value' en la posicion 'index' de una lista o un map.
+func SetIndex(left, index, value object.Object) object.Object {
+	return evalSetIndexExpression(left, index, value)
+}
+
+func IsTruthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+//Builtin devuelve la funcion del lenguaje llamada 'name'.
+func Builtin(name string) (*object.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+//BuiltinNames devuelve los nombres de las funciones del lenguaje en orden alfabetico.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Declare comprueba que 'value' se pueda guardar en la declaracion var name:typeName.
+func Declare(name, typeName string, value object.Object) *object.Error {
+	return checkDeclaration(name, typeName, value)
+}
+
+//Implicit comprueba que 'value' se pueda guardar en la declaracion name := value.
+func Implicit(name string, value object.Object) *object.Error {
+	if !isBasicDataType(value) {
+		return newError(diag.TypeMismatch, "declaration not compatible '%s' := '%s'.", name, value.Type())
+	}
+	return nil
+}
+
+//Assign devuelve el valor que se guarda al asignar 'value' a una variable que contiene
+//'current'.
+func Assign(current, value object.Object) object.Object {
+	return assignValue(current, value)
+}
+
+//Argument convierte el argumento 'arg' al tipo 'typeName' del parametro, devuelve false
+//si no es compatible.
+func Argument(typeName string, arg object.Object) (object.Object, bool) {
+	return convertArgument(typeName, arg)
+}
+
+//ReturnValue comprueba que el valor 'value' devuelto por la funcion 'name' sea del tipo
+//declarado 'declared' y lo convierte.
+func ReturnValue(name string, declared *ast.Identifier, value object.Object) object.Object {
+	if declared == nil {
+		return newError(diag.TypeMismatch, "function '%s' has no return type, got '%s'", name, value.Type())
+	}
+
+	types, known := returnTypes[declared.Name]
+	if !known {
+		return value
+	}
+
+	for _, typ := range types {
+		if value.Type() != typ {
+			continue
+		}
+		if integer, ok := value.(*object.Integer); ok && declared.Name == "double" {
+			return &object.Double{Value: float64(integer.Value)}
+		}
+		return value
+	}
+
+	return newError(diag.TypeMismatch, "function '%s' must return '%s', got '%s'", name, declared.Name, value.Type())
+}
+
+//MissingReturn es el error de una funcion con tipo 'declared' que termina sin return.
+func MissingReturn(name string, declared *ast.Identifier) *object.Error {
+	return newError(diag.MissingReturn, "missing return in function '%s', expected '%s'", name, declared.Name)
+}
+
+//Apply llama a la funcion 'fn' con los argumentos 'args' fuera de una expresion del
+//programa, la usa el paquete april para llamar funciones desde Go. Las funciones del
+//lenguaje usan los flujos de 'env'.
+func Apply(env *object.Environment, fn object.Object, args []object.Object) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		if len(function.Parameters) != len(args) {
+			return newError(diag.WrongArgumentNum, "count parameters not match.")
+		}
+	case *object.FunctionClosure:
+		if len(function.Parameters) != len(args) {
+			return newError(diag.WrongArgumentNum, "count parameters not match.")
+		}
+	}
+	return applyFunction(fn, args, token.Position{}, env.Runtime())
+}
+
+//Call llama a la funcion 'fn' desde 'call' con la ejecucion 'runtime'. La vm la usa para
+//las funciones de los modulos, que se ejecutan con el evaluador.
+func Call(runtime *object.Runtime, fn object.Object, args []object.Object, call token.Position) object.Object {
+	return applyFunction(fn, args, call, runtime)
+}
+
+//Import carga el modulo de la sentencia 'node' con el Importer de 'runtime'.
+func Import(runtime *object.Runtime, node *ast.ImportStatement) object.Object {
+	if runtime.Importer == nil {
+		return newErrorAt(node, diag.ImportNotFound, "modules can not be imported here: '%s'.", node.Path.Value)
+	}
+	module := runtime.Importer.Import(runtime, node.Token.Pos.File, node.Path.Value)
+	if err, ok := module.(*object.Error); ok && !err.Span.Start.IsValid() {
+		err.Span = spanOf(node.Path)
+	}
+	return module
+}
+
+//moduleField devuelve el valor exportado 'name' del modulo 'module'.
+func moduleField(module *object.Module, name string) object.Object {
+	if !object.Exported(name) {
+		return newError(diag.NameNotFound, "'%s' is not exported by %s.", name, module.Inspect())
+	}
+	value, ok := module.Export(name)
+	if !ok {
+		return newError(diag.NameNotFound, "%s has no '%s'.", module.Inspect(), name)
+	}
+	return value
+}
+
+//Throw crea el error que produce la sentencia 'throw value'.
+func Throw(value object.Object) *object.Error {
+	return thrownError(value, object.NewEnvironment())
+}
+
+//Caught devuelve el valor que recibe el catch que atrapa el error 'err'.
+func Caught(err *object.Error) object.Object {
+	return caughtValue(err, object.NewEnvironment())
+}
+
+//Struct crea el valor de la expresion struct 'node' con sus campos inicializados.
+func Struct(node *ast.Struct) object.Object {
+	return evalStructExpression(node, object.NewEnvironment())
+}
+
+//Field devuelve el campo 'name' del struct 'obj'.
+func Field(obj object.Object, name string) object.Object {
+	if module, ok := obj.(*object.Module); ok {
+		return moduleField(module, name)
+	}
+
+	dataStruct, ok := obj.(*object.Struct)
+	if !ok {
+		return newError(diag.TypeMismatch, "error is not a struct type.")
+	}
+
+	value, ok := dataStruct.Env.Get(name)
+	if !ok {
+		return newError(diag.NameNotFound, "var '%s' is not define.", name)
+	}
+	return value
+}
+
+//SetField guarda 'value' en el campo 'name' del struct 'obj'.
+func SetField(obj object.Object, name string, value object.Object) object.Object {
+	if module, ok := obj.(*object.Module); ok {
+		return newError(diag.TypeMismatch, "can not assign '%s' of %s.", name, module.Inspect())
+	}
+
+	dataStruct, ok := obj.(*object.Struct)
+	if !ok {
+		return newError(diag.TypeMismatch, "error is not a struct type.")
+	}
+
+	current, ok := dataStruct.Env.Get(name)
+	if !ok {
+		return newError(diag.NameNotFound, "var '%s' is not define.", name)
+	}
+	return setField(dataStruct, name, current, value)
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+func checkDeclaration(name, typeName string, val object.Object) *object.Error {
+	var expected, got string
+
+	switch val.(type) {
+	case *object.Integer:
+		expected, got = "int", "INTEGER"
+	case *object.Double:
+		expected, got = "double", "DOUBLE"
+	case *object.Boolean:
+		expected, got = "bool", "BOOL"
+	case *object.String:
+		expected, got = "string", "STRING"
+	case *object.List:
+		expected, got = "list", "LIST"
+	case *object.Hash:
+		expected, got = "map", "MAP"
+	case *object.Stream:
+		expected, got = "stream", "STREAM"
+	case *object.FunctionClosure, *object.Closure:
+		expected, got = "func", "FUNC"
+	default:
+		return nil
+	}
+
+	if typeName != expected {
+		return newError(diag.TypeMismatch, "declaration error: var %s:%s = %s", name, strings.ToUpper(typeName), got)
+	}
+	return nil
+}
+
+func assignValue(current, value object.Object) object.Object {
+	if value.Type() == current.Type() {
+		return value
+	}
+	if current.Type() == object.DOUBLE_OBJ && value.Type() == object.INTEGER_OBJ {
+		return &object.Double{Value: float64(value.(*object.Integer).Value)}
+	}
+	return newError(diag.TypeMismatch, "assign not compatible '%s' : '%s'.", value.Type(), current.Type())
+}
+
+func setField(dataStruct *object.Struct, name string, current, value object.Object) object.Object {
+	if current.Type() == object.DOUBLE_OBJ && value.Type() == object.INTEGER_OBJ {
+		dataStruct.Env.Set(name, &object.Double{Value: float64(value.(*object.Integer).Value)})
+		return NIL
+	} else if current.Type() != value.Type() {
+		return newError(diag.TypeMismatch, "assignment is not compatible.")
+	}
+
+	dataStruct.Env.Set(name, value)
+	return NIL
+}
+
+func convertArgument(typeName string, arg object.Object) (object.Object, bool) {
+	switch {
+	case typeName == "int" && arg.Type() == object.INTEGER_OBJ:
+		return arg, true
+	case typeName == "double" && arg.Type() == object.INTEGER_OBJ:
+		return &object.Double{Value: float64(arg.(*object.Integer).Value)}, true
+	case typeName == "double" && arg.Type() == object.DOUBLE_OBJ:
+		return arg, true
+	case typeName == "bool" && arg.Type() == object.BOOLEAN_OBJ:
+		return arg, true
+	case typeName == "string" && arg.Type() == object.STRING_OBJ:
+		return arg, true
+	case typeName == "list" && arg.Type() == object.LIST_OBJ:
+		return arg, true
+	case typeName == "map" && arg.Type() == object.HASH_OBJ:
+		return arg, true
+	case typeName == "func" && arg.Type() == object.CLOSURE_OBJ:
+		return arg, true
+	}
+	return nil, false
+}
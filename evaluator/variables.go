@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/object"
+)
+
+//Las variables de un programa que paso por el paquete resolver se guardan en posiciones
+//fijas de cada entorno. Los identificadores sin Binding se buscan por nombre como antes.
+
+//lookup devuelve el valor de la variable 'ident' visible desde 'env'.
+func lookup(ident *ast.Identifier, env *object.Environment) (object.Object, bool) {
+	switch binding := ident.Binding; {
+	case binding == nil:
+		return env.Get(ident.Name)
+	case binding.Global:
+		return env.GetGlobal(ident.Name)
+	default:
+		return env.GetAt(binding.Depth, binding.Slot)
+	}
+}
+
+//assign cambia el valor de la variable 'ident', que ya existe.
+func assign(ident *ast.Identifier, env *object.Environment, value object.Object) {
+	if binding := ident.Binding; binding != nil && !binding.Global {
+		env.SetAt(binding.Depth, binding.Slot, value)
+		return
+	}
+	env.Set(ident.Name, value)
+}
+
+//declare crea la variable 'ident' en 'env'.
+func declare(ident *ast.Identifier, env *object.Environment, value object.Object) {
+	if binding := ident.Binding; binding != nil && !binding.Global {
+		env.SetAt(0, binding.Slot, value)
+		return
+	}
+	env.Save(ident.Name, value)
+}
+
+//exists indica si 'ident' ya es una variable visible desde 'env'. Las declaraciones que
+//paso el resolver ya se comprobaron antes de ejecutar el programa.
+func exists(ident *ast.Identifier, env *object.Environment) bool {
+	if ident.Binding != nil {
+		return false
+	}
+	_, ok := env.Get(ident.Name)
+	return ok
+}
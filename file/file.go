@@ -1,45 +1,175 @@
 package file
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
+	"strings"
 
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/compiler"
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/evaluator"
 	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/module"
 	"github.com/kenshindeveloper/april/object"
 	"github.com/kenshindeveloper/april/parser"
 	"github.com/kenshindeveloper/april/repl"
+	"github.com/kenshindeveloper/april/resolver"
+	"github.com/kenshindeveloper/april/typecheck"
+	"github.com/kenshindeveloper/april/vm"
 )
 
-func Start(w io.Writer, r io.Reader, path string) {
-	if len(path) <= len(".april") || path[len(path)-6:len(path)] != ".april" {
-		log.Fatalf("incorrect path file.")
+//Backends con los que se puede ejecutar un archivo.
+const (
+	Eval = "eval" //recorre el arbol con el paquete evaluator
+	VM   = "vm"   //compila a bytecode y lo ejecuta en el paquete vm
+)
+
+//Codigos de salida de Start. Un programa que termina con exit(n) sale con n.
+const (
+	StatusOK      = 0 //el programa termino sin errores
+	StatusError   = 1 //el programa termino con un error de ejecucion
+	StatusInvalid = 2 //el archivo no se pudo leer o tiene errores antes de ejecutarse
+)
+
+//Etapas de los errores de Check.
+const (
+	ParseStage   = "parser errors"
+	TypeStage    = "type errors"
+	ResolveStage = "resolve errors"
+)
+
+//Error son los errores que encontro Check en la etapa Stage.
+type Error struct {
+	Stage       string
+	Diagnostics []*diag.Diagnostic
+}
+
+func (e *Error) Error() string {
+	messages := []string{}
+	for _, d := range e.Diagnostics {
+		messages = append(messages, d.Error())
+	}
+	return e.Stage + ": " + strings.Join(messages, "; ")
+}
+
+//Options son las opciones con las que Start ejecuta un archivo.
+type Options struct {
+	Backend string             //Eval o VM, "" es Eval
+	FS      *object.FileSystem //permisos de las funciones de archivos y de los imports, nil permite todo
+	Args    []string           //argumentos que el programa recibe con argv()
+	Stderr  io.Writer          //errores del archivo y salida de errores del programa, nil es os.Stderr
+}
+
+//Check lee el archivo 'path' y lo verifica sin ejecutarlo. Devuelve el programa y el codigo
+//leido, o un *Error con los errores de sintaxis, de tipos o de ambito. 'sources' guarda el
+//codigo del archivo para mostrar los errores. Los imports sin 'as' se leen con los permisos
+//'fs', nil permite todo.
+func Check(path string, sources diag.Sources, fs *object.FileSystem) (*ast.Program, error) {
+	if !strings.HasSuffix(path, ".april") {
+		return nil, fmt.Errorf("incorrect path file: '%s' is not an .april file", path)
 	}
 
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatalf("error to open file: '%s'", path)
+		return nil, fmt.Errorf("error to open file: '%s'", path)
 	}
+	sources[path] = string(data)
 
-	l := lexer.New(string(data))
+	l := lexer.NewFile(path, string(data))
+	l.SetFileSystem(fs)
 	p := parser.New(l)
 	program := p.ParserProgram()
-	if len(p.Error()) > 0 {
-		repl.PrintParseError(w, p.Error())
-		os.Exit(2)
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		return nil, &Error{Stage: ParseStage, Diagnostics: diagnostics}
+	}
+
+	if diagnostics := typecheck.Check(program); len(diagnostics) > 0 {
+		return nil, &Error{Stage: TypeStage, Diagnostics: diagnostics}
+	}
+
+	if diagnostics := resolver.Resolve(program); len(diagnostics) > 0 {
+		return nil, &Error{Stage: ResolveStage, Diagnostics: diagnostics}
+	}
+	return program, nil
+}
+
+//Start ejecuta el archivo 'path' con las opciones 'options' y devuelve el codigo de
+//salida del programa. El resultado se muestra en 'w' y los errores en options.Stderr.
+func Start(w io.Writer, r io.Reader, path string, options Options) int {
+	stderr := options.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	sources := diag.Sources{}
+	program, err := Check(path, sources, options.FS)
+	if err != nil {
+		PrintError(stderr, err, sources)
+		return StatusInvalid
+	}
+
+	streams := object.NewIO(w, stderr, r)
+	loader := module.New(sources)
+	loader.Main(path)
+	var evaluated object.Object
+	switch options.Backend {
+	case VM:
+		c := compiler.New()
+		if diagnostics := c.Compile(program); len(diagnostics) > 0 {
+			repl.PrintCompileError(stderr, diagnostics, sources)
+			return StatusInvalid
+		}
+		machine := vm.New(c.Bytecode())
+		machine.SetIO(streams)
+		machine.SetFileSystem(options.FS)
+		machine.SetImporter(loader)
+		machine.SetArgs(options.Args)
+		evaluated = machine.Run()
+	default:
+		env := object.NewEnvironment()
+		env.Runtime().IO = streams
+		env.Runtime().FS = options.FS
+		env.Runtime().Importer = loader
+		env.Runtime().Args = options.Args
+		evaluated = evaluator.Eval(program, env)
 	}
 
-	env := object.NewEnvironment()
-	evaluated := evaluator.Eval(program, env)
 	if evaluated != nil {
-		switch evaluated.(type) {
+		switch evaluated := evaluated.(type) {
 		case *object.Nil:
 			io.WriteString(w, "")
+		case *object.Error:
+			if status, ok := repl.ExitStatus(evaluated); ok {
+				return status
+			}
+			repl.PrintRuntimeError(stderr, evaluated, sources)
+			return StatusError
 		default:
 			io.WriteString(w, evaluated.Inspect())
 			io.WriteString(w, "\n")
 		}
 	}
+	return StatusOK
+}
+
+//PrintError muestra un error de Check: los de un *Error con un extracto del codigo de
+//'sources' y los demas con su mensaje.
+func PrintError(w io.Writer, err error, sources diag.Sources) {
+	checkErr, ok := err.(*Error)
+	if !ok {
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	switch checkErr.Stage {
+	case ParseStage:
+		repl.PrintParseError(w, checkErr.Diagnostics, sources)
+	case TypeStage:
+		repl.PrintTypeError(w, checkErr.Diagnostics, sources)
+	default:
+		repl.PrintResolveError(w, checkErr.Diagnostics, sources)
+	}
 }
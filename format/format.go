@@ -0,0 +1,453 @@
+//Package format escribe el codigo de un programa de april con un formato unico: cuatro
+//espacios de indentacion, una sentencia por linea, ';' al final de las sentencias simples
+//y los comentarios del archivo en su lugar.
+package format
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/parser"
+)
+
+//INDENT es la indentacion de cada nivel de bloque.
+const INDENT = "    "
+
+//atom es la precedencia de las expresiones que nunca necesitan parentesis.
+const atom = parser.INDEX + 1
+
+//Source da formato al codigo 'source' del archivo 'name'. Si tiene errores de sintaxis
+//devuelve sus diagnosticos. Los archivos de los imports no se leen.
+func Source(name, source string) (string, []*diag.Diagnostic) {
+	l := lexer.NewFile(name, source)
+	l.SkipImports()
+	p := parser.New(l)
+	program := p.ParserProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		return "", diagnostics
+	}
+
+	pr := &printer{source: source, lines: strings.Split(source, "\n"), comments: program.Comments}
+	pr.program(program)
+	return pr.out.String(), nil
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//printer escribe el arbol en 'out'. Los comentarios se escriben antes de la sentencia o
+//de la llave que los sigue en el codigo, y los que estaban al final de una linea se
+//agregan al final de la ultima linea escrita.
+type printer struct {
+	out      bytes.Buffer
+	source   string
+	lines    []string
+	comments []*ast.Comment //comentarios que faltan por escribir
+	indent   int
+	start    bool //no se escribio nada en el bloque actual
+}
+
+func (p *printer) program(program *ast.Program) {
+	p.start = true
+	p.statements(program.Statements)
+	p.flush(len(p.source) + 1)
+}
+
+//statements escribe una sentencia por linea con los comentarios que estan antes de cada
+//una. Las lineas en blanco entre sentencias se conservan, pero solo una.
+func (p *printer) statements(statements []ast.Statement) {
+	for i, stmt := range statements {
+		p.flush(stmt.Pos().Offset)
+		p.blank(stmt.Pos().Line)
+		p.newLine()
+
+		var next ast.Statement
+		if i+1 < len(statements) {
+			next = statements[i+1]
+		}
+		p.statement(stmt, next)
+		p.out.WriteString("\n")
+		p.start = false
+	}
+}
+
+//flush escribe los comentarios que estan antes de 'offset'.
+func (p *printer) flush(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos().Offset < offset {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		text := strings.TrimRight(comment.Text, " \t")
+
+		if p.trailing(comment) && p.out.Len() > 0 {
+			p.out.Truncate(p.out.Len() - 1)
+			p.out.WriteString(" " + text + "\n")
+			continue
+		}
+		p.blank(comment.Pos().Line)
+		p.newLine()
+		p.out.WriteString(text + "\n")
+		p.start = false
+	}
+}
+
+//pending indica si hay comentarios antes de 'offset'.
+func (p *printer) pending(offset int) bool {
+	return len(p.comments) > 0 && p.comments[0].Pos().Offset < offset
+}
+
+//trailing indica si el comentario esta al final de una linea con codigo.
+func (p *printer) trailing(comment *ast.Comment) bool {
+	offset := comment.Pos().Offset
+	start := strings.LastIndex(p.source[:offset], "\n") + 1
+	return strings.TrimSpace(p.source[start:offset]) != ""
+}
+
+//blank escribe una linea en blanco si en el codigo la linea anterior a 'line' esta vacia.
+func (p *printer) blank(line int) {
+	if p.start || line < 2 || line > len(p.lines) {
+		return
+	}
+	if strings.TrimSpace(p.lines[line-2]) == "" {
+		p.out.WriteString("\n")
+	}
+}
+
+func (p *printer) newLine() {
+	p.out.WriteString(strings.Repeat(INDENT, p.indent))
+}
+
+func (p *printer) write(s ...string) {
+	for _, str := range s {
+		p.out.WriteString(str)
+	}
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//statement escribe 'stmt' sin la indentacion ni el salto de linea. 'next' es la sentencia
+//que la sigue en el bloque, nil si es la ultima.
+func (p *printer) statement(stmt ast.Statement, next ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.VarStatement:
+		p.declaration("var", stmt.Name, stmt.Type, stmt.Value)
+	case *ast.GlobalStatement:
+		p.declaration("global", stmt.Name, stmt.Type, stmt.Value)
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+		//un if sin ';' seguido de '(', '[' o '-' se leeria como una sola expresion.
+		if _, ok := stmt.Expression.(*ast.IfExpression); !ok || continues(next) {
+			p.write(";")
+		}
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(stmt.Expression)
+		p.write(";")
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expression(stmt.Expression)
+		p.write(";")
+	case *ast.BreakStatement:
+		p.write("break;")
+	case *ast.ImportStatement:
+		p.write("import \"", stmt.Path.Value, "\"")
+		if stmt.Name != nil {
+			p.write(" as ", stmt.Name.Name)
+		}
+	case *ast.Function:
+		p.write("fn ", stmt.Name.Name)
+		p.function(stmt.Parameters, stmt.Type, stmt.Body)
+	case *ast.ForStatement:
+		p.write("for (")
+		if stmt.Declaration != nil {
+			p.expression(stmt.Declaration)
+			p.write("; ")
+			p.expression(stmt.Condition)
+			p.write("; ")
+			p.expression(stmt.Operation)
+		} else {
+			p.expression(stmt.Condition)
+		}
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.TryStatement:
+		p.write("try ")
+		p.block(stmt.Block)
+		if stmt.Catch != nil {
+			p.write(" catch (", stmt.Parameter.Name, ") ")
+			p.block(stmt.Catch)
+		}
+		if stmt.Finally != nil {
+			p.write(" finally ")
+			p.block(stmt.Finally)
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+//declaration escribe 'var nombre:tipo = valor;'. Sin valor en el codigo el parser
+//agrega el valor por defecto del tipo en la posicion del ';', ese no se escribe.
+func (p *printer) declaration(keyword string, name, typ *ast.Identifier, value ast.Expression) {
+	p.write(keyword, " ", name.Name, ":", typ.Name)
+	if offset := value.Pos().Offset; offset < len(p.source) && p.source[offset] != ';' {
+		p.write(" = ")
+		p.expression(value)
+	}
+	p.write(";")
+}
+
+func (p *printer) function(parameters []*ast.FunctionParameters, typ *ast.Identifier, body *ast.BlockStatement) {
+	params := []string{}
+	for _, param := range parameters {
+		params = append(params, param.Name.Name+":"+param.Type.Name)
+	}
+	p.write("(", strings.Join(params, ", "), ") ")
+	if typ != nil {
+		p.write(typ.Name, " ")
+	}
+	p.block(body)
+}
+
+//block escribe '{', las sentencias del bloque un nivel mas adentro y '}'. Un bloque sin
+//sentencias ni comentarios es '{}'.
+func (p *printer) block(block *ast.BlockStatement) {
+	end := block.Rbrace.Pos.Offset
+	if len(block.Statements) == 0 && !p.pending(end) {
+		p.write("{}")
+		return
+	}
+
+	p.write("{\n")
+	p.indent++
+	p.start = true
+	p.statements(block.Statements)
+	p.flush(end)
+	p.indent--
+	p.start = false
+	p.newLine()
+	p.write("}")
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+func (p *printer) expression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		p.write(expr.Name)
+	case *ast.Nil:
+		p.write("nil")
+	case *ast.Integer:
+		p.write(expr.Token.Literal)
+	case *ast.Double:
+		p.write(expr.Token.Literal)
+	case *ast.Boolean:
+		p.write(expr.Token.Literal)
+	case *ast.String:
+		p.write("\"", expr.Value, "\"")
+	case *ast.Stream:
+		p.write(expr.Token.Literal)
+	case *ast.PrefixExpression:
+		p.write(expr.Operator)
+		if expr.Operator == "not" {
+			p.write(" ")
+		}
+		right, ok := expr.Right.(*ast.PrefixExpression)
+		p.operand(expr.Right, precedence(expr.Right) < parser.PREFIX || ok && right.Operator == "-" && expr.Operator == "-")
+	case *ast.InfixExpression:
+		p.infix(expr)
+	case *ast.ImplicitDeclarationExpression:
+		p.expression(expr.Left)
+		p.write(" := ")
+		p.expression(expr.Right)
+	case *ast.AssignExpression:
+		p.expression(expr.Left)
+		p.write(" = ")
+		p.expression(expr.Right)
+	case *ast.AssignOperationExpression:
+		p.expression(expr.Left)
+		p.write(" ", expr.Operator, " ")
+		p.expression(expr.Right)
+	case *ast.PostfixExpression:
+		p.write(expr.Left.Name, expr.Operator)
+	case *ast.CallExpression:
+		p.operand(expr.Function, precedence(expr.Function) < parser.CALL)
+		p.write("(")
+		p.list(expr.Arguments)
+		p.write(")")
+	case *ast.IndexExpression:
+		p.operand(expr.Left, precedence(expr.Left) < parser.INDEX)
+		p.write("[")
+		p.expression(expr.Index)
+		p.write("]")
+	case *ast.List:
+		p.write("[")
+		p.list(expr.Elements)
+		p.write("]")
+	case *ast.Hash:
+		p.hash(expr)
+	case *ast.Struct:
+		p.structure(expr)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(expr.Codition)
+		p.write(") ")
+		p.block(expr.Consequence)
+		if expr.Alternative != nil {
+			p.write(" else ")
+			p.block(expr.Alternative)
+		}
+	case *ast.FunctionClosure:
+		p.write("fn")
+		p.function(expr.Parameters, expr.Type, expr.Body)
+	}
+}
+
+//infix escribe los operandos con parentesis solo donde el parser los necesita, y
+//tambien cuando se mezclan 'and' y 'or' con otros operadores, que tienen su misma
+//precedencia o una mayor y se leen mal sin ellos.
+func (p *printer) infix(expr *ast.InfixExpression) {
+	preceden := parser.Precedence(expr.Token.Type)
+
+	left := precedence(expr.Left) < preceden || mixed(expr, expr.Left)
+	right := precedence(expr.Right) <= preceden || mixed(expr, expr.Right)
+	if expr.Operator == "." {
+		//el campo de 'a.b' es solo el nombre, una llamada o un indice va entre parentesis.
+		right = precedence(expr.Right) < atom || isPostfix(expr.Right)
+	}
+
+	p.operand(expr.Left, left)
+	if expr.Operator == "." {
+		p.write(".")
+	} else {
+		p.write(" ", expr.Operator, " ")
+	}
+	p.operand(expr.Right, right)
+}
+
+func (p *printer) operand(expr ast.Expression, parens bool) {
+	if parens {
+		p.write("(")
+	}
+	p.expression(expr)
+	if parens {
+		p.write(")")
+	}
+}
+
+func (p *printer) list(elements []ast.Expression) {
+	for i, element := range elements {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(element)
+	}
+}
+
+//hash escribe los pares en el orden en que estan en el codigo.
+func (p *printer) hash(hash *ast.Hash) {
+	keys := []ast.Expression{}
+	for key := range hash.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Pos().Offset < keys[j].Pos().Offset
+	})
+
+	p.write("{")
+	for i, key := range keys {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(key)
+		p.write(": ")
+		p.expression(hash.Pairs[key])
+	}
+	p.write("}")
+}
+
+//structure escribe un campo por linea, en el orden en que estan en el codigo.
+func (p *printer) structure(s *ast.Struct) {
+	names := []*ast.Identifier{}
+	for name := range s.Element {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].Pos().Offset < names[j].Pos().Offset
+	})
+
+	p.write("{\n")
+	p.indent++
+	for i, name := range names {
+		p.newLine()
+		p.write(name.Name, ":", s.Element[name].Name)
+		if i < len(names)-1 {
+			p.write(",")
+		}
+		p.write("\n")
+	}
+	p.indent--
+	p.newLine()
+	p.write("}")
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//precedence devuelve la precedencia con la que el parser forma 'expr'. Las llamadas y
+//los indices se agregan a la izquierda de cualquier expresion, por eso no necesitan
+//parentesis como operando izquierdo.
+func precedence(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(expr.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.ImplicitDeclarationExpression, *ast.AssignExpression, *ast.AssignOperationExpression:
+		return parser.LESSVALUE
+	}
+	return atom
+}
+
+func isPostfix(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.CallExpression, *ast.IndexExpression:
+		return true
+	}
+	return false
+}
+
+func isLogic(expr ast.Expression) bool {
+	infix, ok := expr.(*ast.InfixExpression)
+	return ok && (infix.Operator == "and" || infix.Operator == "or")
+}
+
+//mixed indica si 'operand' es una operacion que se mezcla con 'and' u 'or' en 'expr'.
+func mixed(expr *ast.InfixExpression, operand ast.Expression) bool {
+	infix, ok := operand.(*ast.InfixExpression)
+	if !ok || infix.Operator == "." || expr.Operator == "." {
+		return false
+	}
+	return isLogic(expr) != isLogic(infix)
+}
+
+//continues indica si la sentencia 'next' empieza con un token que continuaria la
+//expresion anterior.
+func continues(next ast.Statement) bool {
+	stmt, ok := next.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	p := &printer{}
+	p.expression(stmt.Expression)
+	return p.out.Len() > 0 && strings.ContainsAny(p.out.String()[:1], "([-")
+}
@@ -0,0 +1,76 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var   x:int=5", "var x:int = 5;\n"},
+		{"var x:int;\nglobal s:string;", "var x:int;\nglobal s:string;\n"},
+		{"x:=1;y:=x+2", "x := 1;\ny := x + 2;\n"},
+		{"x := (1 + 2) * 3;", "x := (1 + 2) * 3;\n"},
+		{"x := 1 + (2 * 3);", "x := 1 + 2 * 3;\n"},
+		{"x := a - (b - c);", "x := a - (b - c);\n"},
+		{"x := (a - b) - c;", "x := a - b - c;\n"},
+		{"x := a == (b and c);", "x := a == (b and c);\n"},
+		{"x := a > 1 and b;", "x := (a > 1) and b;\n"},
+		{"x := a and b or c;", "x := a and b or c;\n"},
+		{"x := -(-y);", "x := -(-y);\n"},
+		{"x := not (a and b);", "x := not (a and b);\n"},
+		{"x := -(a + b).c;", "x := -(a + b).c;\n"},
+		{"x := (-a).c;", "x := (-a).c;\n"},
+		{"x := s.f(1)[0];", "x := s.f(1)[0];\n"},
+		{"x := int(y / 2);", "x := int(y / 2);\n"},
+		{"a.b = [1,2,  3];", "a.b = [1, 2, 3];\n"},
+		{"i++;\ni += 2;", "i++;\ni += 2;\n"},
+		{`var m:map = {"b":2,"a" : 1};`, "var m:map = {\"b\": 2, \"a\": 1};\n"},
+		{"var s:struct = { x:int, f:func };", "var s:struct = {\n    x:int,\n    f:func\n};\n"},
+		{"fn add( a:int,b:int ) int {return a+b;}", "fn add(a:int, b:int) int {\n    return a + b;\n}\n"},
+		{"fn nada() { }", "fn nada() {}\n"},
+		{"f(fn(x:int) int { return x; }, 2);", "f(fn(x:int) int {\n    return x;\n}, 2);\n"},
+		{"if(x>1){print(x);}else{ print(0) }", "if (x > 1) {\n    print(x);\n} else {\n    print(0);\n}\n"},
+		{"if (x) {};\n-x;", "if (x) {};\n-x;\n"},
+		{"if (x) {};\nprint(x);", "if (x) {}\nprint(x);\n"},
+		{"for(i:=0;i<3;i++){ break; }", "for (i := 0; i < 3; i++) {\n    break;\n}\n"},
+		{"for x < 3 { x += 1; }", "for (x < 3) {\n    x += 1;\n}\n"},
+		{"for (e := [1]) { print(e); }", "for (e := [1]) {\n    print(e);\n}\n"},
+		{"try { throw \"e\" } catch(e) { } finally { print(1) }", "try {\n    throw \"e\";\n} catch (e) {} finally {\n    print(1);\n}\n"},
+		{"import \"std/math\"\nimport \"lib.april\" as lib", "import \"std/math\"\nimport \"lib.april\" as lib\n"},
+		{"x := 1;\n\n\n\ny := 2;", "x := 1;\n\ny := 2;\n"},
+		{"// inicio\n\nx := 1; // uno   \n// fin", "// inicio\n\nx := 1; // uno\n// fin\n"},
+		{"fn f() { // abre\n  x := 1;\n\n  // antes del cierre\n}", "fn f() { // abre\n    x := 1;\n\n    // antes del cierre\n}\n"},
+		{"fn f() {\n// vacio\n}", "fn f() {\n    // vacio\n}\n"},
+		{"print(1, // uno\n 2);\nprint(3);", "print(1, 2); // uno\nprint(3);\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		output, diagnostics := Source("test.april", tt.input)
+		if len(diagnostics) > 0 {
+			t.Fatalf("input %q: errors %v", tt.input, diagnostics[0])
+		}
+		if output != tt.expected {
+			t.Errorf("input %q: output is not %q. got=%q", tt.input, tt.expected, output)
+			continue
+		}
+
+		again, diagnostics := Source("test.april", output)
+		if len(diagnostics) > 0 || again != output {
+			t.Errorf("input %q: formatting the output again changes it. got=%q", tt.input, again)
+		}
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	_, diagnostics := Source("test.april", "var x:int = ;")
+	if len(diagnostics) == 0 {
+		t.Fatalf("no errors for invalid code")
+	}
+	if pos := diagnostics[0].Span.Start; pos.File != "test.april" || pos.Line != 1 {
+		t.Fatalf("error position is not test.april:1. got=%s", pos)
+	}
+}
@@ -1,57 +1,118 @@
 package lexer
 
 import (
-	"io/ioutil"
+	"errors"
 	"regexp"
 	"strings"
 
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/object"
 	"github.com/kenshindeveloper/april/token"
 )
 
-var (
-	currentPosition int
-	NUMBER_LINE     = 1
-)
-
 type fileInput struct {
+	name     string
 	input    string
 	position int
 	char     byte
+	line     int
+	column   int
 	prev     *fileInput
 }
 
+//checkpoint es el estado que guarda Save y restaura Continue.
+type checkpoint struct {
+	top      *fileInput
+	position int
+	char     byte
+	line     int
+	column   int
+}
+
 type Lexer struct {
-	top    *fileInput
-	length int
+	top      *fileInput
+	length   int
+	saved    checkpoint
+	start    token.Position
+	errors   []*diag.Diagnostic
+	included map[string]bool    //archivos que ya agrego ReadFile
+	paths    []string           //directorios donde se buscan los imports, nil es SearchPath()
+	fs       *object.FileSystem //permisos con los que se leen los imports, nil permite todo
+	skip     bool               //ReadFile no agrega los archivos, ver SkipImports
 }
 
 func New(input string) *Lexer {
-	fi := &fileInput{input: input, prev: nil}
-	l := &Lexer{top: fi, length: 1}
+	return NewFile("", input)
+}
+
+//NewFile crea un lexer cuyo codigo proviene del archivo 'name', las posiciones de los
+//tokens llevaran ese nombre.
+func NewFile(name string, input string) *Lexer {
+	fi := &fileInput{name: name, input: input, line: 1, prev: nil}
+	l := &Lexer{top: fi, length: 1, included: map[string]bool{}}
 	l.readToken()
 	return l
 }
 
-//ReadFile push input into stack
-func (l *Lexer) ReadFile(path string) bool {
-	data, err := ioutil.ReadFile(path)
+//ErrCycle es el error de ReadFile con un archivo que todavia se esta leyendo.
+var ErrCycle = errors.New("import cycle")
+
+//SetSearchPath cambia los directorios donde ReadFile busca los imports que no estan junto
+//al archivo que los importa.
+func (l *Lexer) SetSearchPath(paths []string) {
+	l.paths = paths
+}
+
+//SetFileSystem cambia los permisos con los que ReadFile lee los archivos importados.
+func (l *Lexer) SetFileSystem(fs *object.FileSystem) {
+	l.fs = fs
+}
+
+//SkipImports hace que ReadFile no agregue los archivos importados: el import queda en el
+//arbol pero sus sentencias no. Lo usa el formateador, que solo escribe el archivo.
+func (l *Lexer) SkipImports() {
+	l.skip = true
+}
+
+//ReadFile push input into stack. El archivo se busca con Load desde el archivo que se
+//esta leyendo. Un archivo que ya se agrego no se vuelve a agregar, y uno que se esta leyendo
+//todavia es un ciclo de imports.
+func (l *Lexer) ReadFile(path string) error {
+	if l.skip {
+		return nil
+	}
+	if l.paths == nil {
+		l.paths = SearchPath()
+	}
+	path, source, err := Load(l.top.name, path, l.paths, l.fs)
 	if err != nil {
-		return false
+		return err
 	}
 
-	if len(path) <= len(".april") || path[len(path)-6:len(path)] != ".april" {
-		return false
+	name := Canonical(path)
+	for fi := l.top; fi != nil; fi = fi.prev {
+		if Canonical(fi.name) == name {
+			return ErrCycle
+		}
+	}
+	if l.included[name] {
+		return nil
 	}
 
-	fi := &fileInput{input: string(data), prev: l.top}
+	l.included[name] = true
+	fi := &fileInput{name: path, input: source, line: 1, prev: l.top}
 	l.top = fi
 	l.readToken()
-	return true
+	return nil
 }
 
 func (l *Lexer) readString() string {
 	str := []byte{}
 	for l.top.char != '"' {
+		if l.top.char == 0 {
+			l.report(diag.Errorf(diag.UnterminatedString, diag.SpanAt(l.start, 1), "string literal not terminated"))
+			return string(str)
+		}
 		str = append(str, l.top.char)
 		l.readToken()
 	}
@@ -60,17 +121,68 @@ func (l *Lexer) readString() string {
 	return string(str)
 }
 
+//report registra un diagnostico. El parser puede volver a leer tokens con Save y
+//Continue, por eso se ignoran los diagnosticos repetidos en la misma posicion.
+func (l *Lexer) report(d *diag.Diagnostic) {
+	for _, e := range l.errors {
+		if e.Span.Start == d.Span.Start {
+			return
+		}
+	}
+	l.errors = append(l.errors, d)
+}
+
+//Errors devuelve los diagnosticos encontrados hasta ahora.
+func (l *Lexer) Errors() []*diag.Diagnostic {
+	return l.errors
+}
+
 func (l *Lexer) readToken() {
 	if l.top.position >= len(l.top.input) {
 		if !l.expectedFilePrev() {
+			l.advanceColumn()
 			l.top.char = 0
 		}
 	} else {
+		l.advanceColumn()
 		l.top.char = l.top.input[l.top.position]
 		l.top.position++
 	}
 }
 
+//peekChar devuelve el caracter que sigue al actual, 0 al final del archivo.
+func (l *Lexer) peekChar() byte {
+	if l.top.position >= len(l.top.input) {
+		return 0
+	}
+	return l.top.input[l.top.position]
+}
+
+//advanceColumn actualiza la linea y la columna antes de leer el siguiente caracter.
+func (l *Lexer) advanceColumn() {
+	if l.top.char == '\n' {
+		l.top.line++
+		l.top.column = 1
+		return
+	}
+	l.top.column++
+}
+
+//resetColumn recalcula la columna del caracter actual cuando el lexer retrocede.
+func (l *Lexer) resetColumn() {
+	index := l.top.position - 1
+	l.top.column = index - strings.LastIndexByte(l.top.input[:index], '\n')
+}
+
+//position devuelve la posicion del caracter actual.
+func (l *Lexer) position() token.Position {
+	offset := l.top.position - 1
+	if l.top.char == 0 {
+		offset = len(l.top.input)
+	}
+	return token.Position{File: l.top.name, Line: l.top.line, Column: l.top.column, Offset: offset}
+}
+
 func (l *Lexer) expectedFilePrev() bool {
 	if l.top.prev == nil {
 		return false
@@ -80,18 +192,29 @@ func (l *Lexer) expectedFilePrev() bool {
 	return true
 }
 
-func (l *Lexer) comments() {
-	if l.top.char == '/' && l.top.char == l.top.input[l.top.position] {
-		for l.top.char != '\n' && l.top.char != 0 && l.top.char != '\r' {
-			l.readToken()
-		}
+//readComment lee un comentario hasta el final de la linea y devuelve su texto con '//'.
+func (l *Lexer) readComment() string {
+	str := []byte{}
+	for l.top.char != '\n' && l.top.char != 0 && l.top.char != '\r' {
+		str = append(str, l.top.char)
+		l.readToken()
 	}
+	return string(str)
 }
 
 func (l *Lexer) NextToken() token.Token {
-	l.comments()
 	l.skypeSpace()
 
+	l.start = l.position()
+	tok := l.nextToken()
+	tok.Pos = l.start
+	if tok.Type == token.ILLEGAL {
+		l.report(diag.Errorf(diag.IllegalCharacter, diag.SpanOf(tok), "illegal character '%s'", tok.Literal))
+	}
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	switch l.top.char {
 	case ']':
 		l.readToken()
@@ -109,7 +232,7 @@ func (l *Lexer) NextToken() token.Token {
 
 		return tok
 	case ':':
-		if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.DECLARATION, Literal: ":="}
@@ -117,7 +240,7 @@ func (l *Lexer) NextToken() token.Token {
 		l.readToken()
 		return token.Token{Type: token.COLON, Literal: ":"}
 	case '<':
-		if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.COMLE, Literal: "<="}
@@ -126,7 +249,7 @@ func (l *Lexer) NextToken() token.Token {
 		return token.Token{Type: token.COMLT, Literal: "<"}
 
 	case '>':
-		if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.COMGE, Literal: ">="}
@@ -135,7 +258,7 @@ func (l *Lexer) NextToken() token.Token {
 		return token.Token{Type: token.COMGT, Literal: ">"}
 
 	case '!':
-		if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.COMNE, Literal: "!="}
@@ -144,7 +267,7 @@ func (l *Lexer) NextToken() token.Token {
 		return token.Token{Type: token.BANG, Literal: "!"}
 
 	case '=':
-		if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.COMEQ, Literal: "=="}
@@ -155,7 +278,7 @@ func (l *Lexer) NextToken() token.Token {
 		l.readToken()
 		return token.Token{Type: token.SEMICOLON, Literal: ";"}
 	case '%':
-		if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.ASIGMOD, Literal: "%="}
@@ -163,11 +286,11 @@ func (l *Lexer) NextToken() token.Token {
 		l.readToken()
 		return token.Token{Type: token.MOD, Literal: "%"}
 	case '+':
-		if l.top.input[l.top.position] == '+' {
+		if l.peekChar() == '+' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.OPEPLUS, Literal: "++"}
-		} else if l.top.input[l.top.position] == '=' {
+		} else if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.ASIGPLUS, Literal: "+="}
@@ -175,11 +298,11 @@ func (l *Lexer) NextToken() token.Token {
 		l.readToken()
 		return token.Token{Type: token.PLUS, Literal: "+"}
 	case '-':
-		if l.top.input[l.top.position] == '-' {
+		if l.peekChar() == '-' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.OPEMIN, Literal: "--"}
-		} else if l.top.input[l.top.position] == '=' {
+		} else if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.ASIGMIN, Literal: "-="}
@@ -187,7 +310,7 @@ func (l *Lexer) NextToken() token.Token {
 		l.readToken()
 		return token.Token{Type: token.MIN, Literal: "-"}
 	case '*':
-		if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.ASIGMUL, Literal: "*="}
@@ -195,10 +318,9 @@ func (l *Lexer) NextToken() token.Token {
 		l.readToken()
 		return token.Token{Type: token.MUL, Literal: "*"}
 	case '/':
-		if (l.top.position) < len(l.top.input) && l.top.input[l.top.position] == '/' {
-			l.comments()
-			return token.Token{Type: token.COMMENT, Literal: "//"}
-		} else if l.top.input[l.top.position] == '=' {
+		if l.peekChar() == '/' {
+			return token.Token{Type: token.COMMENT, Literal: l.readComment()}
+		} else if l.peekChar() == '=' {
 			l.readToken()
 			l.readToken()
 			return token.Token{Type: token.ASIGDIV, Literal: "/="}
@@ -254,6 +376,7 @@ func (l *Lexer) tokenEvaluation() token.Token {
 		// fmt.Println("ENTRO")
 		l.top.position = charPos + strings.Index(ident, ".") + 1
 		l.top.char = l.top.input[l.top.position-1]
+		l.resetColumn()
 		text := ident[:strings.Index(ident, ".")]
 
 		if isNumeric(text) {
@@ -270,19 +393,25 @@ func (l *Lexer) tokenEvaluation() token.Token {
 }
 
 func (l *Lexer) Save() {
-	currentPosition = l.top.position
+	l.saved = checkpoint{
+		top:      l.top,
+		position: l.top.position,
+		char:     l.top.char,
+		line:     l.top.line,
+		column:   l.top.column,
+	}
 }
 
 func (l *Lexer) Continue() {
-	l.top.position = currentPosition
-	l.top.char = l.top.input[l.top.position-1]
+	l.top = l.saved.top
+	l.top.position = l.saved.position
+	l.top.char = l.saved.char
+	l.top.line = l.saved.line
+	l.top.column = l.saved.column
 }
 
 func (l *Lexer) skypeSpace() {
 	for l.top.char == ' ' || l.top.char == '\n' || l.top.char == '\t' || l.top.char == '\a' || l.top.char == '\r' {
-		if l.top.char == '\n' {
-			NUMBER_LINE += 1
-		}
 		l.readToken()
 	}
 }
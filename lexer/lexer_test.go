@@ -1,9 +1,14 @@
 package lexer
 
 import (
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/token"
 )
 
@@ -67,6 +72,40 @@ func TestDoubleToken(t *testing.T) {
 	}
 }
 
+//TestOperatorAtEnd verifica que un operador al final del codigo, como en una linea
+//incompleta de la consola, no lea fuera de la entrada.
+func TestOperatorAtEnd(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected token.TokenType
+	}{
+		{"1 +", token.PLUS},
+		{"1 -", token.MIN},
+		{"1 *", token.MUL},
+		{"1 /", token.DIV},
+		{"1 %", token.MOD},
+		{"x :", token.COLON},
+		{"x =", token.EQUAL},
+		{"x <", token.COMLT},
+		{"x >", token.COMGT},
+		{"x !", token.BANG},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		l.NextToken()
+
+		tok := l.NextToken()
+		if tok.Type != tt.expected {
+			t.Fatalf("tests[%d] - tok.Type is not '%s'. got='%s'", i, tt.expected, tok.Type)
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("tests[%d] - tok.Type is not 'EOF'. got='%s'", i, tok.Type)
+		}
+	}
+}
+
 func TestStringToken(t *testing.T) {
 	input := `"hola"`
 
@@ -103,7 +142,7 @@ func TestLexer(t *testing.T) {
 		expectedType    token.TokenType
 		expectedLiteral string
 	}{
-		{token.COMMENT, "//"},
+		{token.COMMENT, "// esto es una prueba"},
 		{token.VAR, "var"},
 		{token.IDENT, "x"},
 		{token.COLON, ":"},
@@ -111,7 +150,7 @@ func TestLexer(t *testing.T) {
 		{token.EQUAL, "="},
 		{token.INT, "5"},
 		{token.SEMICOLON, ";"},
-		{token.COMMENT, "//"},
+		{token.COMMENT, "// esto es otra prueba"},
 		{token.IDENT, "y"},
 		{token.DECLARATION, ":="},
 		{token.INT, "6"},
@@ -164,3 +203,147 @@ func TestLexer(t *testing.T) {
 	}
 
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := "var x:int = 5;\n  // comentario\n  y := \"hola\";\nmath.Pow"
+
+	tests := []struct {
+		expectedType token.TokenType
+		line         int
+		column       int
+		offset       int
+	}{
+		{token.VAR, 1, 1, 0},
+		{token.IDENT, 1, 5, 4},
+		{token.COLON, 1, 6, 5},
+		{token.OPEINT, 1, 7, 6},
+		{token.EQUAL, 1, 11, 10},
+		{token.INT, 1, 13, 12},
+		{token.SEMICOLON, 1, 14, 13},
+		{token.IDENT, 3, 3, 33},
+		{token.DECLARATION, 3, 5, 35},
+		{token.STRING, 3, 8, 38},
+		{token.SEMICOLON, 3, 14, 44},
+		{token.IDENT, 4, 1, 46},
+		{token.DOT, 4, 5, 50},
+		{token.IDENT, 4, 6, 51},
+		{token.EOF, 4, 9, 54},
+	}
+
+	l := NewFile("test.april", input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type == token.COMMENT {
+			tok = l.NextToken()
+		}
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tok.Type is not '%s'. got='%s'", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.File != "test.april" {
+			t.Fatalf("tests[%d] - tok.Pos.File is not 'test.april'. got='%s'", i, tok.Pos.File)
+		}
+
+		if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column || tok.Pos.Offset != tt.offset {
+			t.Fatalf("tests[%d] - tok.Pos is not '%d:%d (%d)'. got='%d:%d (%d)'", i, tt.line, tt.column, tt.offset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     string
+		expected string
+	}{
+		{"x := @;", diag.IllegalCharacter, "1:6 - illegal character '@'"},
+		{"var s:string = \"hola;\nx := 1;", diag.UnterminatedString, "1:16 - string literal not terminated"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("tests[%d] - len(errors) is not 1. got=%d", i, len(errors))
+		}
+
+		if errors[0].Code != tt.code {
+			t.Errorf("tests[%d] - errors[0].Code is not '%s'. got='%s'", i, tt.code, errors[0].Code)
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("tests[%d] - errors[0] is not %q. got=%q", i, tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"main/main.april", "main/near.april", "lib/near.april", "lib/far.april", "std/far.april", "std/std.april"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	from := filepath.Join(dir, "main", "main.april")
+	paths := []string{filepath.Join(dir, "lib"), filepath.Join(dir, "std")}
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"near.april", "main/near.april"},
+		{"far.april", "lib/far.april"},
+		{"std.april", "std/std.april"},
+		{"../lib/near.april", "lib/near.april"},
+		{filepath.Join(dir, "std", "far.april"), "std/far.april"},
+	}
+
+	for _, tt := range tests {
+		found, err := Find(from, tt.path, paths)
+		if err != nil {
+			t.Fatalf("Find(%q) has error: %s", tt.path, err)
+		}
+		if found != filepath.Join(dir, tt.expected) {
+			t.Fatalf("Find(%q) is not %q. got=%q", tt.path, tt.expected, found)
+		}
+	}
+
+	_, err = Find(from, "nope.april", paths)
+	notFound, ok := err.(*NotFoundError)
+	if !ok {
+		t.Fatalf("err is not *NotFoundError. got=%T", err)
+	}
+	searched := []string{filepath.Join(dir, "main", "nope.april"), filepath.Join(dir, "lib", "nope.april"), filepath.Join(dir, "std", "nope.april")}
+	if strings.Join(notFound.Searched, ", ") != strings.Join(searched, ", ") {
+		t.Fatalf("notFound.Searched is not %v. got=%v", searched, notFound.Searched)
+	}
+	if !strings.Contains(err.Error(), "searched: "+searched[0]) {
+		t.Fatalf("err.Error() does not list the searched paths. got=%q", err.Error())
+	}
+
+	//la biblioteca estandar ya no esta en package/, el error indica el import nuevo.
+	_, _, err = Load(from, "package/math.april", paths, nil)
+	if notFound, ok := err.(*NotFoundError); !ok || notFound.Moved != "std/math" {
+		t.Fatalf("Load(package/math.april) does not suggest std/math. got=%v", err)
+	}
+	if !strings.Contains(err.Error(), "import 'std/math' instead") {
+		t.Fatalf("err.Error() does not suggest the new import. got=%q", err.Error())
+	}
+	_, _, err = Load(from, "package/nope.april", paths, nil)
+	if notFound, ok := err.(*NotFoundError); !ok || notFound.Moved != "" {
+		t.Fatalf("Load(package/nope.april) suggests an import. got=%v", err)
+	}
+}
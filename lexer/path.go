@@ -0,0 +1,158 @@
+package lexer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/std"
+)
+
+//LegacyPrefix es el directorio donde estaba la biblioteca estandar antes de ir dentro del
+//ejecutable. Sus imports ya no se encuentran, "package/math.april" ahora es "std/math".
+const LegacyPrefix = "package/"
+
+//NotFoundError es el error de un import cuyo archivo no esta en ninguna de las rutas
+//que se buscaron. Sin rutas el archivo no se busco porque no termina en .april.
+type NotFoundError struct {
+	Path     string
+	Searched []string
+	Moved    string //import de la biblioteca estandar que reemplaza a Path, "" si no hay
+}
+
+func (e *NotFoundError) Error() string {
+	if len(e.Searched) == 0 {
+		return fmt.Sprintf("module not found: '%s', only .april files can be imported", e.Path)
+	}
+	msg := fmt.Sprintf("module not found: '%s', searched: %s", e.Path, strings.Join(e.Searched, ", "))
+	if e.Moved != "" {
+		msg += fmt.Sprintf("; the standard library moved from '%s' to '%s', import '%s' instead", LegacyPrefix, std.Prefix, e.Moved)
+	}
+	return msg
+}
+
+//DeniedError es el error de un import cuyo archivo existe pero los permisos de archivos
+//de la ejecucion no dejan leer.
+type DeniedError struct {
+	Path    string
+	Message string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("module can not be imported: '%s', %s", e.Path, e.Message)
+}
+
+//SearchPath devuelve los directorios donde se buscan los imports que no estan junto al
+//archivo que los importa, los de la variable de entorno APRIL_PATH en orden.
+func SearchPath() []string {
+	paths := []string{}
+	for _, dir := range filepath.SplitList(os.Getenv("APRIL_PATH")) {
+		if dir != "" {
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
+
+//Find busca el archivo del import 'path' hecho desde el archivo 'from'. Una ruta relativa
+//se busca primero junto a 'from', o en el directorio actual si 'from' es "", y despues en
+//los directorios 'paths'. Devuelve la primera ruta que existe o un *NotFoundError.
+func Find(from, path string, paths []string) (string, error) {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		candidates = []string{filepath.Join(filepath.Dir(from), path)}
+		for _, dir := range paths {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", &NotFoundError{Path: path, Searched: candidates}
+}
+
+//Load busca el archivo del import 'path' hecho desde el archivo 'from' y devuelve su
+//nombre y su codigo. Las rutas que empiezan con std.Prefix, como "std/math", son de la
+//biblioteca estandar que lleva el ejecutable. Las demas se buscan con Find y por ultimo
+//en la biblioteca estandar. Un archivo que 'fs' no deja leer devuelve un *DeniedError,
+//la biblioteca estandar siempre se puede importar. Un import con LegacyPrefix que no se
+//encuentra devuelve en el error el import de std.Prefix que lo reemplaza.
+func Load(from, path string, paths []string, fs *object.FileSystem) (string, string, error) {
+	if std.Is(path) {
+		name := std.Name(path)
+		if source, ok := std.Read(name); ok {
+			return name, source, nil
+		}
+		return "", "", &NotFoundError{Path: path, Searched: []string{name}}
+	}
+	if !strings.HasSuffix(path, ".april") {
+		return "", "", &NotFoundError{Path: path}
+	}
+
+	name, err := Find(from, path, paths)
+	if err == nil {
+		if err := readable(fs, path, name); err != nil {
+			return "", "", err
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", "", &NotFoundError{Path: path, Searched: []string{name}}
+		}
+		return name, string(data), nil
+	}
+
+	notFound := err.(*NotFoundError)
+	if !filepath.IsAbs(path) {
+		name = std.Prefix + filepath.ToSlash(path)
+		if source, ok := std.Read(name); ok {
+			return name, source, nil
+		}
+		notFound.Searched = append(notFound.Searched, name)
+		notFound.Moved = moved(path)
+	}
+	return "", "", notFound
+}
+
+//moved devuelve el import de la biblioteca estandar que reemplaza al import 'path' de
+//LegacyPrefix, "package/math.april" es "std/math", o "" si no hay.
+func moved(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, LegacyPrefix) {
+		return ""
+	}
+	name := std.Prefix + strings.TrimSuffix(strings.TrimPrefix(path, LegacyPrefix), ".april")
+	if _, ok := std.Read(name); !ok {
+		return ""
+	}
+	return name
+}
+
+//readable devuelve un *DeniedError si 'fs' no deja leer el archivo 'name' que se encontro
+//para el import 'path'.
+func readable(fs *object.FileSystem, path, name string) error {
+	if fs == nil {
+		return nil
+	}
+	if _, err := fs.Read(Canonical(name)); err != nil {
+		return &DeniedError{Path: path, Message: err.Message}
+	}
+	return nil
+}
+
+//Canonical devuelve el nombre que identifica al archivo 'name' de Load: la ruta absoluta,
+//o el mismo nombre si es de la biblioteca estandar.
+func Canonical(name string) string {
+	if std.Is(name) {
+		return name
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return filepath.Clean(name)
+}
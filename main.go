@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/kenshindeveloper/april/file"
-	"github.com/kenshindeveloper/april/repl"
+	"github.com/kenshindeveloper/april/cli"
 )
 
 const mayor = 1
@@ -13,12 +12,11 @@ const minus = 0
 const micro = 0
 
 func main() {
-	switch len(os.Args) {
-	case 1:
-		repl.Start(os.Stdout, os.Stdin, fmt.Sprintf("%d.%d.%d", mayor, minus, micro))
-	case 2:
-		file.Start(os.Stdout, os.Stdin, os.Args[1])
-	default:
-		fmt.Printf("number parameters is incorrect.")
+	c := &cli.CLI{
+		Version: fmt.Sprintf("%d.%d.%d", mayor, minus, micro),
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+		Stdin:   os.Stdin,
 	}
+	os.Exit(c.Run(os.Args[1:]))
 }
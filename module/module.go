@@ -0,0 +1,133 @@
+//Package module carga los archivos de 'import "ruta" as nombre'. Cada modulo se lee,
+//se verifica y se ejecuta una sola vez por Loader, los siguientes imports del mismo
+//archivo reciben el mismo modulo.
+package module
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+	"github.com/kenshindeveloper/april/resolver"
+	"github.com/kenshindeveloper/april/typecheck"
+)
+
+//Loader es el object.Importer de una ejecucion. Guarda el codigo de cada modulo en
+//'sources' para que sus errores se muestren con el extracto del archivo.
+type Loader struct {
+	sources diag.Sources
+	modules map[string]*object.Module
+	loading []string //modulos que se estan ejecutando, el ultimo es el mas reciente
+	paths   []string //directorios donde se buscan los modulos, nil es lexer.SearchPath()
+}
+
+func New(sources diag.Sources) *Loader {
+	return &Loader{sources: sources, modules: map[string]*object.Module{}}
+}
+
+//Main indica que el programa principal es el archivo 'path', asi un modulo que lo
+//importa se informa como un ciclo en lugar de ejecutarlo otra vez.
+func (l *Loader) Main(path string) {
+	l.loading = []string{lexer.Canonical(path)}
+}
+
+//SetSearchPath cambia los directorios donde se buscan los modulos que no estan junto al
+//archivo que los importa.
+func (l *Loader) SetSearchPath(paths []string) {
+	l.paths = paths
+}
+
+//Import devuelve el modulo del archivo 'path' importado desde el archivo 'from', que se
+//busca con lexer.Load y se lee con los permisos de archivos de 'runtime'. La primera vez
+//lo ejecuta en un entorno propio que comparte la ejecucion 'runtime'.
+func (l *Loader) Import(runtime *object.Runtime, from, path string) object.Object {
+	if l.paths == nil {
+		l.paths = lexer.SearchPath()
+	}
+	path, source, err := lexer.Load(from, path, l.paths, runtime.FS)
+	if _, ok := err.(*lexer.DeniedError); ok {
+		return newError(diag.PermissionDenied, "%s.", err)
+	}
+	if err != nil {
+		return newError(diag.ImportNotFound, "%s.", err)
+	}
+
+	name := lexer.Canonical(path)
+	if module, ok := l.modules[name]; ok {
+		return module
+	}
+	for i, loading := range l.loading {
+		if loading == name {
+			return newError(diag.ImportCycle, "import cycle: %s", l.cycle(i, name))
+		}
+	}
+	l.sources[path] = source
+
+	l.loading = append(l.loading, name)
+	defer func() {
+		l.loading = l.loading[:len(l.loading)-1]
+	}()
+
+	env := object.NewRuntimeEnvironment(runtime)
+	if err := l.run(path, source, env, runtime.FS); err != nil {
+		return err
+	}
+
+	module := &object.Module{Path: path, Env: env}
+	l.modules[name] = module
+	return module
+}
+
+//run verifica y ejecuta el codigo del modulo, sus imports sin 'as' se leen con los
+//permisos 'fs'. Un error de una etapa anterior a la ejecucion se devuelve con la posicion
+//del primer diagnostico.
+func (l *Loader) run(path, source string, env *object.Environment, fs *object.FileSystem) *object.Error {
+	lex := lexer.NewFile(path, source)
+	lex.SetFileSystem(fs)
+	p := parser.New(lex)
+	program := p.ParserProgram()
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
+		diagnostics = typecheck.Check(program)
+	}
+	if len(diagnostics) == 0 {
+		diagnostics = resolver.Resolve(program)
+	}
+	if len(diagnostics) > 0 {
+		d := diagnostics[0]
+		return &object.Error{Code: d.Code, Span: d.Span, Message: "in module '" + path + "': " + d.Message}
+	}
+
+	if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		return err
+	}
+	return nil
+}
+
+//cycle describe el ciclo que empieza en el modulo 'loading[start]' y vuelve a 'name'.
+func (l *Loader) cycle(start int, name string) string {
+	names := []string{}
+	for _, loading := range l.loading[start:] {
+		names = append(names, relative(loading))
+	}
+	return strings.Join(append(names, relative(name)), " -> ")
+}
+
+//relative devuelve 'path' relativo al directorio actual si esta dentro de el.
+func relative(path string) string {
+	if wd, err := filepath.Abs("."); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+func newError(code string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,211 @@
+package module
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/object"
+)
+
+//load crea los archivos 'files' en un directorio temporal, donde "{dir}" se reemplaza
+//por el directorio, e importa el archivo "main.april" con un Loader nuevo que busca los
+//modulos en los directorios 'paths' del directorio temporal. Devuelve el resultado del
+//import y lo que se imprimio.
+func load(t *testing.T, files map[string]string, paths ...string) (object.Object, string) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, source := range files {
+		source = strings.Replace(source, "{dir}", dir, -1)
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	runtime := object.NewRuntime()
+	runtime.IO.Stdout = &stdout
+	loader := New(diag.Sources{})
+	search := []string{}
+	for _, path := range paths {
+		search = append(search, filepath.Join(dir, path))
+	}
+	loader.SetSearchPath(search)
+	runtime.Importer = loader
+
+	result := loader.Import(runtime, "", filepath.Join(dir, "main.april"))
+	return result, stdout.String()
+}
+
+func TestImport(t *testing.T) {
+	lib := `
+print("loading lib");
+global PI:double = 3.14;
+global hidden:int = 1;
+fn Abs(x:int) int { if (x < 0) { return -x; } return x; }`
+
+	tests := []struct {
+		main     string
+		expected string
+	}{
+		{`import "{dir}/lib.april" as lib
+print(lib.Abs(-3));
+print(lib.PI);`, "'loading lib'\n3\n3.14\n"},
+		{`import "{dir}/lib.april" as lib
+fn twice(x:int) int { return lib.Abs(x) * 2; }
+print(twice(-4));`, "'loading lib'\n8\n"},
+		{`import "{dir}/lib.april" as a
+import "{dir}/lib.april" as b
+print(a.Abs(-1) + b.Abs(-1));`, "'loading lib'\n2\n"},
+		{`import "{dir}/other.april" as other
+import "{dir}/lib.april" as lib
+print(other.Twice(-2));`, "'loading lib'\n4\n"},
+	}
+
+	for _, tt := range tests {
+		result, stdout := load(t, map[string]string{
+			"lib.april": lib,
+			"other.april": `import "{dir}/lib.april" as lib
+fn Twice(x:int) int { return lib.Abs(x) * 2; }`,
+			"main.april": tt.main,
+		})
+		if err, ok := result.(*object.Error); ok {
+			t.Fatalf("unexpected error: %s", err.Message)
+		}
+		if stdout != tt.expected {
+			t.Fatalf("output is not %q. got=%q", tt.expected, stdout)
+		}
+	}
+}
+
+func TestImportSearch(t *testing.T) {
+	files := map[string]string{
+		"main.april": `import "sub/lib.april" as lib
+import "shared.april" as shared
+print(lib.Name());
+print(shared.Name());`,
+		"sub/lib.april": `import "util.april" as util
+fn Name() string { return util.Name(); }`,
+		"sub/util.april":      `fn Name() string { return "sub/util"; }`,
+		"first/shared.april":  `fn Name() string { return "first"; }`,
+		"second/shared.april": `fn Name() string { return "second"; }`,
+	}
+
+	result, stdout := load(t, files, "first", "second")
+	if err, ok := result.(*object.Error); ok {
+		t.Fatalf("unexpected error: %s", err.Message)
+	}
+	if stdout != "'sub/util'\n'first'\n" {
+		t.Fatalf("output is not 'sub/util' and 'first'. got=%q", stdout)
+	}
+
+	result, _ = load(t, map[string]string{"main.april": `import "nope.april" as nope`}, "lib")
+	err, ok := result.(*object.Error)
+	if !ok || err.Code != diag.ImportNotFound {
+		t.Fatalf("result is not an ImportNotFound error. got=%T (%+v)", result, result)
+	}
+	if !strings.Contains(err.Message, "searched: ") || !strings.Contains(err.Message, filepath.Join("lib", "nope.april")) {
+		t.Fatalf("err.Message does not list the searched paths. got=%q", err.Message)
+	}
+}
+
+func TestImportStd(t *testing.T) {
+	tests := []struct {
+		main     string
+		expected string
+	}{
+		{`import "std/math" as math
+print(math.Abs(-2.5));`, "2.5\n"},
+		{`import "std/math.april" as math
+import "std/string" as s
+print(math.Abs(-4.0));`, "4\n"},
+		{`import "math.april" as math
+print(math.Abs(-1.0));`, "1\n"},
+	}
+
+	for _, tt := range tests {
+		result, stdout := load(t, map[string]string{"main.april": tt.main})
+		if err, ok := result.(*object.Error); ok {
+			t.Fatalf("unexpected error: %s", err.Message)
+		}
+		if stdout != tt.expected {
+			t.Fatalf("output is not %q. got=%q", tt.expected, stdout)
+		}
+	}
+
+	result, _ := load(t, map[string]string{"main.april": `import "std/nope" as nope`})
+	err, ok := result.(*object.Error)
+	if !ok || err.Code != diag.ImportNotFound || !strings.Contains(err.Message, "std/nope.april") {
+		t.Fatalf("result is not an ImportNotFound error for std/nope.april. got=%T (%+v)", result, result)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		files    map[string]string
+		code     string
+		contains string
+	}{
+		{
+			map[string]string{"main.april": `import "{dir}/nope.april" as nope`},
+			diag.ImportNotFound, "module not found",
+		},
+		{
+			map[string]string{
+				"main.april": `import "{dir}/a.april" as a`,
+				"a.april":    `import "{dir}/b.april" as b`,
+				"b.april":    `import "{dir}/a.april" as a`,
+			},
+			diag.ImportCycle, "import cycle: ",
+		},
+		{
+			map[string]string{
+				"main.april": `import "{dir}/lib.april" as lib
+print(lib.hidden);`,
+				"lib.april": `global hidden:int = 1;`,
+			},
+			diag.NameNotFound, "'hidden' is not exported",
+		},
+		{
+			map[string]string{
+				"main.april": `import "{dir}/lib.april" as lib
+lib.Value = 2;`,
+				"lib.april": `global Value:int = 1;`,
+			},
+			"", "can not assign 'Value'",
+		},
+		{
+			map[string]string{
+				"main.april": `import "{dir}/lib.april" as lib`,
+				"lib.april":  `x := ;`,
+			},
+			"", "in module '",
+		},
+	}
+
+	for _, tt := range tests {
+		result, _ := load(t, tt.files)
+		err, ok := result.(*object.Error)
+		if !ok {
+			t.Fatalf("result is not *object.Error. got=%T (%+v)", result, result)
+		}
+		if tt.code != "" && err.Code != tt.code {
+			t.Fatalf("err.Code is not %s. got=%s (%s)", tt.code, err.Code, err.Message)
+		}
+		if !strings.Contains(err.Message, tt.contains) {
+			t.Fatalf("err.Message does not contain %q. got=%q", tt.contains, err.Message)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/code"
+	"github.com/kenshindeveloper/april/diag"
+)
+
+const (
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+)
+
+//CompiledFunction es una funcion traducida a bytecode por el paquete compiler.
+//SourceMap relaciona cada instruccion con la posicion del codigo que la produjo.
+type CompiledFunction struct {
+	Name         string
+	Parameters   []*ast.FunctionParameters
+	Return       *ast.Identifier
+	Body         *ast.BlockStatement
+	Instructions code.Instructions
+	SourceMap    code.SourceMap
+	NumLocals    int
+	LocalNames   []string
+	Span         diag.Span //donde apunta el error de un return que falta
+}
+
+func (cf *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJ
+}
+
+func (cf *CompiledFunction) Inspect() string {
+	return inspectFunction(cf.Parameters, cf.Body)
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//Scope guarda las variables locales de una llamada en curso. Los closures conservan el
+//Scope en el que se crearon para leer y modificar esas variables.
+type Scope struct {
+	Locals []Object
+	Names  []string
+	Outer  *Scope
+}
+
+//Closure es una funcion compilada junto con el Scope en el que se creo. Para el lenguaje
+//es del mismo tipo que FunctionClosure.
+type Closure struct {
+	Fn    *CompiledFunction
+	Scope *Scope
+}
+
+func (c *Closure) Type() ObjectType {
+	return CLOSURE_OBJ
+}
+
+func (c *Closure) Inspect() string {
+	return c.Fn.Inspect()
+}
+
+func inspectFunction(parameters []*ast.FunctionParameters, body *ast.BlockStatement) string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ","))
+	out.WriteString(")")
+	out.WriteString("{ ")
+	if body != nil {
+		out.WriteString(body.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
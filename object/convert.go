@@ -0,0 +1,468 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/kenshindeveloper/april/diag"
+)
+
+//Las funciones de este archivo convierten valores de Go en objetos del lenguaje y al
+//reves. Los campos de un struct de Go se relacionan con los campos del struct de April
+//por la etiqueta `april:"nombre"`, sin etiqueta se usa el nombre del campo y con
+//`april:"-"` el campo se ignora.
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+//FromGo convierte 'value' en un Object. Acepta booleanos, numeros, strings, slices,
+//arrays, maps, structs, punteros a esos tipos y funciones, que se convierten en Builtin.
+//Una funcion puede devolver un valor como maximo, seguido o no de un error. Un entero
+//sin signo que no entra en un int64 y un valor que se contiene a si mismo son errores.
+func FromGo(value interface{}) (Object, error) {
+	if value == nil {
+		return &Nil{}, nil
+	}
+	return fromValue(reflect.ValueOf(value), map[visit]bool{})
+}
+
+//visit es un puntero, map o slice que se esta convirtiendo. Si vuelve a aparecer dentro
+//de su propio valor el valor es ciclico y no se puede convertir.
+type visit struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+//fromValue convierte 'v'. En 'path' estan los punteros, maps y slices que contienen a 'v'.
+func fromValue(v reflect.Value, path map[visit]bool) (Object, error) {
+	if v.Type().Implements(objectType) && v.Kind() != reflect.Interface {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return &Nil{}, nil
+		}
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			break
+		}
+		key := visit{typ: v.Type(), ptr: v.Pointer()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if path[key] {
+			return nil, fmt.Errorf("cannot convert cyclic go value of type %s", v.Type())
+		}
+		path[key] = true
+		defer delete(path, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return &Boolean{Value: v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d of type %s overflows int", v.Uint(), v.Type())
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Double{Value: v.Float()}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return &Nil{}, nil
+		}
+		return fromValue(v.Elem(), path)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return &Nil{}, nil
+		}
+		list := &List{Elements: make([]Object, v.Len())}
+		for i := 0; i < v.Len(); i++ {
+			element, err := fromValue(v.Index(i), path)
+			if err != nil {
+				return nil, err
+			}
+			list.Elements[i] = element
+		}
+		return list, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return &Nil{}, nil
+		}
+		hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := fromValue(iter.Key(), path)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", v.Type().Key())
+			}
+			value, err := fromValue(iter.Value(), path)
+			if err != nil {
+				return nil, err
+			}
+			hash.Pairs[hashable.HashKey()] = HashPair{Key: key, Value: value}
+		}
+		return hash, nil
+
+	case reflect.Struct:
+		strucObj := &Struct{Env: NewEnvironment()}
+		for _, field := range structFields(v.Type()) {
+			value, err := fromValue(v.FieldByIndex(field.index), path)
+			if err != nil {
+				return nil, err
+			}
+			strucObj.Env.Save(field.name, value)
+		}
+		return strucObj, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return &Nil{}, nil
+		}
+		if fn, ok := v.Interface().(func(args ...Object) Object); ok {
+			return &Builtin{Fn: fn}, nil
+		}
+		if fn, ok := v.Interface().(BuiltinFunction); ok {
+			return &Builtin{Fn: fn}, nil
+		}
+		if !returnsOneValue(v.Type()) {
+			return nil, fmt.Errorf("cannot convert go function of type %s, it must return at most one value and an error", v.Type())
+		}
+		return wrapFunction(v), nil
+	}
+
+	return nil, fmt.Errorf("cannot convert go value of type %s", v.Type())
+}
+
+//ToGo guarda el valor de 'obj' en 'target', que debe ser un puntero. Si 'target' apunta
+//a un interface{} se usan int64, float64, string, bool, []interface{} y
+//map[string]interface{}. Un Object nil es un error.
+func ToGo(obj Object, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	return toValue(obj, v.Elem())
+}
+
+func toValue(obj Object, v reflect.Value) error {
+	if obj == nil {
+		return fmt.Errorf("cannot convert nil object to go type %s", v.Type())
+	}
+	if objectType.AssignableTo(v.Type()) && v.Kind() == reflect.Interface && v.NumMethod() > 0 {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(v.Type()) && v.Kind() != reflect.Interface {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	if _, ok := obj.(*Nil); ok {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return mismatch(obj, v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return mismatch(obj, v.Type())
+		}
+		value, err := natural(obj)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := toValue(obj, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Bool:
+		if boolean, ok := obj.(*Boolean); ok {
+			v.SetBool(boolean.Value)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if integer, ok := obj.(*Integer); ok && !v.OverflowInt(integer.Value) {
+			v.SetInt(integer.Value)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if integer, ok := obj.(*Integer); ok && integer.Value >= 0 && !v.OverflowUint(uint64(integer.Value)) {
+			v.SetUint(uint64(integer.Value))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch number := obj.(type) {
+		case *Double:
+			v.SetFloat(number.Value)
+			return nil
+		case *Integer:
+			v.SetFloat(float64(number.Value))
+			return nil
+		}
+
+	case reflect.String:
+		if str, ok := obj.(*String); ok {
+			v.SetString(str.Value)
+			return nil
+		}
+
+	case reflect.Slice:
+		if list, ok := obj.(*List); ok {
+			slice := reflect.MakeSlice(v.Type(), len(list.Elements), len(list.Elements))
+			for i, element := range list.Elements {
+				if err := toValue(element, slice.Index(i)); err != nil {
+					return err
+				}
+			}
+			v.Set(slice)
+			return nil
+		}
+
+	case reflect.Array:
+		if list, ok := obj.(*List); ok && len(list.Elements) == v.Len() {
+			for i, element := range list.Elements {
+				if err := toValue(element, v.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case reflect.Map:
+		if hash, ok := obj.(*Hash); ok {
+			m := reflect.MakeMapWithSize(v.Type(), len(hash.Pairs))
+			for _, pair := range hash.Pairs {
+				key := reflect.New(v.Type().Key()).Elem()
+				if err := toValue(pair.Key, key); err != nil {
+					return err
+				}
+				value := reflect.New(v.Type().Elem()).Elem()
+				if err := toValue(pair.Value, value); err != nil {
+					return err
+				}
+				m.SetMapIndex(key, value)
+			}
+			v.Set(m)
+			return nil
+		}
+
+	case reflect.Struct:
+		return toStruct(obj, v)
+	}
+
+	return mismatch(obj, v.Type())
+}
+
+//toStruct copia los campos de un struct de April o de un map con claves string en el
+//struct de Go 'v'. Los campos que no estan en 'obj' conservan su valor.
+func toStruct(obj Object, v reflect.Value) error {
+	var get func(name string) (Object, bool)
+	switch obj := obj.(type) {
+	case *Struct:
+		get = obj.Env.Get
+	case *Hash:
+		get = func(name string) (Object, bool) {
+			pair, ok := obj.Pairs[(&String{Value: name}).HashKey()]
+			return pair.Value, ok
+		}
+	default:
+		return mismatch(obj, v.Type())
+	}
+
+	for _, field := range structFields(v.Type()) {
+		value, ok := get(field.name)
+		if !ok {
+			continue
+		}
+		if err := toValue(value, v.FieldByIndex(field.index)); err != nil {
+			return fmt.Errorf("field '%s': %s", field.name, err)
+		}
+	}
+	return nil
+}
+
+//natural devuelve el valor de Go que corresponde a 'obj' sin un tipo de destino.
+func natural(obj Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Nil:
+		return nil, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Integer:
+		return obj.Value, nil
+	case *Double:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *List:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			value, err := natural(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+	case *Hash:
+		m := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*String)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert map key of type %s to string", GetType(pair.Key.Type()))
+			}
+			value, err := natural(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = value
+		}
+		return m, nil
+	}
+	return obj, nil
+}
+
+func mismatch(obj Object, typ reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to go type %s", GetType(obj.Type()), typ)
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+type structField struct {
+	name  string
+	index []int
+}
+
+//structFields devuelve los campos exportados de 'typ' con el nombre que tienen en April.
+func structFields(typ reflect.Type) []structField {
+	fields := []structField{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("april"); ok {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: field.Index})
+	}
+	return fields
+}
+
+//returnsOneValue indica si la funcion 'typ' devuelve un valor como maximo, sin contar
+//un error al final.
+func returnsOneValue(typ reflect.Type) bool {
+	results := typ.NumOut()
+	if results > 0 && typ.Out(results-1) == errorType {
+		results--
+	}
+	return results <= 1
+}
+
+//wrapFunction convierte la funcion de Go 'fn' en un Builtin. Los argumentos se convierten
+//con ToGo y los resultados con FromGo. Si el ultimo resultado es un error distinto de nil
+//se devuelve como error del lenguaje, igual que un panico de la funcion.
+func wrapFunction(fn reflect.Value) *Builtin {
+	typ := fn.Type()
+	return &Builtin{Fn: func(args ...Object) Object {
+		required := typ.NumIn()
+		if typ.IsVariadic() {
+			required--
+		}
+		if len(args) < required || !typ.IsVariadic() && len(args) > required {
+			return &Error{Code: diag.WrongArgumentNum, Message: fmt.Sprintf("wrong number of arguments. got'%d', want='%d'", len(args), required)}
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var paramType reflect.Type
+			if i < required {
+				paramType = typ.In(i)
+			} else {
+				paramType = typ.In(required).Elem()
+			}
+			value := reflect.New(paramType).Elem()
+			if err := toValue(arg, value); err != nil {
+				return &Error{Code: diag.TypeMismatch, Message: fmt.Sprintf("argument %d: %s", i+1, err)}
+			}
+			in[i] = value
+		}
+
+		out, panicked := call(fn, in)
+		if panicked != nil {
+			return panicked
+		}
+		if len(out) > 0 && typ.Out(len(out)-1) == errorType {
+			if err := out[len(out)-1]; !err.IsNil() {
+				return &Error{Code: diag.RuntimeError, Message: err.Interface().(error).Error()}
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return &Nil{}
+		}
+
+		result, err := fromValue(out[0], map[visit]bool{})
+		if err != nil {
+			return &Error{Code: diag.TypeMismatch, Message: err.Error()}
+		}
+		return result
+	}}
+}
+
+//call llama a la funcion de Go 'fn'. Si la funcion entra en panico devuelve el panico
+//como error del lenguaje en lugar de terminar el programa que usa el interprete.
+func call(fn reflect.Value, in []reflect.Value) (out []reflect.Value, err *Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &Error{Code: diag.RuntimeError, Message: fmt.Sprintf("go function panicked: %v", r)}
+		}
+	}()
+	return fn.Call(in), nil
+}
@@ -1,22 +1,37 @@
 package object
 
+import "sort"
+
 type Environment struct {
-	store  map[string]Object
-	global map[string]Object
-	outer  *Environment
-	Scope  bool
+	slots   []Object
+	store   map[string]Object
+	global  map[string]Object
+	outer   *Environment
+	runtime *Runtime
+	Scope   bool
 }
 
 func NewEncloseEnvironment(outer *Environment) *Environment {
-	return &Environment{store: make(map[string]Object), global: nil, outer: outer, Scope: false}
+	return &Environment{store: make(map[string]Object), global: nil, outer: outer, runtime: outer.runtime, Scope: false}
 }
 
 func NewEnvironment() *Environment {
-	return &Environment{store: make(map[string]Object), global: make(map[string]Object), outer: nil, Scope: true}
+	return &Environment{store: make(map[string]Object), global: make(map[string]Object), outer: nil, runtime: NewRuntime(), Scope: true}
+}
+
+//NewRuntimeEnvironment crea un entorno de programa principal que comparte la ejecucion
+//'runtime', es el entorno de un modulo importado.
+func NewRuntimeEnvironment(runtime *Runtime) *Environment {
+	return &Environment{store: make(map[string]Object), global: make(map[string]Object), outer: nil, runtime: runtime, Scope: true}
 }
 
 func NewEnvironmentFn(env *Environment) *Environment {
-	return &Environment{store: make(map[string]Object), global: env.global, outer: nil, Scope: false}
+	return &Environment{store: make(map[string]Object), global: env.global, outer: nil, runtime: env.runtime, Scope: false}
+}
+
+//Runtime devuelve el estado de la ejecucion a la que pertenece el entorno.
+func (e *Environment) Runtime() *Runtime {
+	return e.runtime
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -64,3 +79,68 @@ func (e *Environment) GetLocal(name string) (Object, bool) {
 	obj, ok := e.global[name]
 	return obj, ok
 }
+
+//GetAt devuelve la variable de la posicion 'slot' del entorno que esta 'depth' niveles
+//hacia afuera. Lo usan los identificadores que resolvio el paquete resolver.
+func (e *Environment) GetAt(depth, slot int) (Object, bool) {
+	env := e.ancestor(depth)
+	if env == nil || slot >= len(env.slots) || env.slots[slot] == nil {
+		return nil, false
+	}
+	return env.slots[slot], true
+}
+
+//SetAt guarda 'value' en la posicion 'slot' del entorno que esta 'depth' niveles hacia
+//afuera.
+func (e *Environment) SetAt(depth, slot int, value Object) Object {
+	env := e.ancestor(depth)
+	if env == nil {
+		return value
+	}
+
+	if slot >= len(env.slots) {
+		slots := make([]Object, slot+1, 2*slot+2)
+		copy(slots, env.slots)
+		env.slots = slots
+	}
+	env.slots[slot] = value
+	return value
+}
+
+//GetGlobal busca 'name' solo entre las funciones y las variables 'global'.
+func (e *Environment) GetGlobal(name string) (Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		if env.global != nil {
+			obj, ok := env.global[name]
+			return obj, ok
+		}
+	}
+	return nil, false
+}
+
+//Names devuelve los nombres de las variables y funciones visibles desde el entorno, sin
+//repetir y en orden alfabetico.
+func (e *Environment) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for env := e; env != nil; env = env.outer {
+		for _, store := range []map[string]Object{env.global, env.store} {
+			for name := range store {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *Environment) ancestor(depth int) *Environment {
+	env := e
+	for i := 0; i < depth && env != nil; i++ {
+		env = env.outer
+	}
+	return env
+}
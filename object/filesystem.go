@@ -0,0 +1,114 @@
+package object
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kenshindeveloper/april/diag"
+)
+
+//FileSystem decide que archivos pueden usar las funciones del lenguaje open, create,
+//write, read, remove, rename, move e isExist. El valor cero permite todo, como antes.
+type FileSystem struct {
+	Disabled bool   //ninguna funcion de archivos se puede usar
+	ReadOnly bool   //solo se pueden leer archivos, no crearlos, escribirlos ni borrarlos
+	Root     string //si no es "" solo se usan archivos dentro de Root, las rutas relativas parten de Root
+}
+
+//Read devuelve la ruta con la que se lee el archivo 'path', o un error si no se permite.
+func (fs *FileSystem) Read(path string) (string, *Error) {
+	return fs.resolve(path, false)
+}
+
+//Write devuelve la ruta con la que se crea, escribe o borra el archivo 'path', o un error
+//si no se permite.
+func (fs *FileSystem) Write(path string) (string, *Error) {
+	return fs.resolve(path, true)
+}
+
+//CanWrite indica si se puede escribir en un archivo ya abierto.
+func (fs *FileSystem) CanWrite() *Error {
+	if fs == nil {
+		return nil
+	}
+	if fs.Disabled {
+		return denied("file access is disabled")
+	}
+	if fs.ReadOnly {
+		return denied("file system is read-only")
+	}
+	return nil
+}
+
+func (fs *FileSystem) resolve(path string, write bool) (string, *Error) {
+	if fs == nil {
+		return path, nil
+	}
+	if fs.Disabled {
+		return "", denied("file access is disabled")
+	}
+	if write && fs.ReadOnly {
+		return "", denied("file system is read-only, can not modify '%s'", path)
+	}
+	if fs.Root == "" {
+		return path, nil
+	}
+
+	root, err := filepath.Abs(fs.Root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", denied("root directory '%s' is not accessible", fs.Root)
+	}
+	full := path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(root, full)
+	}
+	real, err := realPath(full)
+	if err != nil || !within(root, real) {
+		return "", denied("path '%s' is outside of the root directory", path)
+	}
+	return real, nil
+}
+
+//realPath devuelve la ruta absoluta de 'path' sin enlaces simbolicos. La parte de la
+//ruta que todavia no existe se agrega tal cual a la parte que existe.
+func realPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	rest := ""
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+//within indica si 'path' es 'root' o esta dentro de el.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func denied(format string, a ...interface{}) *Error {
+	return &Error{Code: diag.PermissionDenied, Message: fmt.Sprintf(format, a...)}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
 )
 
 const (
@@ -26,6 +27,7 @@ const (
 	HASH_OBJ     = "HASH"
 	STREAM_OBJ   = "STREAM"
 	STRUCT_OBJ   = "STRUCT"
+	MODULE_OBJ   = "MODULE"
 )
 
 type ObjectType string
@@ -67,6 +69,10 @@ func GetType(name ObjectType) string {
 		return "map"
 	case STREAM_OBJ:
 		return "stream"
+	case MODULE_OBJ:
+		return "module"
+	case STRUCT_OBJ:
+		return "struct"
 	default:
 		return "null"
 	}
@@ -155,7 +161,11 @@ func (n *Nil) Inspect() string {
 //***************************************************************************************
 
 type Error struct {
+	Code    string
+	Span    diag.Span
 	Message string
+	Trace   []Frame //llamadas en curso cuando se produjo el error, la mas reciente primero.
+	Value   Object  //valor lanzado con throw, es el que recibe el catch.
 }
 
 func (e *Error) Type() ObjectType {
@@ -163,7 +173,35 @@ func (e *Error) Type() ObjectType {
 }
 
 func (e *Error) Inspect() string {
-	return "Error: " + e.Message
+	return "Error: " + e.Diagnostic().Error()
+}
+
+//Catchable indica si un try puede atrapar el error. Los limites de la ejecucion y exit
+//terminan el programa aunque esten dentro de un try, sin ejecutar los finally.
+func (e *Error) Catchable() bool {
+	switch e.Code {
+	case diag.StepLimit, diag.Canceled, diag.Exit:
+		return false
+	}
+	return true
+}
+
+//maxTraceNotes es la cantidad de llamadas de la traza que se muestran en un diagnostico.
+const maxTraceNotes = 20
+
+//Diagnostic devuelve el error como un diagnostico para mostrarlo con el codigo fuente,
+//cada llamada de la traza se agrega como una nota. De una traza muy larga, como la de una
+//recursion sin fin, solo se muestran las llamadas mas recientes.
+func (e *Error) Diagnostic() *diag.Diagnostic {
+	d := &diag.Diagnostic{Severity: diag.Error, Code: e.Code, Span: e.Span, Message: e.Message}
+	for i, frame := range e.Trace {
+		if i == maxTraceNotes {
+			d.Notes = append(d.Notes, fmt.Sprintf("... and %d more calls", len(e.Trace)-i))
+			break
+		}
+		d.Notes = append(d.Notes, fmt.Sprintf("in function '%s' called at %s", frame.Function, frame.Call))
+	}
+	return d
 }
 
 //***************************************************************************************
@@ -172,8 +210,24 @@ func (e *Error) Inspect() string {
 
 type BuiltinFunction func(args ...Object) Object
 
+//Builtin es una funcion del lenguaje escrita en Go. Las que leen o escriben, usan archivos
+//o necesitan otro dato de la ejecucion que las llama usan RuntimeFn en lugar de Fn y
+//reciben esa ejecucion: sus flujos, sus permisos y sus argumentos.
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn        BuiltinFunction
+	RuntimeFn func(runtime *Runtime, args ...Object) Object
+}
+
+//Call llama a la funcion con la ejecucion 'runtime', o con una con los flujos y permisos
+//del proceso si es nil.
+func (b *Builtin) Call(runtime *Runtime, args ...Object) Object {
+	if b.RuntimeFn == nil {
+		return b.Fn(args...)
+	}
+	if runtime == nil {
+		runtime = NewRuntime()
+	}
+	return b.RuntimeFn(runtime, args...)
 }
 
 func (b *Builtin) Type() ObjectType {
@@ -190,6 +244,7 @@ func (b *Builtin) Inspect() string {
 
 type ReturnStatement struct {
 	Value Object
+	Span  diag.Span //sentencia return que produjo el valor
 }
 
 func (rs *ReturnStatement) Type() ObjectType {
@@ -413,3 +468,35 @@ func (s *Struct) Type() ObjectType {
 func (s *Struct) Inspect() string {
 	return "struct"
 }
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//Module es un archivo cargado con 'import "ruta" as nombre'. Sus funciones y variables
+//'global' cuyo nombre empieza con mayuscula se usan desde el programa como nombre.Campo.
+type Module struct {
+	Path string
+	Env  *Environment
+}
+
+func (m *Module) Type() ObjectType {
+	return MODULE_OBJ
+}
+
+func (m *Module) Inspect() string {
+	return "module '" + m.Path + "'"
+}
+
+//Export devuelve el valor del nombre exportado 'name'.
+func (m *Module) Export(name string) (Object, bool) {
+	if !Exported(name) {
+		return nil, false
+	}
+	return m.Env.GetGlobal(name)
+}
+
+//Exported indica si un modulo exporta el nombre 'name': empieza con mayuscula.
+func Exported(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
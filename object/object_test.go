@@ -1,6 +1,16 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenshindeveloper/april/diag"
+)
 
 func TestStringHash(t *testing.T) {
 	hello0 := &String{Value: "hola mundo"}
@@ -20,3 +30,250 @@ func TestStringHash(t *testing.T) {
 		t.Errorf("strings with different context have same hash keys")
 	}
 }
+
+func TestFromGo(t *testing.T) {
+	type point struct {
+		X      int64 `april:"x"`
+		Y      int64
+		hidden int64
+	}
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{int8(5), "5"},
+		{uint(7), "7"},
+		{2.5, "2.5"},
+		{"hola", "'hola'"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "['a', 'b']"},
+		{map[string]int{"a": 1}, "{'a': 1}"},
+		{&Integer{Value: 3}, "3"},
+		{(*int)(nil), "null"},
+		{point{X: 1, Y: 2}, "struct"},
+	}
+
+	for i, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("tests[%d] - unexpected error: %s", i, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("tests[%d] - object is not %q. got=%q", i, tt.expected, obj.Inspect())
+		}
+	}
+
+	obj, _ := FromGo(point{X: 1, Y: 2, hidden: 3})
+	env := obj.(*Struct).Env
+	if x, ok := env.Get("x"); !ok || x.Inspect() != "1" {
+		t.Errorf("field x is wrong. got=%v", x)
+	}
+	if y, ok := env.Get("Y"); !ok || y.Inspect() != "2" {
+		t.Errorf("field Y is wrong. got=%v", y)
+	}
+	if _, ok := env.Get("hidden"); ok {
+		t.Errorf("unexported field must not be converted")
+	}
+
+	if _, err := FromGo(make(chan int)); err == nil {
+		t.Errorf("expected error converting a channel")
+	}
+	if _, err := FromGo(uint64(math.MaxUint64)); err == nil {
+		t.Errorf("expected overflow error converting math.MaxUint64")
+	}
+	if _, err := FromGo(func() (int, string) { return 1, "a" }); err == nil {
+		t.Errorf("expected error converting a function with two results")
+	}
+	if _, err := FromGo(func() (int, error) { return 1, nil }); err != nil {
+		t.Errorf("unexpected error converting a function with a result and an error: %s", err)
+	}
+
+	type node struct {
+		Next *node
+	}
+	loop := &node{}
+	loop.Next = loop
+	cycle := map[string]interface{}{}
+	cycle["self"] = cycle
+	list := []interface{}{nil}
+	list[0] = list
+	for i, input := range []interface{}{loop, cycle, list} {
+		if _, err := FromGo(input); err == nil {
+			t.Errorf("cycles[%d] - expected error converting a cyclic value", i)
+		}
+	}
+
+	shared := []int{1, 2}
+	if obj, err := FromGo([][]int{shared, shared}); err != nil || obj.Inspect() != "[[1, 2], [1, 2]]" {
+		t.Errorf("shared slices are wrong. got=%v (%v)", obj, err)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	var integer int
+	if err := ToGo(&Integer{Value: 42}, &integer); err != nil || integer != 42 {
+		t.Errorf("int is wrong. got=%d (%v)", integer, err)
+	}
+
+	var small int8
+	if err := ToGo(&Integer{Value: 300}, &small); err == nil {
+		t.Errorf("expected overflow error, got=%d", small)
+	}
+
+	var double float64
+	if err := ToGo(&Integer{Value: 2}, &double); err != nil || double != 2 {
+		t.Errorf("float64 is wrong. got=%g (%v)", double, err)
+	}
+
+	var list []string
+	strs := &List{Elements: []Object{&String{Value: "a"}, &String{Value: "b"}}}
+	if err := ToGo(strs, &list); err != nil || len(list) != 2 || list[1] != "b" {
+		t.Errorf("[]string is wrong. got=%v (%v)", list, err)
+	}
+
+	var any interface{}
+	if err := ToGo(strs, &any); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if values, ok := any.([]interface{}); !ok || values[0] != "a" {
+		t.Errorf("interface{} is wrong. got=%#v", any)
+	}
+
+	key := &String{Value: "total"}
+	hash := &Hash{Pairs: map[HashKey]HashPair{key.HashKey(): {Key: key, Value: &Double{Value: 1.5}}}}
+	var m map[string]float64
+	if err := ToGo(hash, &m); err != nil || m["total"] != 1.5 {
+		t.Errorf("map is wrong. got=%v (%v)", m, err)
+	}
+
+	var target struct {
+		Total float64 `april:"total"`
+	}
+	if err := ToGo(hash, &target); err != nil || target.Total != 1.5 {
+		t.Errorf("struct is wrong. got=%+v (%v)", target, err)
+	}
+
+	var obj Object
+	if err := ToGo(hash, &obj); err != nil || obj != hash {
+		t.Errorf("Object is wrong. got=%v (%v)", obj, err)
+	}
+
+	if err := ToGo(&String{Value: "a"}, &integer); err == nil {
+		t.Errorf("expected error converting string to int")
+	}
+	if err := ToGo(&Integer{Value: 1}, integer); err == nil {
+		t.Errorf("expected error with a target that is not a pointer")
+	}
+	if err := ToGo(nil, &integer); err == nil {
+		t.Errorf("expected error converting a nil object")
+	}
+}
+
+func TestWrapFunction(t *testing.T) {
+	obj, err := FromGo(func(sep string, words ...string) (string, error) {
+		if len(words) == 0 {
+			return "", fmt.Errorf("no words")
+		}
+		return strings.Join(words, sep), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	builtin := obj.(*Builtin)
+
+	result := builtin.Fn(&String{Value: "-"}, &String{Value: "a"}, &String{Value: "b"})
+	if result.Inspect() != "'a-b'" {
+		t.Errorf("result is wrong. got=%q", result.Inspect())
+	}
+
+	tests := []struct {
+		args []Object
+		code string
+	}{
+		{[]Object{}, diag.WrongArgumentNum},
+		{[]Object{&Integer{Value: 1}}, diag.TypeMismatch},
+		{[]Object{&String{Value: "-"}}, diag.RuntimeError},
+	}
+
+	for i, tt := range tests {
+		err, ok := builtin.Fn(tt.args...).(*Error)
+		if !ok {
+			t.Errorf("tests[%d] - result is not an error", i)
+			continue
+		}
+		if err.Code != tt.code {
+			t.Errorf("tests[%d] - code is not %s. got=%s (%s)", i, tt.code, err.Code, err.Message)
+		}
+	}
+
+	obj, _ = FromGo(func(i int) int { return []int{}[i] })
+	if err, ok := obj.(*Builtin).Fn(&Integer{Value: 1}).(*Error); !ok || err.Code != diag.RuntimeError {
+		t.Errorf("panic is not a runtime error. got=%v", err)
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root, _ := filepath.EvalSymlinks(dir)
+	if err := os.Mkdir(filepath.Join(root, "box"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "box", "up")); err != nil {
+		t.Fatal(err)
+	}
+	box := filepath.Join(root, "box")
+
+	tests := []struct {
+		fs       *FileSystem
+		path     string
+		write    bool
+		expected string //ruta resultante, "" si no se permite
+	}{
+		{nil, "a.txt", true, "a.txt"},
+		{&FileSystem{}, "a.txt", true, "a.txt"},
+		{&FileSystem{ReadOnly: true}, "a.txt", false, "a.txt"},
+		{&FileSystem{ReadOnly: true}, "a.txt", true, ""},
+		{&FileSystem{Disabled: true}, "a.txt", false, ""},
+		{&FileSystem{Root: box}, "a.txt", true, filepath.Join(box, "a.txt")},
+		{&FileSystem{Root: box}, "new/dir/a.txt", true, filepath.Join(box, "new", "dir", "a.txt")},
+		{&FileSystem{Root: box}, filepath.Join(box, "a.txt"), false, filepath.Join(box, "a.txt")},
+		{&FileSystem{Root: box}, "sub/../a.txt", false, filepath.Join(box, "a.txt")},
+		{&FileSystem{Root: box}, "../a.txt", false, ""},
+		{&FileSystem{Root: box}, "/etc/passwd", false, ""},
+		{&FileSystem{Root: box}, "up/a.txt", false, ""},
+		{&FileSystem{Root: box, ReadOnly: true}, "a.txt", true, ""},
+		{&FileSystem{Root: filepath.Join(root, "missing")}, "a.txt", false, ""},
+	}
+
+	for i, tt := range tests {
+		var path string
+		var err *Error
+		if tt.write {
+			path, err = tt.fs.Write(tt.path)
+		} else {
+			path, err = tt.fs.Read(tt.path)
+		}
+
+		if tt.expected == "" {
+			if err == nil || err.Code != diag.PermissionDenied {
+				t.Errorf("tests[%d] - %q must be denied. got=%q", i, tt.path, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("tests[%d] - unexpected error: %s", i, err.Message)
+			continue
+		}
+		if path != tt.expected {
+			t.Errorf("tests[%d] - path is not %q. got=%q", i, tt.expected, path)
+		}
+	}
+}
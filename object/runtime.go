@@ -0,0 +1,179 @@
+package object
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/token"
+)
+
+//Frame es una llamada a funcion en curso: el nombre de la funcion y el lugar desde
+//donde se llamo.
+type Frame struct {
+	Function string
+	Call     token.Position
+}
+
+//Runtime guarda el estado de una ejecucion que comparten todos los entornos creados a
+//partir del mismo NewEnvironment.
+type Runtime struct {
+	frames   []Frame
+	tries    []int //cantidad de try en curso de cada llamada
+	IO       *IO
+	FS       *FileSystem //permisos de las funciones de archivos, nil permite todo
+	Importer Importer    //carga los modulos de 'import ... as', nil si no se pueden importar
+	Args     []string    //argumentos del programa, los que siguen al archivo en 'april run'
+	ctx      context.Context
+	maxSteps int64
+	steps    int64
+	maxDepth int
+}
+
+//DefaultMaxDepth es la cantidad maxima de llamadas en curso de una ejecucion si no se
+//cambia con SetMaxDepth.
+const DefaultMaxDepth = 10000
+
+func NewRuntime() *Runtime {
+	return &Runtime{IO: &IO{Stdout: os.Stdout, Stderr: os.Stderr, Stdin: stdin}, maxDepth: DefaultMaxDepth}
+}
+
+//Importer carga los modulos de una ejecucion. Import devuelve el *Module del archivo
+//'path' importado desde el archivo 'from', o un *Error si no se puede cargar.
+type Importer interface {
+	Import(runtime *Runtime, from, path string) Object
+}
+
+//stdin es la entrada del proceso, la comparten todas las ejecuciones para no perder lo
+//que ya se leyo en el buffer de otra.
+var stdin = bufio.NewReader(os.Stdin)
+
+//IO son los flujos con los que las funciones del lenguaje escriben y leen, por defecto
+//la salida, la salida de errores y la entrada del proceso.
+type IO struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  *bufio.Reader
+}
+
+func NewIO(stdout, stderr io.Writer, input io.Reader) *IO {
+	return &IO{Stdout: stdout, Stderr: stderr, Stdin: NewReader(input)}
+}
+
+//NewReader devuelve 'input' con buffer, sin agregar otro si ya lo tiene.
+func NewReader(input io.Reader) *bufio.Reader {
+	if reader, ok := input.(*bufio.Reader); ok {
+		return reader
+	}
+	if input == os.Stdin {
+		return stdin
+	}
+	return bufio.NewReader(input)
+}
+
+//contextCheck indica cada cuantos pasos se consulta si el contexto se cancelo.
+const contextCheck = 256
+
+//Limit empieza a contar los pasos de una ejecucion. 'ctx' puede cancelarla y 'maxSteps'
+//es la cantidad maxima de pasos, 0 indica que no hay limite.
+func (r *Runtime) Limit(ctx context.Context, maxSteps int64) {
+	r.ctx = ctx
+	r.maxSteps = maxSteps
+	r.steps = 0
+}
+
+//Step cuenta un paso de la ejecucion: una sentencia, una vuelta de un for o una llamada.
+//Devuelve un error si se supero el limite de pasos o si se cancelo el contexto.
+func (r *Runtime) Step() *Error {
+	r.steps++
+	if r.maxSteps > 0 && r.steps > r.maxSteps {
+		return &Error{Code: diag.StepLimit, Message: fmt.Sprintf("step limit of %d exceeded", r.maxSteps)}
+	}
+	if r.ctx != nil && r.steps%contextCheck == 1 {
+		if err := r.ctx.Err(); err != nil {
+			return &Error{Code: diag.Canceled, Message: "execution canceled: " + err.Error()}
+		}
+	}
+	return nil
+}
+
+//SetMaxDepth cambia la cantidad maxima de llamadas en curso, 0 vuelve al valor por
+//defecto DefaultMaxDepth.
+func (r *Runtime) SetMaxDepth(maxDepth int) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	r.maxDepth = maxDepth
+}
+
+//MaxDepth devuelve la cantidad maxima de llamadas en curso.
+func (r *Runtime) MaxDepth() int {
+	return r.maxDepth
+}
+
+//Overflow devuelve un error si hay mas llamadas en curso que las permitidas.
+func (r *Runtime) Overflow() *Error {
+	if len(r.frames) > r.maxDepth {
+		return &Error{Code: diag.StackOverflow, Message: fmt.Sprintf("maximum call depth of %d exceeded", r.maxDepth)}
+	}
+	return nil
+}
+
+//Push registra la llamada 'frame' al entrar a una funcion.
+func (r *Runtime) Push(frame Frame) {
+	r.frames = append(r.frames, frame)
+	r.tries = append(r.tries, 0)
+}
+
+//Pop elimina la ultima llamada registrada.
+func (r *Runtime) Pop() {
+	if len(r.frames) > 0 {
+		r.frames = r.frames[:len(r.frames)-1]
+		r.tries = r.tries[:len(r.tries)-1]
+	}
+}
+
+//Replace cambia la ultima llamada registrada por 'frame', es lo que hace una llamada en
+//posicion de cola que reutiliza la llamada en curso.
+func (r *Runtime) Replace(frame Frame) {
+	if len(r.frames) > 0 {
+		r.frames[len(r.frames)-1] = frame
+	}
+}
+
+//EnterTry indica que la llamada en curso entra a un try, y LeaveTry que sale de el.
+func (r *Runtime) EnterTry() {
+	if len(r.tries) > 0 {
+		r.tries[len(r.tries)-1]++
+	}
+}
+
+func (r *Runtime) LeaveTry() {
+	if len(r.tries) > 0 {
+		r.tries[len(r.tries)-1]--
+	}
+}
+
+//TailPosition indica si un return puede terminar la llamada en curso antes de llamar a
+//la funcion que devuelve: hay una llamada en curso y no esta dentro de un try, cuyo
+//catch y finally deben ver el resultado.
+func (r *Runtime) TailPosition() bool {
+	return len(r.tries) > 0 && r.tries[len(r.tries)-1] == 0
+}
+
+//Depth devuelve la cantidad de llamadas en curso.
+func (r *Runtime) Depth() int {
+	return len(r.frames)
+}
+
+//Trace devuelve una copia de las llamadas en curso, la mas reciente primero.
+func (r *Runtime) Trace() []Frame {
+	trace := make([]Frame, len(r.frames))
+	for i, frame := range r.frames {
+		trace[len(r.frames)-1-i] = frame
+	}
+	return trace
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/lexer"
 	"github.com/kenshindeveloper/april/token"
 )
@@ -26,6 +27,7 @@ var scope = map[string]int{
 	"fn":  0,
 	"if":  1,
 	"for": 2,
+	"try": 3,
 }
 
 var precedens = map[token.TokenType]int{
@@ -71,7 +73,10 @@ type Parser struct {
 	infixFns   map[token.TokenType]infixFn
 	postfixFns map[token.TokenType]postfixFn
 
-	errors []string
+	errors    []*diag.Diagnostic
+	panicking bool
+
+	comments []*ast.Comment
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -140,12 +145,61 @@ func (p *Parser) ParserProgram() *ast.Program {
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+		if p.panicking {
+			p.recoverStatement()
+		}
 		p.nextToken()
 	}
 
+	program.Comments = p.comments
 	return program
 }
 
+//recoverStatement sale del modo panico despues de una sentencia con errores.
+func (p *Parser) recoverStatement() {
+	if p.curTokenIs(token.RBRACE) {
+		p.panicking = false
+		return
+	}
+	p.synchronize()
+}
+
+//synchronize descarta tokens despues de un error hasta un punto seguro: un ';', el
+//final de un bloque o el comienzo de una sentencia. Al volver el token actual es el
+//ultimo token descartado, de modo que el siguiente nextToken comienza la proxima sentencia.
+func (p *Parser) synchronize() {
+	p.panicking = false
+
+	for !p.curTokenIs(token.EOF) && !p.curTokenIs(token.SEMICOLON) {
+		if p.curTokenIs(token.LBRACE) {
+			p.skipBlock()
+			return
+		}
+
+		switch p.peekToken.Type {
+		case token.VAR, token.GLOBAL, token.FNCLOSURE, token.FOR, token.IF, token.RETURN, token.IMPORT, token.TRY, token.THROW, token.RBRACE, token.EOF:
+			return
+		}
+		p.nextToken()
+	}
+}
+
+//skipBlock avanza desde un '{' hasta su '}' correspondiente.
+func (p *Parser) skipBlock() {
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.LBRACE) {
+			depth++
+		} else if p.curTokenIs(token.RBRACE) {
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.VAR:
@@ -162,20 +216,22 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseImportStatement()
 	case token.FNCLOSURE:
 		return p.parseFunctionStatement()
-	case token.COMMENT:
-		return nil
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
 func (p *Parser) parseFunctionStatement() ast.Statement {
-	fn := &ast.Function{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	fn := &ast.Function{Token: p.curToken}
 
 	if !p.expectedTokenPeek(token.IDENT) {
 		return nil
 	}
-	fn.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	fn.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 	if !p.expectedTokenPeek(token.LPAREN) {
 		return nil
@@ -188,12 +244,11 @@ func (p *Parser) parseFunctionStatement() ast.Statement {
 
 	if p.isPeekBasicType() {
 		p.nextToken()
-		fn.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+		fn.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 		p.nextToken()
 
 	} else if p.peekTokenIs(token.IDENT) {
-		msg := fmt.Sprintf("Line: %d - function expression is incorrect.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidStatement, "function expression is incorrect.")
 		return nil
 	} else if p.peekTokenIs(token.LBRACE) {
 		fn.Type = nil
@@ -202,8 +257,7 @@ func (p *Parser) parseFunctionStatement() ast.Statement {
 
 	//ultima validacion para estar seguro del token actual.
 	if !p.curTokenIs(token.LBRACE) {
-		msg := fmt.Sprintf("Line: %d - function expression is incorrect. : %s", lexer.NUMBER_LINE, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.missing(p.curToken), diag.InvalidStatement, "function expression is incorrect. : %s", p.curToken.Literal)
 		return nil
 	}
 
@@ -224,42 +278,39 @@ func (p *Parser) parseFunctionParametersExpression() []*ast.FunctionParameters {
 	}
 
 	p.nextToken()
-	param := &ast.FunctionParameters{Token: p.curToken, Line: lexer.NUMBER_LINE}
-	param.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	param := &ast.FunctionParameters{Token: p.curToken}
+	param.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 	if !p.expectedTokenPeek(token.COLON) {
 		return nil
 	}
 	if !p.isPeekBasicType() {
-		msg := fmt.Sprintf("Line: %d - function paramenters incorrect. : %s", lexer.NUMBER_LINE, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.missing(p.curToken), diag.InvalidStatement, "function paramenters incorrect. : %s", p.curToken.Literal)
 		return nil
 	}
 	p.nextToken()
-	param.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	param.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 	paramenters = append(paramenters, param)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken() //coma
 		p.nextToken() //valor
-		param := &ast.FunctionParameters{Token: p.curToken, Line: lexer.NUMBER_LINE}
-		param.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+		param := &ast.FunctionParameters{Token: p.curToken}
+		param.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 		if !p.expectedTokenPeek(token.COLON) {
 			return nil
 		}
 		if !p.isPeekBasicType() {
-			msg := fmt.Sprintf("Line: %d - function paramenters incorrect. : %s", lexer.NUMBER_LINE, p.curToken.Literal)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.missing(p.curToken), diag.InvalidStatement, "function paramenters incorrect. : %s", p.curToken.Literal)
 			return nil
 		}
 		p.nextToken()
-		param.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+		param.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 		paramenters = append(paramenters, param)
 	}
 	if !p.peekTokenIs(token.RPAREN) {
-		msg := fmt.Sprintf("Line: %d - function paramenters incorrect.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.missing(p.curToken), diag.InvalidStatement, "function paramenters incorrect.")
 		return nil
 	}
 	p.nextToken()
@@ -268,21 +319,49 @@ func (p *Parser) parseFunctionParametersExpression() []*ast.FunctionParameters {
 }
 
 func (p *Parser) parseImportStatement() ast.Statement {
+	is := &ast.ImportStatement{Token: p.curToken}
+
 	if !p.peekTokenIs(token.STRING) {
-		msg := fmt.Sprintf("Line: %d - import expression wrong.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.peekToken, diag.InvalidStatement, "import expression wrong.")
 		return nil
 	}
+	is.Path = &ast.String{Token: p.peekToken, Value: p.peekToken.Literal}
 
-	if !p.lexer.ReadFile(p.peekToken.Literal) {
-		msg := fmt.Sprintf("Line: %d - incorrect path file: '%s'.", lexer.NUMBER_LINE, p.peekToken.Literal)
-		p.errors = append(p.errors, msg)
+	//con 'as' el archivo se carga como un modulo al ejecutar el import.
+	p.lexer.Save()
+	next := p.lexer.NextToken()
+	for next.Type == token.COMMENT {
+		next = p.lexer.NextToken()
+	}
+	p.lexer.Continue()
+	if next.Type == token.IDENT && next.Literal == "as" {
+		p.nextToken()
+		p.nextToken()
+		if !p.expectedTokenPeek(token.IDENT) {
+			return nil
+		}
+		is.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
+		return is
+	}
+
+	//el lexer agrega el archivo antes de avanzar, asi el siguiente token es el primero del archivo importado.
+	switch err := p.lexer.ReadFile(is.Path.Value); {
+	case err == lexer.ErrCycle:
+		p.errorf(is.Path.Token, diag.ImportCycle, "import cycle: '%s' is already being imported.", is.Path.Value)
+	case err != nil:
+		code := diag.ImportNotFound
+		if _, ok := err.(*lexer.DeniedError); ok {
+			code = diag.PermissionDenied
+		}
+		p.errorf(is.Path.Token, code, "%s.", err)
 	}
-	return nil
+	p.nextToken()
+
+	return is
 }
 
 func (p *Parser) parseForStatement() *ast.ForStatement {
-	fs := &ast.ForStatement{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	fs := &ast.ForStatement{Token: p.curToken}
 	flag := false
 
 	if p.peekTokenIs(token.LPAREN) {
@@ -303,6 +382,7 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 		}
 		p.nextToken()
 	}
+	end := p.curToken //'{' del cuerpo, o EOF si el for no termina
 
 	p.lexer.Continue()
 	p.curToken = auxCurToken
@@ -313,14 +393,12 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 		fs.Condition = p.parseExpression(LESSVALUE) //aqui
 		_, ok := fs.Condition.(*ast.ImplicitDeclarationExpression)
 		if !ok && flag && !p.expectedTokenPeek(token.RPAREN) {
-			msg := fmt.Sprintf("Line: %d - for expression is incorrect, expected token ')'", lexer.NUMBER_LINE)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.missing(p.curToken), diag.UnexpectedToken, "for expression is incorrect, expected token ')'")
 			return nil
 		}
 
 		if !ok && !p.peekTokenIs(token.LBRACE) {
-			msg := fmt.Sprintf("Line: %d - for expression is incorrect, expected token '{'", lexer.NUMBER_LINE)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.missing(p.curToken), diag.UnexpectedToken, "for expression is incorrect, expected token '{'")
 			return nil
 		}
 
@@ -339,15 +417,13 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 		p.nextToken()
 		fs.Condition = p.parseExpression(LESSVALUE) //aqui
 		if !p.expectedTokenPeek(token.SEMICOLON) {
-			msg := fmt.Sprintf("Line: %d - for expression is incorrect, expected token ';'", lexer.NUMBER_LINE)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.missing(p.curToken), diag.UnexpectedToken, "for expression is incorrect, expected token ';'")
 			return nil
 		}
 		p.nextToken()
 		fs.Operation = p.parseExpression(LESSVALUE)
 		if flag && !p.expectedTokenPeek(token.LBRACE) {
-			msg := fmt.Sprintf("Line: %d - for expression is incorrect, expected token '{'", lexer.NUMBER_LINE)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.missing(p.curToken), diag.UnexpectedToken, "for expression is incorrect, expected token '{'")
 			return nil
 		}
 
@@ -358,14 +434,22 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 		fs.Body.Type = scope["for"]
 		return fs
 	default:
-		msg := fmt.Sprintf("Line: %d - count ';' incorrect.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		tok := fs.Token
+		if end.Type == token.EOF {
+			tok = end
+		}
+		p.errorf(tok, diag.UnexpectedToken, "count ';' incorrect.")
+		//se descarta el cuerpo completo para que el error no se propague a sus sentencias.
+		for !p.curTokenIs(token.LBRACE) && !p.curTokenIs(token.EOF) {
+			p.nextToken()
+		}
+		p.skipBlock()
 		return nil
 	}
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
-	rs := &ast.ReturnStatement{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	rs := &ast.ReturnStatement{Token: p.curToken}
 
 	p.nextToken()
 	rs.Expression = p.parseExpression(LESSVALUE)
@@ -377,7 +461,7 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 }
 
 func (p *Parser) parseBreakStatement() *ast.BreakStatement {
-	b := &ast.BreakStatement{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	b := &ast.BreakStatement{Token: p.curToken}
 
 	p.nextToken()
 	if !p.curTokenIs(token.SEMICOLON) {
@@ -387,64 +471,123 @@ func (p *Parser) parseBreakStatement() *ast.BreakStatement {
 	return b
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	ts := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	ts.Expression = p.parseExpression(LESSVALUE)
+	if ts.Expression == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return ts
+}
+
+//try { ... } catch (e) { ... } finally { ... }
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	ts := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectedTokenPeek(token.LBRACE) {
+		return nil
+	}
+	ts.Block = p.parseBlockStatement()
+	if ts.Block == nil {
+		return nil
+	}
+	ts.Block.Type = scope["try"]
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if !p.expectedTokenPeek(token.LPAREN) || !p.expectedTokenPeek(token.IDENT) {
+			return nil
+		}
+		ts.Parameter = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
+		if !p.expectedTokenPeek(token.RPAREN) || !p.expectedTokenPeek(token.LBRACE) {
+			return nil
+		}
+
+		ts.Catch = p.parseBlockStatement()
+		if ts.Catch == nil {
+			return nil
+		}
+		ts.Catch.Type = scope["try"]
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectedTokenPeek(token.LBRACE) {
+			return nil
+		}
+
+		ts.Finally = p.parseBlockStatement()
+		if ts.Finally == nil {
+			return nil
+		}
+		ts.Finally.Type = scope["try"]
+	}
+
+	if ts.Catch == nil && ts.Finally == nil {
+		p.errorf(p.missing(ts.Token), diag.InvalidStatement, "try statement is incorrect, expected 'catch' or 'finally'")
+		return nil
+	}
+	return ts
+}
+
 func (p *Parser) parseVarStatement() *ast.VarStatement {
-	vs := &ast.VarStatement{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	vs := &ast.VarStatement{Token: p.curToken}
 
 	if !p.expectedTokenPeek(token.IDENT) {
-		msg := fmt.Sprintf("Line: %d - var is not declated.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "var is not declated.")
 		return nil
 	}
-	vs.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	vs.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 	if !p.expectedTokenPeek(token.COLON) {
-		msg := fmt.Sprintf("Line: %d - colon ':' is not declated.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "colon ':' is not declated.")
 		return nil
 	}
 
 	if !p.isPeekBasicType() {
-		msg := fmt.Sprintf("Line: %d - type '%s' is not declated.", lexer.NUMBER_LINE, p.peekToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.missing(p.curToken), diag.InvalidDeclaration, "type '%s' is not declated.", p.peekToken.Literal)
 		return nil
 	}
 	p.nextToken()
 
 	typeName := p.curToken.Literal
-	vs.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	vs.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 	p.nextToken()
 	//!p.curTokenIs(token.EQUAL)
 	if p.curTokenIs(token.EOF) || p.curTokenIs(token.SEMICOLON) {
 		if typeName == "int" {
-			tok := token.Token{Type: token.INT, Literal: "0"}
-			vs.Value = &ast.Integer{Token: tok, Value: 0, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.INT, Literal: "0", Pos: p.curToken.Pos}
+			vs.Value = &ast.Integer{Token: tok, Value: 0}
 		} else if typeName == "bool" {
-			tok := token.Token{Type: token.FALSE, Literal: "false"}
-			vs.Value = &ast.Boolean{Token: tok, Value: false, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.FALSE, Literal: "false", Pos: p.curToken.Pos}
+			vs.Value = &ast.Boolean{Token: tok, Value: false}
 		} else if typeName == "string" {
-			tok := token.Token{Type: token.STRING, Literal: ""}
-			vs.Value = &ast.String{Token: tok, Value: "", Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "", Pos: p.curToken.Pos}
+			vs.Value = &ast.String{Token: tok, Value: ""}
 		} else if typeName == "double" {
-			tok := token.Token{Type: token.STRING, Literal: "0.0"}
-			vs.Value = &ast.Double{Token: tok, Value: 0.0, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "0.0", Pos: p.curToken.Pos}
+			vs.Value = &ast.Double{Token: tok, Value: 0.0}
 		} else if typeName == "list" {
-			tok := token.Token{Type: token.STRING, Literal: "[]"}
-			vs.Value = &ast.List{Token: tok, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "[]", Pos: p.curToken.Pos}
+			vs.Value = &ast.List{Token: tok}
 		} else if typeName == "map" {
-			tok := token.Token{Type: token.STRING, Literal: "{}"}
-			vs.Value = &ast.Hash{Token: tok, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "{}", Pos: p.curToken.Pos}
+			vs.Value = &ast.Hash{Token: tok}
 		} else if typeName == "func" {
-			msg := fmt.Sprintf("Line: %d - declaration func error: var %s '%s' must be a expression.", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.InvalidDeclaration, "declaration func error: var %s '%s' must be a expression.", vs.Name, strings.ToUpper(typeName))
 			return nil
 		} else if typeName == "stream" {
-			msg := fmt.Sprintf("Line: %d - declaration stream error: var %s '%s' must be a expression.", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.InvalidDeclaration, "declaration stream error: var %s '%s' must be a expression.", vs.Name, strings.ToUpper(typeName))
 			return nil
 		} else {
-			msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' ", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' ", vs.Name, strings.ToUpper(typeName))
 			return nil
 		}
 
@@ -456,53 +599,45 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 			switch vs.Value.(type) {
 			case *ast.Hash:
 				if typeName != "map" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = MAP", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = MAP", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.List:
 				if typeName != "list" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = LIST", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = LIST", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Integer:
 				if typeName == "double" {
 					v := vs.Value.(*ast.Integer)
-					vs.Value = &ast.Double{Token: v.Token, Value: float64(v.Value), Line: lexer.NUMBER_LINE}
+					vs.Value = &ast.Double{Token: v.Token, Value: float64(v.Value)}
 				} else if typeName != "int" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = INTEGER", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = INTEGER", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Boolean:
 				if typeName != "bool" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = BOOLEAN", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = BOOLEAN", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.String:
 				if typeName != "string" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = STRING", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = STRING", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Double:
 				if typeName != "double" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = DOUBLE", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = DOUBLE", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.FunctionClosure:
 				if typeName != "func" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = FUNC", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = FUNC", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Stream:
 				if typeName != "func" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = STREAM", lexer.NUMBER_LINE, vs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = STREAM", vs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			}
@@ -514,70 +649,63 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 
 		return vs
 	} else {
-		msg := fmt.Sprintf("Line: %d - token incorrect '%s'.", lexer.NUMBER_LINE, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.UnexpectedToken, "token incorrect '%s'.", p.curToken.Literal)
 		return nil
 	}
 }
 
 func (p *Parser) parseGlobalStatement() *ast.GlobalStatement {
-	gs := &ast.GlobalStatement{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	gs := &ast.GlobalStatement{Token: p.curToken}
 
 	if !p.expectedTokenPeek(token.IDENT) {
-		msg := fmt.Sprintf("Line: %d - var is not declated.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "var is not declated.")
 		return nil
 	}
-	gs.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	gs.Name = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 	if !p.expectedTokenPeek(token.COLON) {
-		msg := fmt.Sprintf("Line: %d - colon ':' is not declated.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "colon ':' is not declated.")
 		return nil
 	}
 
 	if !p.isPeekBasicType() {
-		msg := fmt.Sprintf("Line: %d - type '%s' is not declated.", lexer.NUMBER_LINE, p.peekToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.missing(p.curToken), diag.InvalidDeclaration, "type '%s' is not declated.", p.peekToken.Literal)
 		return nil
 	}
 	p.nextToken()
 
 	typeName := p.curToken.Literal
-	gs.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	gs.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 	p.nextToken()
 	//!p.curTokenIs(token.EQUAL)
 	if p.curTokenIs(token.EOF) || p.curTokenIs(token.SEMICOLON) {
 		if typeName == "int" {
-			tok := token.Token{Type: token.INT, Literal: "0"}
-			gs.Value = &ast.Integer{Token: tok, Value: 0, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.INT, Literal: "0", Pos: p.curToken.Pos}
+			gs.Value = &ast.Integer{Token: tok, Value: 0}
 		} else if typeName == "bool" {
-			tok := token.Token{Type: token.FALSE, Literal: "false"}
-			gs.Value = &ast.Boolean{Token: tok, Value: false, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.FALSE, Literal: "false", Pos: p.curToken.Pos}
+			gs.Value = &ast.Boolean{Token: tok, Value: false}
 		} else if typeName == "string" {
-			tok := token.Token{Type: token.STRING, Literal: ""}
-			gs.Value = &ast.String{Token: tok, Value: "", Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "", Pos: p.curToken.Pos}
+			gs.Value = &ast.String{Token: tok, Value: ""}
 		} else if typeName == "double" {
-			tok := token.Token{Type: token.STRING, Literal: "0.0"}
-			gs.Value = &ast.Double{Token: tok, Value: 0.0, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "0.0", Pos: p.curToken.Pos}
+			gs.Value = &ast.Double{Token: tok, Value: 0.0}
 		} else if typeName == "list" {
-			tok := token.Token{Type: token.STRING, Literal: "[]"}
-			gs.Value = &ast.List{Token: tok, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "[]", Pos: p.curToken.Pos}
+			gs.Value = &ast.List{Token: tok}
 		} else if typeName == "map" {
-			tok := token.Token{Type: token.STRING, Literal: "{}"}
-			gs.Value = &ast.Hash{Token: tok, Line: lexer.NUMBER_LINE}
+			tok := token.Token{Type: token.STRING, Literal: "{}", Pos: p.curToken.Pos}
+			gs.Value = &ast.Hash{Token: tok}
 		} else if typeName == "func" {
-			msg := fmt.Sprintf("Line: %d - declaration func error: var %s '%s' must be a expression.", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.InvalidDeclaration, "declaration func error: var %s '%s' must be a expression.", gs.Name, strings.ToUpper(typeName))
 			return nil
 		} else if typeName == "stream" {
-			msg := fmt.Sprintf("Line: %d - declaration stream error: var %s '%s' must be a expression.", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.InvalidDeclaration, "declaration stream error: var %s '%s' must be a expression.", gs.Name, strings.ToUpper(typeName))
 			return nil
 		} else {
-			msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' ", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' ", gs.Name, strings.ToUpper(typeName))
 			return nil
 		}
 
@@ -589,53 +717,45 @@ func (p *Parser) parseGlobalStatement() *ast.GlobalStatement {
 			switch gs.Value.(type) {
 			case *ast.Hash:
 				if typeName != "map" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = MAP", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = MAP", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.List:
 				if typeName != "list" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = LIST", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = LIST", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Integer:
 				if typeName == "double" {
 					v := gs.Value.(*ast.Integer)
-					gs.Value = &ast.Double{Token: v.Token, Value: float64(v.Value), Line: lexer.NUMBER_LINE}
+					gs.Value = &ast.Double{Token: v.Token, Value: float64(v.Value)}
 				} else if typeName != "int" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = INTEGER", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = INTEGER", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Boolean:
 				if typeName != "bool" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = BOOLEAN", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = BOOLEAN", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.String:
 				if typeName != "string" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = STRING", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = STRING", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Double:
 				if typeName != "double" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = DOUBLE", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = DOUBLE", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.FunctionClosure:
 				if typeName != "func" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = FUNC", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = FUNC", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			case *ast.Stream:
 				if typeName != "func" {
-					msg := fmt.Sprintf("Line: %d - declaration error: var %s '%s' = STREAM", lexer.NUMBER_LINE, gs.Name, strings.ToUpper(typeName))
-					p.errors = append(p.errors, msg)
+					p.errorf(p.curToken, diag.InvalidDeclaration, "declaration error: var %s '%s' = STREAM", gs.Name, strings.ToUpper(typeName))
 					return nil
 				}
 			}
@@ -647,8 +767,7 @@ func (p *Parser) parseGlobalStatement() *ast.GlobalStatement {
 
 		return gs
 	} else {
-		msg := fmt.Sprintf("Line: %d - token incorrect '%s'.", lexer.NUMBER_LINE, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.UnexpectedToken, "token incorrect '%s'.", p.curToken.Literal)
 		return nil
 	}
 }
@@ -679,7 +798,7 @@ func (p *Parser) isPeekBasicType() bool {
 }
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	es := &ast.ExpressionStatement{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	es := &ast.ExpressionStatement{Token: p.curToken}
 	es.Expression = p.parseExpression(LESSVALUE)
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -702,10 +821,9 @@ func (p *Parser) parseExpression(preceden int) ast.Expression {
 		if p.curToken.Literal == "int" || p.curToken.Literal == "double" {
 			tok := p.curToken
 			p.nextToken()
-			leftExpression = p.parseCallExpression(&ast.Identifier{Token: tok, Name: tok.Literal, Line: lexer.NUMBER_LINE})
+			leftExpression = p.parseCallExpression(&ast.Identifier{Token: tok, Name: tok.Literal})
 		} else {
-			msg := fmt.Sprintf("Line: %d - no prefix parse function for '%s' found, literal: '%s'", lexer.NUMBER_LINE, p.curToken.Type, p.curToken.Literal)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.MissingExpression, "no prefix parse function for '%s' found, literal: '%s'", p.curToken.Type, p.curToken.Literal)
 			return nil
 		}
 	}
@@ -734,20 +852,17 @@ func (p *Parser) parsePostfixExpression() ast.Expression {
 	postfix := &ast.PostfixExpression{
 		Token:    p.peekToken,
 		Operator: p.peekToken.Literal,
-		Line:     lexer.NUMBER_LINE,
 	}
 
 	prefix := p.prefixFns[p.curToken.Type]
 	if prefix == nil {
-		msg := fmt.Sprintf("Line: %d - no prefix parse function for '%s' found, literal: '%s'", lexer.NUMBER_LINE, p.curToken.Type, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.MissingExpression, "no prefix parse function for '%s' found, literal: '%s'", p.curToken.Type, p.curToken.Literal)
 		return nil
 	}
 
 	ident, ok := prefix().(*ast.Identifier)
 	if !ok {
-		msg := fmt.Sprintf("Line: %d - error expression is not type 'identifier'. got'%T'", lexer.NUMBER_LINE, prefix())
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "error expression is not type 'identifier'. got'%T'", prefix())
 		return nil
 	}
 
@@ -790,28 +905,26 @@ func (p *Parser) parseEvalBracketExapression() ast.Expression {
 }
 
 func (p *Parser) parseStrucExpression() ast.Expression {
-	s := &ast.Struct{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	s := &ast.Struct{Token: p.curToken}
 	s.Element = make(map[*ast.Identifier]*ast.Identifier)
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 		if token.LookKeyword(p.curToken.Literal) != token.IDENT {
-			msg := fmt.Sprintf("Line: %d - struct var definition is incorrect, expected token type 'IDENTIFIER', got='%s'", lexer.NUMBER_LINE, p.curToken.Literal)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.curToken, diag.UnexpectedToken, "struct var definition is incorrect, expected token type 'IDENTIFIER', got='%s'", p.curToken.Literal)
 			return nil
 		}
-		data := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+		data := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 		if !p.expectedTokenPeek(token.COLON) {
 			return nil
 		}
 
 		if !p.isPeekBasicType() {
-			msg := fmt.Sprintf("Line: %d - struct type definition is incorrect, expected token type 'IDENTIFIER', got='%s'", lexer.NUMBER_LINE, p.peekToken.Literal)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.missing(p.curToken), diag.UnexpectedToken, "struct type definition is incorrect, expected token type 'IDENTIFIER', got='%s'", p.peekToken.Literal)
 			return nil
 		}
 		p.nextToken()
-		dataType := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+		dataType := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 
 		s.Element[data] = dataType
 		if !p.peekTokenIs(token.RBRACE) && !p.expectedTokenPeek(token.COMMA) {
@@ -826,7 +939,7 @@ func (p *Parser) parseStrucExpression() ast.Expression {
 }
 
 func (p *Parser) parseHashExpression() ast.Expression {
-	hash := &ast.Hash{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	hash := &ast.Hash{Token: p.curToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.RBRACE) {
@@ -851,7 +964,7 @@ func (p *Parser) parseHashExpression() ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	expr := &ast.IndexExpression{Token: p.curToken, Left: left, Line: lexer.NUMBER_LINE}
+	expr := &ast.IndexExpression{Token: p.curToken, Left: left}
 
 	p.nextToken()
 	expr.Index = p.parseExpression(LESSVALUE)
@@ -888,7 +1001,7 @@ func (p *Parser) parseListExpressions(end token.TokenType) []ast.Expression {
 }
 
 func (p *Parser) parseListExpression() ast.Expression {
-	l := &ast.List{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	l := &ast.List{Token: p.curToken}
 
 	l.Elements = p.parseListExpressions(token.RBRACKET)
 
@@ -896,7 +1009,7 @@ func (p *Parser) parseListExpression() ast.Expression {
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	expr := &ast.CallExpression{Token: p.curToken, Function: function, Line: lexer.NUMBER_LINE}
+	expr := &ast.CallExpression{Token: p.curToken, Function: function}
 	expr.Arguments = p.parseListExpressions(token.RPAREN)
 	return expr
 }
@@ -926,10 +1039,9 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 }
 
 func (p *Parser) parseFnClosure() ast.Expression {
-	fn := &ast.FunctionClosure{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	fn := &ast.FunctionClosure{Token: p.curToken}
 
-	p.nextToken()
-	if !p.curTokenIs(token.LPAREN) {
+	if !p.expectedTokenPeek(token.LPAREN) {
 		return nil
 	}
 
@@ -940,12 +1052,11 @@ func (p *Parser) parseFnClosure() ast.Expression {
 
 	if p.isPeekBasicType() {
 		p.nextToken()
-		fn.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+		fn.Type = &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 		p.nextToken()
 
 	} else if p.peekTokenIs(token.IDENT) {
-		msg := fmt.Sprintf("Line: %d - function closure expression is incorrect.", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidStatement, "function closure expression is incorrect.")
 		return nil
 	} else if p.peekTokenIs(token.LBRACE) {
 		fn.Type = nil
@@ -970,13 +1081,13 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 
 	p.nextToken()
-	ident := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	ident := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken() //coma
 		p.nextToken() //valor
-		ident := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+		ident := &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
 	if !p.peekTokenIs(token.RPAREN) {
@@ -988,15 +1099,14 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
-	ie := &ast.IfExpression{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	ie := &ast.IfExpression{Token: p.curToken}
 
 	p.nextToken()
 	ie.Codition = p.parseExpression(LESSVALUE)
 	p.nextToken()
 
 	if !p.curTokenIs(token.LBRACE) {
-		msg := fmt.Sprintf("Line: %d - if expression is incorrect, expected token '{'", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.UnexpectedToken, "if expression is incorrect, expected token '{'")
 		return nil
 	}
 	ie.Consequence = p.parseBlockStatement()
@@ -1007,8 +1117,7 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 		if !p.peekTokenIs(token.LBRACE) {
-			msg := fmt.Sprintf("Line: %d - else expression is incorrect, expected token '{'", lexer.NUMBER_LINE)
-			p.errors = append(p.errors, msg)
+			p.errorf(p.missing(p.curToken), diag.UnexpectedToken, "else expression is incorrect, expected token '{'")
 			return nil
 		}
 		p.nextToken()
@@ -1025,22 +1134,37 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	if !p.curTokenIs(token.LBRACE) {
-		msg := fmt.Sprintf("Line: %d - block definition is incorrect, expected token '{'", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.missing(p.curToken), diag.UnexpectedToken, "block definition is incorrect, expected token '{'")
 		return nil
 	}
 
-	block := &ast.BlockStatement{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			if d := p.errorf(p.curToken, diag.UnexpectedToken, "block definition is incorrect, expected token '}'"); d != nil {
+				d.Notes = append(d.Notes, fmt.Sprintf("block opened at %s", block.Token.Pos))
+			}
+			return nil
+		}
+
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+		if p.panicking {
+			//el error ocurrio en la llave que cierra este bloque.
+			if p.curTokenIs(token.RBRACE) {
+				p.panicking = false
+				continue
+			}
+			p.recoverStatement()
+		}
 		p.nextToken()
 	}
+	block.Rbrace = p.curToken
 
 	return block
 }
@@ -1048,15 +1172,13 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseImplicitExpression(left ast.Expression) ast.Expression {
 	ident, ok := left.(*ast.Identifier)
 	if !ok {
-		msg := fmt.Sprintf("Line: %d - declaration is not possible,  %T type is not IDENTIFIER", lexer.NUMBER_LINE, left)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "declaration is not possible,  %T type is not IDENTIFIER", left)
 		return nil
 	}
 
 	ide := &ast.ImplicitDeclarationExpression{
 		Token: p.curToken,
 		Left:  ident,
-		Line:  lexer.NUMBER_LINE,
 	}
 
 	p.nextToken()
@@ -1064,8 +1186,7 @@ func (p *Parser) parseImplicitExpression(left ast.Expression) ast.Expression {
 
 	p.nextToken()
 	if !p.curTokenIs(token.EOF) && !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.RPAREN) && !p.curTokenIs(token.LBRACE) {
-		msg := fmt.Sprintf("Line: %d - implicit assigment incorrect, expected token ';'.\n", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.UnexpectedToken, "implicit assigment incorrect, expected token ';'.")
 		return nil
 	}
 
@@ -1075,8 +1196,7 @@ func (p *Parser) parseImplicitExpression(left ast.Expression) ast.Expression {
 func (p *Parser) parseAssignOpeExpression(left ast.Expression) ast.Expression {
 	ident, ok := left.(*ast.Identifier)
 	if !ok {
-		msg := fmt.Sprintf("Line: %d - operation is not possible,  %T type is not IDENTIFIER", lexer.NUMBER_LINE, left)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "operation is not possible,  %T type is not IDENTIFIER", left)
 		return nil
 	}
 
@@ -1084,7 +1204,6 @@ func (p *Parser) parseAssignOpeExpression(left ast.Expression) ast.Expression {
 		Token:    p.curToken,
 		Left:     ident,
 		Operator: p.curToken.Literal,
-		Line:     lexer.NUMBER_LINE,
 	}
 
 	p.nextToken()
@@ -1092,8 +1211,7 @@ func (p *Parser) parseAssignOpeExpression(left ast.Expression) ast.Expression {
 
 	p.nextToken()
 	if !p.curTokenIs(token.EOF) && !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.RPAREN) && !p.curTokenIs(token.LBRACE) {
-		msg := fmt.Sprintf("Line: %d - expression incorrect, expected token ';'.\n", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.UnexpectedToken, "expression incorrect, expected token ';'.")
 		return nil
 	}
 	return aoe
@@ -1105,15 +1223,13 @@ func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 	_, okStruct := left.(*ast.InfixExpression)
 
 	if !okIdent && !okIndex && !okStruct {
-		msg := fmt.Sprintf("Line: %d - declaration is not possible\n", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidDeclaration, "declaration is not possible.")
 		return nil
 	}
 
 	ae := &ast.AssignExpression{
 		Token: p.curToken,
 		Left:  left,
-		Line:  lexer.NUMBER_LINE,
 	}
 
 	p.nextToken()
@@ -1121,8 +1237,7 @@ func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 
 	p.nextToken()
 	if !p.curTokenIs(token.EOF) && !p.curTokenIs(token.SEMICOLON) {
-		msg := fmt.Sprintf("Line: %d - expression incorrect, expected token ';'.\n", lexer.NUMBER_LINE)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.UnexpectedToken, "expression incorrect, expected token ';'.")
 		return nil
 	}
 
@@ -1130,17 +1245,16 @@ func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseBooleanExpression() ast.Expression {
-	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE), Line: lexer.NUMBER_LINE}
+	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
 func (p *Parser) parseParensExpression() ast.Expression {
 	p.nextToken()
 	expression := p.parseExpression(LESSVALUE)
 
-	if !p.peekTokenIs(token.RPAREN) {
+	if !p.expectedTokenPeek(token.RPAREN) {
 		return nil
 	}
-	p.nextToken()
 	return expression
 }
 
@@ -1148,7 +1262,6 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	prefix := &ast.PrefixExpression{
 		Token:    p.curToken,
 		Operator: p.curToken.Literal,
-		Line:     lexer.NUMBER_LINE,
 	}
 
 	p.nextToken()
@@ -1162,7 +1275,6 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Token:    p.curToken,
 		Left:     left,
 		Operator: p.curToken.Literal,
-		Line:     lexer.NUMBER_LINE,
 	}
 	preceden := p.curPrecedence()
 	p.nextToken()
@@ -1172,11 +1284,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseIntegerExpression() ast.Expression {
-	i := &ast.Integer{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	i := &ast.Integer{Token: p.curToken}
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("Line: %d - could not parse %q as integer", lexer.NUMBER_LINE, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidLiteral, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 	i.Value = value
@@ -1184,16 +1295,15 @@ func (p *Parser) parseIntegerExpression() ast.Expression {
 }
 
 func (p *Parser) parseStringExpression() ast.Expression {
-	return &ast.String{Token: p.curToken, Value: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	return &ast.String{Token: p.curToken, Value: p.curToken.Literal}
 }
 
 func (p *Parser) parseDoubleExpression() ast.Expression {
-	d := &ast.Double{Token: p.curToken, Line: lexer.NUMBER_LINE}
+	d := &ast.Double{Token: p.curToken}
 
 	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("Line: %d - could not parse %q as double", lexer.NUMBER_LINE, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, diag.InvalidLiteral, "could not parse %q as double", p.curToken.Literal)
 		return nil
 	}
 	d.Value = value
@@ -1201,11 +1311,11 @@ func (p *Parser) parseDoubleExpression() ast.Expression {
 }
 
 func (p *Parser) parseIdentifierExpression() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	return &ast.Identifier{Token: p.curToken, Name: p.curToken.Literal}
 }
 
 func (p *Parser) parseNilExpression() ast.Expression {
-	return &ast.Nil{Token: p.curToken, Name: p.curToken.Literal, Line: lexer.NUMBER_LINE}
+	return &ast.Nil{Token: p.curToken, Name: p.curToken.Literal}
 }
 
 //***************************************************************************************
@@ -1223,13 +1333,82 @@ func (p *Parser) expectedTokenPeek(t token.TokenType) bool {
 	}
 }
 
+//errorf registra un error de sintaxis sobre el token 'tok'. Despues del primer error de
+//una sentencia el parser queda en modo panico y descarta los errores derivados hasta
+//que synchronize encuentra el comienzo de la siguiente sentencia, en ese caso devuelve nil.
+func (p *Parser) errorf(tok token.Token, code string, format string, a ...interface{}) *diag.Diagnostic {
+	if p.panicking {
+		return nil
+	}
+	p.panicking = true
+
+	//el lexer ya informo los caracteres ilegales.
+	if tok.Type == token.ILLEGAL {
+		return nil
+	}
+
+	if tok.Type == token.EOF {
+		if code == diag.MissingExpression {
+			format, a = "expected an expression", nil
+		}
+		code = diag.UnexpectedEOF
+		format = "unexpected end of input, " + format
+	}
+	d := diag.Errorf(code, diag.SpanOf(tok), format, a...)
+	p.errors = append(p.errors, d)
+	return d
+}
+
+//Incomplete indica si el codigo termina antes de completar el programa: su primer error
+//es un final inesperado, como un bloque sin cerrar o un string sin terminar. La consola
+//sigue leyendo lineas mientras la entrada esta incompleta.
+func (p *Parser) Incomplete() bool {
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
+		return false
+	}
+	code := diagnostics[0].Code
+	return code == diag.UnexpectedEOF || code == diag.UnterminatedString
+}
+
+//missing devuelve el token sobre el que se informa que falta algo despues de 'tok': el
+//final del codigo si ya no quedan tokens, asi el error indica que la entrada esta
+//incompleta, o 'tok'.
+func (p *Parser) missing(tok token.Token) token.Token {
+	if p.peekTokenIs(token.EOF) {
+		return p.peekToken
+	}
+	return tok
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("Line: %d - expected next token to be '%s', got='%s' instead", lexer.NUMBER_LINE, t, p.peekToken.Literal)
-	p.errors = append(p.errors, msg)
+	p.errorf(p.peekToken, diag.UnexpectedToken, "expected next token to be '%s', got='%s' instead", t, p.peekToken.Literal)
 }
 
+//Error devuelve los errores del lexer y del parser como texto, uno por linea.
 func (p *Parser) Error() []string {
-	return p.errors
+	errors := []string{}
+	for _, d := range p.Diagnostics() {
+		errors = append(errors, d.Error())
+	}
+	return errors
+}
+
+//Diagnostics devuelve los errores del lexer y del parser ordenados por posicion.
+func (p *Parser) Diagnostics() []*diag.Diagnostic {
+	diagnostics := append([]*diag.Diagnostic{}, p.lexer.Errors()...)
+	diagnostics = append(diagnostics, p.errors...)
+	diag.Sort(diagnostics)
+	return diagnostics
+}
+
+//Precedence devuelve la precedencia del operador 't', LESSVALUE si no es un operador.
+func Precedence(t token.TokenType) int {
+	value, ok := precedens[t]
+	if !ok {
+		return LESSVALUE
+	}
+	return value
 }
 
 func (p *Parser) curPrecedence() int {
@@ -1271,4 +1450,20 @@ func (p *Parser) peekTokenIs(tokType token.TokenType) bool {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+	for p.peekToken.Type == token.COMMENT {
+		p.comment(p.peekToken)
+		p.peekToken = p.lexer.NextToken()
+	}
+}
+
+//comment guarda un comentario del codigo. Con Save y Continue el parser vuelve a leer
+//tokens, por eso se ignoran los comentarios que ya estan guardados.
+func (p *Parser) comment(tok token.Token) {
+	if n := len(p.comments); n > 0 {
+		last := p.comments[n-1].Token.Pos
+		if last.File == tok.Pos.File && last.Offset >= tok.Pos.Offset {
+			return
+		}
+	}
+	p.comments = append(p.comments, &ast.Comment{Token: tok, Text: tok.Literal})
 }
@@ -2,9 +2,12 @@ package parser
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/lexer"
 )
 
@@ -191,6 +194,99 @@ func TestParsingImportExpression(t *testing.T) {
 	}
 }
 
+func TestImportAsStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{`import "math.april" as math`, "math", `import "math.april" as math`},
+		{`import "lib/util.april" as u`, "u", `import "lib/util.april" as u`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParserProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("len(program.Statements) is not equal '%d'. got='%d'", 1, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name == nil || stmt.Name.Name != tt.name {
+			t.Fatalf("stmt.Name is not '%s'. got=%v", tt.name, stmt.Name)
+		}
+		if stmt.String() != tt.expected {
+			t.Fatalf("stmt.String() is not %q. got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input     string
+		parameter string
+		catch     bool
+		finally   bool
+	}{
+		{"try { x := 1 / 0; } catch (e) { print(e.message); }", "e", true, false},
+		{"try { x := 1; } finally { print(x); }", "", false, true},
+		{"try { throw \"error\"; } catch (err) { } finally { }", "err", true, true},
+	}
+
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParserProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("tests[%d] - len(program.Statements) is not 1. got=%d", i, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("tests[%d] - program.Statements[0] is not '*ast.TryStatement'. got='%T'", i, program.Statements[0])
+		}
+
+		if (stmt.Catch != nil) != tt.catch || (stmt.Finally != nil) != tt.finally {
+			t.Fatalf("tests[%d] - catch/finally is not '%t/%t'. got='%t/%t'", i, tt.catch, tt.finally, stmt.Catch != nil, stmt.Finally != nil)
+		}
+
+		if tt.catch && stmt.Parameter.Name != tt.parameter {
+			t.Fatalf("tests[%d] - stmt.Parameter is not '%s'. got='%s'", i, tt.parameter, stmt.Parameter.Name)
+		}
+	}
+
+	p := New(lexer.New("try { x := 1; }\nvar a:int = 1;"))
+	p.ParserProgram()
+	errors := p.Error()
+	if len(errors) != 1 || errors[0] != "1:1 - try statement is incorrect, expected 'catch' or 'finally'" {
+		t.Fatalf("parser errors are not equal. got=%q", errors)
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	p := New(lexer.New(`throw "error: " + str(1);`))
+	program := p.ParserProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("len(program.Statements) is not 1. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not '*ast.ThrowStatement'. got='%T'", program.Statements[0])
+	}
+
+	if _, ok := stmt.Expression.(*ast.InfixExpression); !ok {
+		t.Fatalf("stmt.Expression is not '*ast.InfixExpression'. got='%T'", stmt.Expression)
+	}
+}
+
 func TestParsingForExpression(t *testing.T) {
 	tests := []struct {
 		input string
@@ -718,7 +814,7 @@ func testIntegerLiteral(t *testing.T, i ast.Expression, value int64) bool {
 }
 
 func checkParserErrors(t *testing.T, p *Parser) {
-	errors := p.errors
+	errors := p.Error()
 	if len(errors) == 0 {
 		return
 	}
@@ -729,3 +825,191 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestParserErrorPosition(t *testing.T) {
+	input := "var x:int = 5;\nvar y int;"
+
+	l := lexer.NewFile("test.april", input)
+	p := New(l)
+	p.ParserProgram()
+
+	if len(p.Error()) == 0 {
+		t.Fatalf("parser has not errors.")
+	}
+
+	expected := "test.april:2:7 - expected next token to be ':', got='int' instead"
+	if p.Error()[0] != expected {
+		t.Fatalf("p.Error()[0] is not equal %q. got=%q", expected, p.Error()[0])
+	}
+}
+
+//TestConcurrentParsing comprueba que varios lexers y parsers pueden trabajar a la vez en
+//el mismo proceso sin compartir estado, ejecutar con 'go test -race'.
+func TestConcurrentParsing(t *testing.T) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(lines int) {
+			defer wg.Done()
+
+			input := strings.Repeat("\n", lines) + "for (x := 1; x < 10; x++) {\n\ty := {a:int, b:string};\n}\nvar z int;"
+			l := lexer.NewFile("test.april", input)
+			p := New(l)
+			program := p.ParserProgram()
+
+			if len(program.Statements) == 0 {
+				t.Errorf("program.Statements is empty.")
+				return
+			}
+
+			if _, ok := program.Statements[0].(*ast.ForStatement); !ok {
+				t.Errorf("program.Statements[0] is not equal '*ast.ForStatement'. got='%T'", program.Statements[0])
+			}
+
+			expected := fmt.Sprintf("test.april:%d:7 - expected next token to be ':', got='int' instead", lines+4)
+			if len(p.Error()) == 0 || p.Error()[0] != expected {
+				t.Errorf("p.Error() is not equal %q. got=%q", expected, p.Error())
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+//TestParserErrorRecovery comprueba que cada error de sintaxis independiente se informa una
+//sola vez y que el parser continua con la siguiente sentencia.
+func TestParserErrorRecovery(t *testing.T) {
+	input := `var a:int = 1;
+var b int;
+c := (1 + 2;
+fn foo(x:int) int {
+	y := x * 2;
+	return y +;
+}
+var d:int = 4;
+if (d > 1 {
+	print(d);
+}
+for (i := 0; i < 10) {
+	print(i);
+}
+print(a);
+`
+
+	expected := []string{
+		"test.april:2:7 - expected next token to be ':', got='int' instead",
+		"test.april:3:12 - expected next token to be ')', got=';' instead",
+		"test.april:6:12 - no prefix parse function for ';' found, literal: ';'",
+		"test.april:9:11 - expected next token to be ')', got='{' instead",
+		"test.april:12:1 - count ';' incorrect.",
+	}
+
+	l := lexer.NewFile("test.april", input)
+	p := New(l)
+	p.ParserProgram()
+
+	errors := p.Error()
+	if len(errors) != len(expected) {
+		t.Fatalf("parser has %d errors, want=%d. got=%q", len(errors), len(expected), errors)
+	}
+
+	for i, msg := range expected {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] is not equal %q. got=%q", i, msg, errors[i])
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	input := "var a:int = 1;\nx := @;\nvar b int;"
+
+	p := New(lexer.NewFile("test.april", input))
+	p.ParserProgram()
+
+	expected := []struct {
+		code     string
+		expected string
+	}{
+		{diag.IllegalCharacter, "test.april:2:6 - illegal character '@'"},
+		{diag.UnexpectedToken, "test.april:3:7 - expected next token to be ':', got='int' instead"},
+	}
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("parser has %d diagnostics, want=%d. got=%q", len(diagnostics), len(expected), p.Error())
+	}
+
+	for i, tt := range expected {
+		if diagnostics[i].Code != tt.code {
+			t.Errorf("diagnostics[%d].Code is not '%s'. got='%s'", i, tt.code, diagnostics[i].Code)
+		}
+		if diagnostics[i].Error() != tt.expected {
+			t.Errorf("diagnostics[%d] is not %q. got=%q", i, tt.expected, diagnostics[i].Error())
+		}
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"fn f() {", true},
+		{"fn f() {\n\treturn 1;", true},
+		{"fn f(x:int", true},
+		{"if (x > 1) {\n} else", true},
+		{"for (i := 0; i < 3; i++) {", true},
+		{"try {\n} ", true},
+		{"var l:list = [1,", true},
+		{"print(1 +", true},
+		{"print(\"a { b", true},
+		{"var s:struct = { a:", true},
+		{"fn f() {\n}", false},
+		{"print(\"a { b\");", false},
+		{"print(1));", false},
+		{"var x int;", false},
+		{"x := @;", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParserProgram()
+
+		if p.Incomplete() != tt.expected {
+			t.Errorf("input %q: Incomplete() is not %t. errors=%q", tt.input, tt.expected, p.Error())
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// uno
+for (i := 0; i < 3; i++) { // dos
+	print(i, // tres
+		i);
+}
+var s:struct = { a:int, // cuatro
+	b:func };
+x := 1; // cinco`
+
+	p := New(lexer.New(input))
+	program := p.ParserProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+	expected := []string{"// uno", "// dos", "// tres", "// cuatro", "// cinco"}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("program.Comments does not contain %d comments. got=%d", len(expected), len(program.Comments))
+	}
+	for i, comment := range program.Comments {
+		if comment.Text != expected[i] {
+			t.Errorf("comments[%d] is not %q. got=%q", i, expected[i], comment.Text)
+		}
+	}
+	if pos := program.Comments[1].Pos(); pos.Line != 2 || pos.Column != 28 {
+		t.Errorf("comments[1] position is not 2:28. got=%d:%d", pos.Line, pos.Column)
+	}
+}
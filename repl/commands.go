@@ -0,0 +1,231 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+	"github.com/kenshindeveloper/april/token"
+	"github.com/kenshindeveloper/april/typecheck"
+)
+
+//Los comandos de la consola empiezan con ':', que no puede empezar una linea de codigo.
+
+//COMMANDS es la ayuda de los comandos que muestra :help.
+const COMMANDS = `comandos:
+
+	:env            muestra las variables y funciones de la sesion con sus tipos
+	:type expr      muestra el tipo de la expresion, el de la verificacion de tipos o el
+	                que tiene al ejecutarse si no se conoce antes
+	:ast expr       muestra el arbol del parser
+	:tokens expr    muestra los tokens del lexer
+	:load archivo   ejecuta un archivo en la sesion
+	:reset          empieza la sesion de nuevo, sin variables ni funciones
+	:time expr      ejecuta la expresion y muestra cuanto tardo
+	:help           muestra esta ayuda
+`
+
+//isCommand indica si 'line' es un comando de la consola.
+func isCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ":")
+}
+
+//command ejecuta el comando de la linea 'line'. Devuelve el codigo de salida del comando
+//y true si el codigo que ejecuto llamo a exit.
+func (s *session) command(line string) (int, bool) {
+	name, arg := strings.TrimSpace(line), ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i+1:])
+	}
+
+	switch name {
+	case ":help":
+		io.WriteString(s.w, COMMANDS)
+		return statusOK, false
+	case ":env":
+		s.showEnv()
+		return statusOK, false
+	case ":reset":
+		s.reset()
+		io.WriteString(s.w, "session reset\n")
+		return statusOK, false
+	case ":type", ":ast", ":tokens", ":load", ":time":
+	default:
+		fmt.Fprintf(s.errors, "unknown command '%s', use :help to see the commands.\n", name)
+		return statusInvalid, false
+	}
+
+	if arg == "" {
+		fmt.Fprintf(s.errors, "command '%s' expects an argument, use :help to see the commands.\n", name)
+		return statusInvalid, false
+	}
+	switch name {
+	case ":type":
+		return s.showType(arg)
+	case ":ast":
+		return s.showAst(arg)
+	case ":tokens":
+		return s.showTokens(arg)
+	case ":load":
+		return s.load(arg)
+	default:
+		return s.time(arg)
+	}
+}
+
+//showEnv muestra una linea por cada variable y funcion de la sesion: nombre, tipo y valor.
+func (s *session) showEnv() {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, name := range append(s.resolve.Names(), s.env.Names()...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(s.w, 0, 8, 2, ' ', 0)
+	for _, name := range names {
+		value, ok := s.lookup(name)
+		if !ok || value == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, object.GetType(value.Type()), describe(value))
+	}
+	tw.Flush()
+}
+
+//describe muestra un valor de :env, de las funciones solo los parametros y el tipo que
+//devuelven.
+func describe(value object.Object) string {
+	switch fn := value.(type) {
+	case *object.Function:
+		return signature(fn.Parameters, fn.Return)
+	case *object.FunctionClosure:
+		return signature(fn.Parameters, fn.Return)
+	}
+	return value.Inspect()
+}
+
+func signature(parameters []*ast.FunctionParameters, result *ast.Identifier) string {
+	params := []string{}
+	for _, p := range parameters {
+		params = append(params, p.String())
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if result != nil {
+		out += " " + result.Name
+	}
+	return out
+}
+
+//parse lee 'input' como el codigo de una entrada. Si tiene errores de sintaxis los
+//muestra y devuelve false.
+func (s *session) parse(input string) (*parser.Parser, *ast.Program, bool) {
+	s.sources[""] = input
+	p := parser.New(lexer.New(input))
+	program := p.ParserProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		PrintParseError(s.errors, diagnostics, s.sources)
+		return nil, nil, false
+	}
+	return p, program, true
+}
+
+//showType muestra el tipo de la expresion 'input'. Si la verificacion de tipos no lo
+//conoce ejecuta la expresion y muestra el tipo del valor.
+func (s *session) showType(input string) (int, bool) {
+	p, program, ok := s.parse(input)
+	if !ok {
+		return statusInvalid, false
+	}
+	var stmt *ast.ExpressionStatement
+	if len(program.Statements) == 1 {
+		stmt, _ = program.Statements[0].(*ast.ExpressionStatement)
+	}
+	if stmt == nil {
+		fmt.Fprintf(s.errors, "command ':type' expects an expression.\n")
+		return statusInvalid, false
+	}
+
+	if typ := s.checker.TypeOf(stmt.Expression); typ != typecheck.Any {
+		fmt.Fprintf(s.w, "%s\n", typ)
+		return statusOK, false
+	}
+	if !s.check(p, program) {
+		return statusInvalid, false
+	}
+	evaluated := s.run(program)
+	if _, ok := evaluated.(*object.Error); ok {
+		return s.result(evaluated)
+	}
+	if evaluated == nil {
+		evaluated = &object.Nil{}
+	}
+	fmt.Fprintf(s.w, "%s (runtime)\n", object.GetType(evaluated.Type()))
+	return statusOK, false
+}
+
+func (s *session) showAst(input string) (int, bool) {
+	_, program, ok := s.parse(input)
+	if !ok {
+		return statusInvalid, false
+	}
+	ast.Fprint(s.w, program)
+	return statusOK, false
+}
+
+//showTokens muestra un token por linea: posicion, tipo y texto.
+func (s *session) showTokens(input string) (int, bool) {
+	s.sources[""] = input
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.w, "%d:%d\t%s\t%q\n", tok.Pos.Line, tok.Pos.Column, tok.Type, tok.Literal)
+	}
+	if diagnostics := l.Errors(); len(diagnostics) > 0 {
+		PrintParseError(s.errors, diagnostics, s.sources)
+		return statusInvalid, false
+	}
+	return statusOK, false
+}
+
+//load ejecuta el archivo 'path' en la sesion: sus variables y funciones quedan declaradas.
+func (s *session) load(path string) (int, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(s.errors, "error to open file: '%s'\n", path)
+		return statusInvalid, false
+	}
+	s.sources[path] = string(data)
+
+	p := parser.New(lexer.NewFile(path, string(data)))
+	return s.execute(p, p.ParserProgram())
+}
+
+//time ejecuta 'input', muestra su resultado y cuanto tardo la ejecucion. Si termina con
+//un error solo muestra el error.
+func (s *session) time(input string) (int, bool) {
+	p, program, ok := s.parse(input)
+	if !ok || !s.check(p, program) {
+		return statusInvalid, false
+	}
+
+	start := time.Now()
+	evaluated := s.run(program)
+	elapsed := time.Since(start)
+	if _, ok := evaluated.(*object.Error); ok {
+		return s.result(evaluated)
+	}
+	status, exit := s.result(evaluated)
+	fmt.Fprintf(s.w, "time: %s\n", elapsed)
+	return status, exit
+}
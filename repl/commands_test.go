@@ -0,0 +1,99 @@
+package repl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+)
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lib := filepath.Join(dir, "lib.april")
+	if err := ioutil.WriteFile(lib, []byte("var loaded:int = 7;\nfn twice(x:int) int { return x * 2; }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setup := []string{
+		"var a:int = 1;",
+		"b := [1, 2];",
+		"fn add(x:int, y:int) int { return x + y; }",
+		"global g:string = \"hi\";",
+	}
+
+	tests := []struct {
+		lines    []string
+		expected []string //textos que debe mostrar la ultima linea, nil si no muestra nada
+	}{
+		{[]string{":env"}, []string{"a    int      1\n", "add  closure  fn(x:int, y:int) int\n", "b    list     [1, 2]\n", "g    string   'hi'\n"}},
+		{[]string{":type a + 1.5"}, []string{"double\n"}},
+		{[]string{":type add(1, 2)"}, []string{"int\n"}},
+		{[]string{":type b[0]"}, []string{"int (runtime)\n"}},
+		{[]string{":type"}, []string{"command ':type' expects an argument"}},
+		{[]string{":type var x:int = 1;"}, []string{"command ':type' expects an expression"}},
+		{[]string{":type c"}, []string{"identifier not found: c"}},
+		{[]string{":ast a + 1"}, []string{"InfixExpression 1:3\n", "Name: \"a\"\n", "Operator: \"+\"\n"}},
+		{[]string{":tokens a := \"x\""}, []string{"1:1\tIDENT\t\"a\"\n", "1:6\tSTRING\t\"x\"\n"}},
+		{[]string{":tokens a @"}, []string{"illegal character '@'"}},
+		{[]string{":load " + lib, "twice(loaded) + a;"}, []string{"15\n"}},
+		{[]string{":load " + filepath.Join(dir, "missing.april")}, []string{"error to open file"}},
+		{[]string{":time add(2, 3)"}, []string{"5\n", "time: "}},
+		{[]string{":time z := 1 / 0;", "z := 2;", "z;"}, []string{"2\n"}},
+		{[]string{":reset", ":env"}, nil},
+		{[]string{":reset", "a;"}, []string{"identifier not found: a"}},
+		{[]string{"x := 1 / 0;", "x;"}, []string{"identifier not found: x"}},
+		{[]string{"x := 1 / 0;", "x := 2;", "x;"}, []string{"2\n"}},
+		{[]string{"if (false) { y := 1; }", "y := 3;", "y;"}, []string{"3\n"}},
+		{[]string{":help"}, []string{":env", ":reset"}},
+		{[]string{":nope"}, []string{"unknown command ':nope'"}},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		s := newSession(&out, &out, object.NewIO(&out, &out, strings.NewReader("")))
+		for _, line := range append(setup, tt.lines...) {
+			out.Reset()
+			if isCommand(line) {
+				s.command(line)
+				continue
+			}
+			s.sources[""] = line
+			p := parser.New(lexer.New(line))
+			s.execute(p, p.ParserProgram())
+		}
+
+		for _, expected := range tt.expected {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("lines %q: output does not contain %q. got=%q", tt.lines, expected, out.String())
+			}
+		}
+		if tt.expected == nil && out.Len() != 0 {
+			t.Errorf("lines %q: output is not empty. got=%q", tt.lines, out.String())
+		}
+	}
+}
+
+func TestTimeError(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, &out, object.NewIO(&out, &out, strings.NewReader("")))
+	status, _ := s.command(":time 1 / 0")
+	if status != statusError {
+		t.Errorf("status is not %d. got=%d", statusError, status)
+	}
+	if !strings.Contains(out.String(), "division by zero") {
+		t.Errorf("output does not contain the error. got=%q", out.String())
+	}
+	if strings.Contains(out.String(), "time: ") {
+		t.Errorf("output contains the time of a failed input. got=%q", out.String())
+	}
+}
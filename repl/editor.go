@@ -0,0 +1,478 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kenshindeveloper/april/object"
+)
+
+//HISTORY es el archivo del directorio del usuario donde la consola guarda el historial.
+const HISTORY = ".april_history"
+
+//maxHistory es la cantidad de lineas que se guardan en el historial.
+const maxHistory = 1000
+
+//errInterrupt indica que se presiono Ctrl-C: se descarta la linea que se estaba escribiendo.
+var errInterrupt = errors.New("interrupt")
+
+//Teclas que reconoce el editor en modo raw.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlJ     = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+//editor lee las lineas de la consola. Si la entrada es una terminal la pone en modo raw
+//mientras se escribe la linea, asi se puede mover el cursor, recorrer el historial con
+//las flechas, buscar en el historial con Ctrl-R y completar nombres con Tab. Si no es una
+//terminal, o el sistema no tiene modo raw, lee las lineas sin editarlas.
+type editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	terminal bool                   //la entrada es una terminal, aunque no se pueda editar la linea
+	raw      func() (func(), error) //pone la terminal en modo raw, nil si no es una terminal
+	complete func() []string        //nombres que se pueden completar con Tab
+	history  []string
+	path     string //archivo del historial, "" no lo guarda
+}
+
+func newEditor(r io.Reader, w io.Writer) *editor {
+	e := &editor{in: object.NewReader(r), out: w}
+	if file, ok := r.(*os.File); ok && isTerminal(file) {
+		e.terminal = true
+		e.raw = func() (func(), error) {
+			return rawMode(file.Fd())
+		}
+	}
+	return e
+}
+
+//historyPath devuelve el archivo del historial en el directorio del usuario, o "" si no
+//se conoce el directorio.
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY)
+}
+
+//loadHistory lee el historial de 'path', donde se agregan despues las lineas nuevas. Si el
+//archivo tiene mas de maxHistory lineas lo reescribe con las ultimas.
+func (e *editor) loadHistory(path string) {
+	e.path = path
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+		ioutil.WriteFile(path, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+}
+
+//addHistory agrega 'line' al historial si no esta vacia ni repite la anterior.
+func (e *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+
+	if e.path == "" {
+		return
+	}
+	file, err := os.OpenFile(e.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+//Prompt muestra 'prompt' y devuelve la linea que se escribe, sin el salto de linea.
+//Devuelve io.EOF al terminar la entrada y errInterrupt con Ctrl-C.
+func (e *editor) Prompt(prompt string) (string, error) {
+	if e.raw == nil {
+		return e.readLine(prompt)
+	}
+	restore, err := e.raw()
+	if err != nil {
+		return e.readLine(prompt)
+	}
+	defer restore()
+
+	s := &state{editor: e, prompt: prompt, index: len(e.history)}
+	s.refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, keyCtrlJ:
+			return s.accept(), nil
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupt
+		case keyCtrlD:
+			if len(s.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.delete()
+		case keyBackspace, keyCtrlH:
+			s.backspace()
+		case keyTab:
+			s.completion()
+		case keyCtrlA:
+			s.move(-len(s.buf))
+		case keyCtrlE:
+			s.move(len(s.buf))
+		case keyCtrlB:
+			s.move(-1)
+		case keyCtrlF:
+			s.move(1)
+		case keyCtrlK:
+			s.buf = s.buf[:s.pos]
+			s.refresh()
+		case keyCtrlU:
+			s.buf = s.buf[s.pos:]
+			s.pos = 0
+			s.refresh()
+		case keyCtrlW:
+			s.deleteWord()
+		case keyCtrlP:
+			s.previous()
+		case keyCtrlN:
+			s.next()
+		case keyCtrlL:
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+			s.refresh()
+		case keyCtrlR:
+			if s.search() {
+				return s.accept(), nil
+			}
+		case keyEscape:
+			s.escape()
+		default:
+			if unicode.IsPrint(r) {
+				s.insert([]rune{r})
+			}
+		}
+	}
+}
+
+//readLine lee una linea sin editarla, cuando la entrada no es una terminal.
+func (e *editor) readLine(prompt string) (string, error) {
+	io.WriteString(e.out, prompt)
+	line, err := e.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//state es la linea que se esta editando: el texto, la posicion del cursor y la linea del
+//historial que se muestra.
+type state struct {
+	*editor
+	prompt string
+	buf    []rune
+	pos    int
+	index  int    //linea del historial, len(history) es la linea nueva
+	saved  string //la linea nueva mientras se recorre el historial
+}
+
+//refresh vuelve a escribir la linea y deja el cursor en su posicion.
+func (s *state) refresh() {
+	fmt.Fprintf(s.out, "\r%s%s\x1b[K", s.prompt, string(s.buf))
+	if back := len(s.buf) - s.pos; back > 0 {
+		fmt.Fprintf(s.out, "\x1b[%dD", back)
+	}
+}
+
+//accept termina la linea y la agrega al historial.
+func (s *state) accept() string {
+	s.pos = len(s.buf)
+	s.refresh()
+	io.WriteString(s.out, "\r\n")
+	line := string(s.buf)
+	s.addHistory(line)
+	return line
+}
+
+//set reemplaza la linea por 'line' con el cursor al final.
+func (s *state) set(line string) {
+	s.buf = []rune(line)
+	s.pos = len(s.buf)
+	s.refresh()
+}
+
+func (s *state) insert(text []rune) {
+	buf := make([]rune, 0, len(s.buf)+len(text))
+	buf = append(buf, s.buf[:s.pos]...)
+	buf = append(buf, text...)
+	s.buf = append(buf, s.buf[s.pos:]...)
+	s.pos += len(text)
+	s.refresh()
+}
+
+func (s *state) move(n int) {
+	s.pos += n
+	if s.pos < 0 {
+		s.pos = 0
+	}
+	if s.pos > len(s.buf) {
+		s.pos = len(s.buf)
+	}
+	s.refresh()
+}
+
+//delete borra el caracter que esta debajo del cursor.
+func (s *state) delete() {
+	if s.pos < len(s.buf) {
+		s.buf = append(s.buf[:s.pos], s.buf[s.pos+1:]...)
+		s.refresh()
+	}
+}
+
+//backspace borra el caracter que esta antes del cursor.
+func (s *state) backspace() {
+	if s.pos > 0 {
+		s.pos--
+		s.delete()
+	}
+}
+
+//deleteWord borra la palabra que esta antes del cursor y los espacios que la siguen.
+func (s *state) deleteWord() {
+	start := s.pos
+	for start > 0 && unicode.IsSpace(s.buf[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(s.buf[start-1]) {
+		start--
+	}
+	s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+	s.pos = start
+	s.refresh()
+}
+
+//previous muestra la linea anterior del historial.
+func (s *state) previous() {
+	if s.index == 0 {
+		return
+	}
+	if s.index == len(s.history) {
+		s.saved = string(s.buf)
+	}
+	s.index--
+	s.set(s.history[s.index])
+}
+
+//next muestra la linea siguiente del historial, o la linea nueva despues de la ultima.
+func (s *state) next() {
+	if s.index == len(s.history) {
+		return
+	}
+	s.index++
+	if s.index == len(s.history) {
+		s.set(s.saved)
+		return
+	}
+	s.set(s.history[s.index])
+}
+
+//escape lee las secuencias de las flechas, Inicio, Fin y Suprimir.
+func (s *state) escape() {
+	r, _, err := s.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+	r, _, err = s.in.ReadRune()
+	if err != nil {
+		return
+	}
+
+	if r >= '0' && r <= '9' {
+		code := string(r)
+		for {
+			next, _, err := s.in.ReadRune()
+			if err != nil || next == '~' {
+				break
+			}
+			code += string(next)
+		}
+		switch code {
+		case "1", "7":
+			s.move(-len(s.buf))
+		case "4", "8":
+			s.move(len(s.buf))
+		case "3":
+			s.delete()
+		}
+		return
+	}
+
+	switch r {
+	case 'A':
+		s.previous()
+	case 'B':
+		s.next()
+	case 'C':
+		s.move(1)
+	case 'D':
+		s.move(-1)
+	case 'H':
+		s.move(-len(s.buf))
+	case 'F':
+		s.move(len(s.buf))
+	}
+}
+
+//search busca hacia atras en el historial la linea que contiene lo que se escribe, y
+//Ctrl-R busca la siguiente. Enter acepta la linea encontrada y devuelve true, Ctrl-G la
+//descarta y cualquier otra tecla la deja en la linea para seguir editandola.
+func (s *state) search() bool {
+	query := ""
+	match := len(s.history)
+	found := string(s.buf)
+	failed := false
+
+	//find busca desde la linea 'from' hacia atras.
+	find := func(from int) {
+		if from >= len(s.history) {
+			from = len(s.history) - 1
+		}
+		for i := from; i >= 0; i-- {
+			if strings.Contains(s.history[i], query) {
+				match, found, failed = i, s.history[i], false
+				return
+			}
+		}
+		failed = true
+	}
+
+	for {
+		title := "reverse-i-search"
+		if failed {
+			title = "failed " + title
+		}
+		fmt.Fprintf(s.out, "\r(%s)'%s': %s\x1b[K", title, query, found)
+
+		r, _, err := s.in.ReadRune()
+		if err != nil {
+			s.set(found)
+			return false
+		}
+		switch {
+		case r == keyCtrlR:
+			find(match - 1)
+		case r == keyBackspace || r == keyCtrlH:
+			if query != "" {
+				query = string([]rune(query)[:len([]rune(query))-1])
+				find(len(s.history) - 1)
+			}
+		case r == keyCtrlG || r == keyCtrlC:
+			s.refresh()
+			return false
+		case r == keyEnter || r == keyCtrlJ:
+			s.buf = []rune(found)
+			return true
+		case unicode.IsPrint(r):
+			query += string(r)
+			find(match)
+		default:
+			s.in.UnreadRune()
+			s.set(found)
+			return false
+		}
+	}
+}
+
+//completion completa la palabra que esta antes del cursor con los nombres que empiezan
+//igual. Si hay varios completa la parte comun y, si no queda nada en comun, los muestra.
+func (s *state) completion() {
+	if s.complete == nil {
+		return
+	}
+	start := s.pos
+	for start > 0 && isIdentifier(s.buf[start-1]) {
+		start--
+	}
+	prefix := string(s.buf[start:s.pos])
+
+	candidates := []string{}
+	seen := map[string]bool{}
+	for _, name := range s.complete() {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		io.WriteString(s.out, "\a")
+		return
+	}
+	sort.Strings(candidates)
+
+	common := candidates[0]
+	for _, name := range candidates[1:] {
+		for !strings.HasPrefix(name, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(prefix) {
+		s.insert([]rune(common[len(prefix):]))
+		return
+	}
+	if len(candidates) > 1 {
+		fmt.Fprintf(s.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		s.refresh()
+	}
+}
+
+func isIdentifier(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,156 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//terminal crea un editor que lee 'input' como si fuera una terminal en modo raw.
+func terminal(input string, history ...string) (*editor, *bytes.Buffer) {
+	var out bytes.Buffer
+	e := newEditor(strings.NewReader(input), &out)
+	e.raw = func() (func(), error) {
+		return func() {}, nil
+	}
+	e.history = history
+	e.complete = func() []string {
+		return []string{"print", "println", "printf", "var", "value", "len"}
+	}
+	return e, &out
+}
+
+func TestEditorKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		history  []string
+		expected string
+	}{
+		{"abc\r", nil, "abc"},
+		{"abc\n", nil, "abc"},
+		{"ab\x7fc\r", nil, "ac"},
+		{"abc\x1b[D\x1b[DX\r", nil, "aXbc"},
+		{"abc\x01X\x05Y\r", nil, "XabcY"},
+		{"abc\x1b[H\x1b[3~\r", nil, "bc"},
+		{"abc\x02\x02\x0b\r", nil, "a"},
+		{"abc\x02\x15\r", nil, "c"},
+		{"var x\x17y\r", nil, "var y"},
+		{"abc\x02\x04\r", nil, "ab"},
+		{"hola ñu\x7f\x7fyo\r", nil, "hola yo"},
+		{"\x1b[A\r", []string{"uno", "dos"}, "dos"},
+		{"\x1b[A\x1b[A\r", []string{"uno", "dos"}, "uno"},
+		{"\x1b[A\x1b[A\x1b[A\r", []string{"uno", "dos"}, "uno"},
+		{"tres\x1b[A\x1b[B\r", []string{"uno", "dos"}, "tres"},
+		{"\x10\x10\x0e\r", []string{"uno", "dos"}, "dos"},
+		{"\x12un\r", []string{"uno", "dos", "una"}, "una"},
+		{"\x12un\x12\r", []string{"uno", "dos", "una"}, "uno"},
+		{"\x12o\x12\x7fs\r", []string{"uno", "dos"}, "dos"},
+		{"x\x12zz\x07\r", []string{"uno"}, "x"},
+		{"\x12do\x1b[D!\r", []string{"uno", "dos"}, "do!s"},
+		{"\x12do\x01!\r", []string{"uno", "dos"}, "!dos"},
+		{"le\t(1)\r", nil, "len(1)"},
+		{"pri\t\r", nil, "print"},
+		{"x := va\t\r", nil, "x := va"},
+		{"x := val\t\r", nil, "x := value"},
+		{"zz\t\r", nil, "zz"},
+	}
+
+	for _, tt := range tests {
+		e, _ := terminal(tt.input, tt.history...)
+		line, err := e.Prompt(PROMPT)
+		if err != nil {
+			t.Fatalf("input %q: error %v", tt.input, err)
+		}
+		if line != tt.expected {
+			t.Errorf("input %q: line is not %q. got=%q", tt.input, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorCompletionList(t *testing.T) {
+	e, out := terminal("print\t\r")
+	line, err := e.Prompt(PROMPT)
+	if err != nil || line != "print" {
+		t.Fatalf("line is not 'print'. got=%q, %v", line, err)
+	}
+	if !strings.Contains(out.String(), "print  printf  println") {
+		t.Fatalf("output does not contain the candidates. got=%q", out.String())
+	}
+}
+
+func TestEditorEnd(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"\x04", io.EOF},
+		{"abc\x03", errInterrupt},
+		{"abc", io.EOF},
+	}
+
+	for _, tt := range tests {
+		e, _ := terminal(tt.input)
+		if _, err := e.Prompt(PROMPT); err != tt.expected {
+			t.Errorf("input %q: error is not %v. got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestEditorLines(t *testing.T) {
+	var out bytes.Buffer
+	e := newEditor(strings.NewReader("uno\r\ndos\ntres"), &out)
+	for _, expected := range []string{"uno", "dos", "tres"} {
+		line, err := e.Prompt(PROMPT)
+		if err != nil || line != expected {
+			t.Fatalf("line is not %q. got=%q, %v", expected, line, err)
+		}
+	}
+	if _, err := e.Prompt(PROMPT); err != io.EOF {
+		t.Fatalf("error is not io.EOF. got=%v", err)
+	}
+	if len(e.history) != 0 {
+		t.Fatalf("lines without terminal are in the history. got=%q", e.history)
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, HISTORY)
+
+	lines := []string{}
+	for i := 0; i < maxHistory+5; i++ {
+		lines = append(lines, "old")
+	}
+	lines = append(lines, "", "last")
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := terminal("\x1b[A\r  \rnew\rnew\r")
+	e.loadHistory(path)
+	if len(e.history) != maxHistory || e.history[len(e.history)-1] != "last" {
+		t.Fatalf("history has %d lines, last=%q", len(e.history), e.history[len(e.history)-1])
+	}
+	for _, expected := range []string{"last", "  ", "new", "new"} {
+		if line, err := e.Prompt(PROMPT); err != nil || line != expected {
+			t.Fatalf("line is not %q. got=%q, %v", expected, line, err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(saved) != maxHistory+1 || saved[len(saved)-1] != "new" || saved[len(saved)-2] != "last" {
+		t.Fatalf("history file has %d lines, ends with %q", len(saved), saved[len(saved)-2:])
+	}
+}
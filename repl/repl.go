@@ -1,20 +1,17 @@
 package repl
 
 import (
-	"bufio"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"os/exec"
 	"os/user"
-	"regexp"
 
-	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
 	"github.com/kenshindeveloper/april/lexer"
-	"github.com/kenshindeveloper/april/libs"
 	"github.com/kenshindeveloper/april/object"
 	"github.com/kenshindeveloper/april/parser"
+	"github.com/kenshindeveloper/april/token"
 )
 
 const PROMPT = ">> "
@@ -28,103 +25,228 @@ const APRIL_ERROR = `
  __\/_C(")(")__\/___\//____
 `
 
-//Start es una funcion que recibe dos parametros, de escritura y lectura. Ejecuta el programa en modo interactivo
-func Start(w io.Writer, r io.Reader, version string) {
-	info, err := user.Current()
-	if err != nil {
-		log.Fatal("problema al leer la informacion del usuario.")
+//CLEAR limpia la pantalla de una terminal y lleva el cursor al inicio.
+const CLEAR = "\x1b[H\x1b[2J"
+
+//Codigos de salida de Start, los mismos que los de un archivo. Una entrada que llama a
+//exit(n) sale con n.
+const (
+	statusOK      = 0 //la consola termino sin errores
+	statusError   = 1 //una entrada termino con un error de ejecucion
+	statusInvalid = 2 //una entrada tiene errores antes de ejecutarse
+)
+
+//Options son las opciones con las que Start ejecuta la consola.
+type Options struct {
+	Version string
+	Quiet   bool      //no limpia la pantalla ni muestra el saludo
+	Stderr  io.Writer //errores cuando la entrada no es una terminal, nil es os.Stderr
+}
+
+//Start ejecuta la consola: lee el codigo de 'r' y muestra los resultados en 'w'. Si 'r'
+//es una terminal muestra el saludo y el prompt y sigue despues de un error. Si no, por
+//ejemplo cuando la entrada viene de un pipe, solo muestra los resultados, los errores
+//van a options.Stderr y termina con el primero. Un if sin else se ejecuta al leer la
+//linea siguiente, asi el else puede empezar en otra linea. Devuelve el codigo de salida.
+func Start(w io.Writer, r io.Reader, options Options) int {
+	return start(w, newEditor(r, w), options)
+}
+
+//start ejecuta la consola con las lineas que lee 'editor'. La consola es interactiva si
+//la entrada es una terminal, aunque el sistema no permita editar la linea.
+func start(w io.Writer, editor *editor, options Options) int {
+	stderr := options.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	interactive := editor.terminal
+
+	errors := stderr
+	if interactive {
+		errors = w
+		if !options.Quiet {
+			greet(w, options.Version)
+		}
+		editor.loadHistory(historyPath())
 	}
-	cleanConsole()
-	fmt.Printf("Hello %s! This is the April programming language version %s!\nDeveloped for Pandicorn & Kenshin Urashima.\n\n", info.Name, version)
-	env := object.NewEnvironment()
-	stackBlock := libs.NewStack()
-	inputBlock := ""
+	s := newSession(w, errors, object.NewIO(w, stderr, editor.in))
+	editor.complete = s.names
+	input := ""
+	held := false //'input' termina con un if sin else que espera la linea siguiente
 
 	for {
-		if stackBlock.Len() == 0 {
-			fmt.Printf("%s", PROMPT)
-		} else {
-			fmt.Printf("%s", PROMPTBLOCK)
+		prompt := PROMPT
+		if input != "" {
+			prompt = PROMPTBLOCK
 		}
-		reader := bufio.NewScanner(r)
-		if !reader.Scan() {
-			return
+		if !interactive {
+			prompt = ""
+		}
+		line, err := editor.Prompt(prompt)
+		if err == errInterrupt {
+			input, held = "", false
+			continue
+		}
+		if err != nil {
+			return s.end(input, interactive)
 		}
 
-		input := reader.Text()
-
-		for _, char := range input {
-			if char == '{' {
-				stackBlock.Push("{")
-			} else if char == '}' {
-				stackBlock.Pop()
+		//si la linea no empieza con el else del if que se espera, el if se ejecuta antes
+		//que la linea.
+		if held && !startsElse(line) {
+			status, exit := s.executeInput(input)
+			input, held = "", false
+			if exit || (!interactive && status != statusOK) {
+				return status
 			}
 		}
 
-		// if ok, _ := regexp.MatchString("^(.*)?\\{( )*$", input); ok {
-		// 	stackBlock.Push("{")
-		// } else if ok, _ := regexp.MatchString("^(.*)?\\}( |.)*$", input); ok {
-		// 	stackBlock.Pop()
-		// }
-
-		switch stackBlock.Len() {
-		case 0:
-			if inputBlock != "" {
-				input = inputBlock + input
-				inputBlock = ""
+		status, exit := statusOK, false
+		if input == "" && isCommand(line) {
+			status, exit = s.command(line)
+		} else {
+			if input != "" {
+				input += "\n"
 			}
+			input += line
 
-			l := lexer.New(input)
-			p := parser.New(l)
+			//mientras el parser encuentre el final antes de cerrar un bloque, una lista o
+			//un string, la entrada sigue en la linea siguiente. Un if sin else tambien
+			//espera la linea siguiente, porque el else puede estar en ella.
+			p := parser.New(lexer.New(input))
 			program := p.ParserProgram()
-			if len(p.Error()) > 0 {
-				PrintParseError(w, p.Error())
+			held = !p.Incomplete() && awaitsElse(p, program)
+			if p.Incomplete() || held {
 				continue
 			}
+			s.sources[""] = input
+			input = ""
+			status, exit = s.execute(p, program)
+		}
 
-			evaluated := evaluator.Eval(program, env)
-			if evaluated != nil {
-				switch evaluated.(type) {
-				case *object.Nil:
-					io.WriteString(w, "")
-				default:
-					io.WriteString(w, evaluated.Inspect())
-					io.WriteString(w, "\n")
-				}
-			}
-		default:
-			if evalTextInput(input) {
-				input += ";"
-			}
+		if exit || (!interactive && status != statusOK) {
+			return status
+		}
+	}
+}
 
-			inputBlock += input
+//end termina la consola al final de la entrada. Un if que esperaba el else se ejecuta.
+//Sin terminal, el codigo que quedo sin terminar es un error.
+func (s *session) end(input string, interactive bool) int {
+	if input == "" {
+		return statusOK
+	}
+	s.sources[""] = input
+	p := parser.New(lexer.New(input))
+	program := p.ParserProgram()
+	if !p.Incomplete() {
+		status, exit := s.execute(p, program)
+		if interactive && !exit {
+			return statusOK
 		}
+		return status
+	}
+	if interactive {
+		return statusOK
 	}
+	PrintParseError(s.errors, p.Diagnostics(), s.sources)
+	return statusInvalid
+}
+
+//executeInput ejecuta el codigo completo 'input' en la sesion.
+func (s *session) executeInput(input string) (int, bool) {
+	s.sources[""] = input
+	p := parser.New(lexer.New(input))
+	return s.execute(p, p.ParserProgram())
 }
 
-func evalTextInput(text string) bool {
-	if ok, _ := regexp.MatchString("^(.*)?(\\{|\\}|;)( )*$", text); ok {
+//awaitsElse indica si 'program', sin errores, termina con un if sin else: la consola lo
+//ejecuta con la linea siguiente, que puede empezar con el else.
+func awaitsElse(p *parser.Parser, program *ast.Program) bool {
+	if len(p.Diagnostics()) > 0 || len(program.Statements) == 0 {
+		return false
+	}
+	stmt, ok := program.Statements[len(program.Statements)-1].(*ast.ExpressionStatement)
+	if !ok {
 		return false
 	}
-	return true
+	ie, ok := stmt.Expression.(*ast.IfExpression)
+	return ok && ie.Alternative == nil
+}
+
+//startsElse indica si 'line' empieza con else.
+func startsElse(line string) bool {
+	return lexer.New(line).NextToken().Type == token.ELSE
+}
+
+//greet limpia la pantalla, si 'w' es una terminal, y muestra el saludo.
+func greet(w io.Writer, version string) {
+	if file, ok := w.(*os.File); ok && isTerminal(file) {
+		io.WriteString(w, CLEAR)
+	}
+
+	name := ""
+	if info, err := user.Current(); err == nil {
+		name = info.Name
+		if name == "" {
+			name = info.Username
+		}
+	}
+	if name != "" {
+		name = " " + name
+	}
+	fmt.Fprintf(w, "Hello%s! This is the April programming language version %s!\nDeveloped for Pandicorn & Kenshin Urashima.\n\n", name, version)
 }
 
-func PrintParseError(w io.Writer, errors []string) {
+//PrintParseError muestra los errores de sintaxis con un extracto del codigo de 'sources'.
+func PrintParseError(w io.Writer, diagnostics []*diag.Diagnostic, sources diag.Sources) {
+	printErrors(w, "parser errors", diagnostics, sources)
+}
+
+//PrintTypeError muestra los errores que encontro la verificacion de tipos.
+func PrintTypeError(w io.Writer, diagnostics []*diag.Diagnostic, sources diag.Sources) {
+	printErrors(w, "type errors", diagnostics, sources)
+}
+
+//PrintResolveError muestra los usos de variables antes de declararlas y las declaraciones
+//repetidas que encontro el paquete resolver.
+func PrintResolveError(w io.Writer, diagnostics []*diag.Diagnostic, sources diag.Sources) {
+	printErrors(w, "resolve errors", diagnostics, sources)
+}
+
+//PrintCompileError muestra los errores que encontro el compilador a bytecode.
+func PrintCompileError(w io.Writer, diagnostics []*diag.Diagnostic, sources diag.Sources) {
+	printErrors(w, "compile errors", diagnostics, sources)
+}
+
+func printErrors(w io.Writer, title string, diagnostics []*diag.Diagnostic, sources diag.Sources) {
 	io.WriteString(w, APRIL_ERROR+"\n\n")
-	if len(errors) > 1 {
+	if len(diagnostics) > 1 {
 		io.WriteString(w, "shit! there are error.\n")
 	} else {
 		io.WriteString(w, "shit! there is a error.\n")
 	}
-	io.WriteString(w, "parser errors:\n")
+	io.WriteString(w, title+":\n\n")
+
+	for _, d := range diagnostics {
+		diag.Render(w, d, sources)
+		io.WriteString(w, "\n")
+	}
+}
 
-	for _, err := range errors {
-		io.WriteString(w, "\t- "+err+"\n")
+//ExitStatus devuelve el codigo con el que termina el programa si 'err' lo produjo exit.
+func ExitStatus(err *object.Error) (int, bool) {
+	if err.Code != diag.Exit {
+		return 0, false
+	}
+	status, _ := err.Value.(*object.Integer)
+	if status == nil {
+		return 0, true
 	}
+	return int(status.Value), true
 }
 
-func cleanConsole() {
-	command := exec.Command("cmd", "/c", "cls")
-	command.Stdout = os.Stdout
-	command.Run()
+//PrintRuntimeError muestra el error 'err' producido al evaluar el programa.
+func PrintRuntimeError(w io.Writer, err *object.Error, sources diag.Sources) {
+	diag.Render(w, err.Diagnostic(), sources)
 }
@@ -0,0 +1,72 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStartPipe(t *testing.T) {
+	tests := []struct {
+		input  string
+		status int
+		stdout string
+		stderr string //texto que debe estar en los errores
+	}{
+		{"var a:int = 1;\na + 1;\n", statusOK, "2\n", ""},
+		{"fn f(x:int) int {\n\treturn x * 2;\n}\nf(21);", statusOK, "42\n", ""},
+		{"var l:list = [1,\n2];\nvar s:string = \"{\";\nlen(l) + len(s);\n", statusOK, "3\n", ""},
+		{"print(1);\n:type 1.5\n", statusOK, "1\ndouble\n", ""},
+		{"print(1);\nprint(2;\nprint(3);\n", statusInvalid, "1\n", "expected next token to be ')'"},
+		{"print(1);\nx;\nprint(3);\n", statusInvalid, "1\n", "identifier not found: x"},
+		{"print(1);\n1 / 0;\nprint(3);\n", statusError, "1\n", "division by zero"},
+		{"print(1);\nexit(4);\nprint(3);\n", 4, "1\n", ""},
+		{"fn f() {\n", statusInvalid, "", "unexpected end of input"},
+		{":nope\n", statusInvalid, "", "unknown command ':nope'"},
+		{"var a:int = 1;\nif (a == 2) {\n\tprint(1);\n}\nelse {\n\tprint(2);\n}\nprint(3);\n", statusOK, "2\n3\n", ""},
+		{"if (true) { print(1); }\n  else { print(2); }\n", statusOK, "1\n", ""},
+		{"if (true) { print(1); }\nprint(3);\n", statusOK, "1\n3\n", ""},
+		{"if (true) { print(1); }\n:type 2\n", statusOK, "1\nint\n", ""},
+		{"if (true) { print(1); }\n", statusOK, "1\n", ""},
+		{"if (true) { 1 / 0; }\nprint(3);\n", statusError, "", "division by zero"},
+		{"", statusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := Start(&stdout, strings.NewReader(tt.input), Options{Version: "1.2.3", Stderr: &stderr})
+		if status != tt.status {
+			t.Errorf("input %q: status is not %d. got=%d, stderr=%q", tt.input, tt.status, status, stderr.String())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("input %q: stdout is not %q. got=%q", tt.input, tt.stdout, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("input %q: stderr does not contain %q. got=%q", tt.input, tt.stderr, stderr.String())
+		}
+	}
+}
+
+//Una terminal sin modo raw, como en los sistemas que no lo tienen, lee las lineas sin
+//editarlas pero sigue mostrando el prompt y continua despues de un error.
+func TestStartTerminalWithoutRawMode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var stdout, stderr bytes.Buffer
+	e := newEditor(strings.NewReader("1 / 0;\nfn f() int {\nreturn 2;\n}\nf();\n"), &stdout)
+	e.terminal = true
+
+	status := start(&stdout, e, Options{Quiet: true, Stderr: &stderr})
+	if status != statusOK {
+		t.Fatalf("status is not %d. got=%d", statusOK, status)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "division by zero") {
+		t.Errorf("stdout does not contain the error. got=%q", out)
+	}
+	if !strings.HasSuffix(out, PROMPT+PROMPTBLOCK+PROMPTBLOCK+PROMPT+"2\n"+PROMPT) {
+		t.Errorf("stdout does not end with the prompts and the result. got=%q", out)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr is not empty. got=%q", stderr.String())
+	}
+}
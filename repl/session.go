@@ -0,0 +1,120 @@
+package repl
+
+import (
+	"io"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/module"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+	"github.com/kenshindeveloper/april/resolver"
+	"github.com/kenshindeveloper/april/token"
+	"github.com/kenshindeveloper/april/typecheck"
+)
+
+//session es el estado de la consola: el entorno donde se ejecuta cada entrada, las
+//declaraciones que conservan la verificacion de tipos y el resolver, y el codigo de las
+//entradas para mostrar los errores. Los resultados se muestran en 'w' y los errores en
+//'errors'.
+type session struct {
+	w       io.Writer
+	errors  io.Writer
+	streams *object.IO
+	env     *object.Environment
+	checker *typecheck.Checker
+	resolve *resolver.Resolver
+	sources diag.Sources
+}
+
+func newSession(w, errors io.Writer, streams *object.IO) *session {
+	s := &session{w: w, errors: errors, streams: streams}
+	s.reset()
+	return s
+}
+
+//reset empieza la sesion de nuevo, sin las variables ni las funciones declaradas.
+func (s *session) reset() {
+	s.sources = diag.Sources{}
+	s.env = object.NewEnvironment()
+	s.env.Runtime().IO = s.streams
+	s.env.Runtime().Importer = module.New(s.sources)
+	s.checker = typecheck.New()
+	s.resolve = resolver.New()
+}
+
+//check verifica 'program' con lo declarado en las entradas anteriores. Si tiene errores
+//de sintaxis, de tipos o de ambito los muestra y devuelve false.
+func (s *session) check(p *parser.Parser, program *ast.Program) bool {
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		PrintParseError(s.errors, diagnostics, s.sources)
+		return false
+	}
+
+	if diagnostics := s.checker.Check(program); len(diagnostics) > 0 {
+		PrintTypeError(s.errors, diagnostics, s.sources)
+		return false
+	}
+
+	if diagnostics := s.resolve.Resolve(program); len(diagnostics) > 0 {
+		PrintResolveError(s.errors, diagnostics, s.sources)
+		return false
+	}
+	return true
+}
+
+//execute verifica y ejecuta 'program' en el entorno de la sesion y muestra su resultado.
+//Devuelve el codigo de salida de la entrada y true si el programa llamo a exit.
+func (s *session) execute(p *parser.Parser, program *ast.Program) (int, bool) {
+	if !s.check(p, program) {
+		return statusInvalid, false
+	}
+	return s.result(s.run(program))
+}
+
+//run ejecuta 'program', ya verificado, en el entorno de la sesion. El resolver solo
+//conserva las declaraciones que se llegaron a ejecutar.
+func (s *session) run(program *ast.Program) object.Object {
+	evaluated := evaluator.Eval(program, s.env)
+	s.resolve.Commit(func(slot int) bool {
+		_, ok := s.env.GetAt(0, slot)
+		return ok
+	})
+	return evaluated
+}
+
+//result muestra el resultado de una entrada: el valor o el error de ejecucion. Devuelve
+//el codigo de salida y true si el error lo produjo exit.
+func (s *session) result(evaluated object.Object) (int, bool) {
+	switch evaluated := evaluated.(type) {
+	case nil, *object.Nil:
+		return statusOK, false
+	case *object.Error:
+		if status, ok := ExitStatus(evaluated); ok {
+			return status, true
+		}
+		PrintRuntimeError(s.errors, evaluated, s.sources)
+		return statusError, false
+	default:
+		io.WriteString(s.w, evaluated.Inspect())
+		io.WriteString(s.w, "\n")
+		return statusOK, false
+	}
+}
+
+//names devuelve los nombres que se completan con Tab: las palabras clave, las funciones
+//del lenguaje y lo declarado en la sesion.
+func (s *session) names() []string {
+	names := append(token.Keywords(), evaluator.BuiltinNames()...)
+	names = append(names, s.resolve.Names()...)
+	return append(names, s.env.Names()...)
+}
+
+//lookup devuelve el valor de la variable o funcion 'name' de la sesion.
+func (s *session) lookup(name string) (object.Object, bool) {
+	if slot, ok := s.resolve.Slot(name); ok {
+		return s.env.GetAt(0, slot)
+	}
+	return s.env.Get(name)
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package repl
+
+import (
+	"errors"
+	"os"
+)
+
+//En los demas sistemas la consola lee las lineas sin editarlas, pero si la entrada es una
+//terminal sigue mostrando el prompt y continua despues de un error.
+
+func rawMode(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
+
+//isTerminal indica si 'file' es un dispositivo de caracteres, como una terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,472 @@
+package resolver
+
+import (
+	"sort"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/token"
+)
+
+//symbol es una variable de un ambito. Una variable esta reservada desde que empieza su
+//ambito y declarada desde que se ejecuta su declaracion. Las que se declaran dentro de
+//un if quedan como condicionales: otra rama puede volver a declararlas.
+type symbol struct {
+	slot        int
+	declared    bool
+	conditional bool
+	pos         token.Position
+}
+
+//scope es un entorno de ejecucion del evaluador. Root indica un entorno sin 'outer'
+//(el programa o el cuerpo de una funcion con nombre), desde ahi solo se ven las
+//funciones y las variables 'global'. Function cuenta las funciones que lo contienen,
+//asi se sabe si un uso esta dentro de un closure.
+type scope struct {
+	symbols  map[string]*symbol
+	slots    int
+	root     bool
+	function int
+	branches int
+}
+
+//Resolver asigna a cada identificador la posicion de su variable en los entornos del
+//evaluador. Conserva el ambito del programa entre llamadas a Resolve para la consola,
+//'added' son las variables del programa que agrego la ultima llamada.
+type Resolver struct {
+	scopes  []*scope
+	globals map[string]bool
+	added   []string
+	nesting int
+	errors  []*diag.Diagnostic
+}
+
+func New() *Resolver {
+	return &Resolver{
+		scopes:  []*scope{{symbols: map[string]*symbol{}, root: true}},
+		globals: map[string]bool{},
+	}
+}
+
+//Resolve resuelve 'program' con un Resolver nuevo.
+func Resolve(program *ast.Program) []*diag.Diagnostic {
+	return New().Resolve(program)
+}
+
+//Resolve completa el Binding de los identificadores de 'program' y devuelve los usos
+//de variables antes de su declaracion y las declaraciones que ocultan otra variable.
+//Si hay errores el programa no se ejecuta y sus variables no quedan declaradas.
+func (r *Resolver) Resolve(program *ast.Program) []*diag.Diagnostic {
+	r.errors = nil
+	r.added = nil
+	r.hoist(program.Statements)
+	r.reserve(program.Statements)
+	r.statements(program.Statements)
+	if len(r.errors) > 0 {
+		r.Commit(func(int) bool { return false })
+	}
+	return r.errors
+}
+
+//Commit conserva las variables del programa principal que agrego la ultima llamada a
+//Resolve solo si su declaracion se ejecuto, 'ran' indica si la variable de la posicion
+//'slot' tiene valor. Asi, despues de un error de ejecucion o de un if que no se
+//ejecuto, la consola puede volver a declararlas.
+func (r *Resolver) Commit(ran func(slot int) bool) {
+	top := r.scopes[0]
+	for _, name := range r.added {
+		if sym, ok := top.symbols[name]; ok && !ran(sym.slot) {
+			delete(top.symbols, name)
+		}
+	}
+	r.added = nil
+}
+
+//Global agrega 'name' a las funciones y variables 'global', que se buscan por nombre.
+func (r *Resolver) Global(name string) {
+	r.globals[name] = true
+}
+
+//Slot devuelve la posicion de la variable 'name' declarada en el programa principal.
+func (r *Resolver) Slot(name string) (int, bool) {
+	sym, ok := r.scopes[0].symbols[name]
+	if !ok || !sym.declared {
+		return 0, false
+	}
+	return sym.slot, true
+}
+
+//Names devuelve las variables declaradas en el programa principal y las funciones y
+//variables 'global', en orden alfabetico.
+func (r *Resolver) Names() []string {
+	names := []string{}
+	for name, sym := range r.scopes[0].symbols {
+		if sym.declared && !r.globals[name] {
+			names = append(names, name)
+		}
+	}
+	for name := range r.globals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//hoist registra las funciones y las variables 'global', que se buscan por nombre.
+func (r *Resolver) hoist(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.Function:
+			r.globals[stmt.Name.Name] = true
+			r.hoist(stmt.Body.Statements)
+		case *ast.GlobalStatement:
+			r.globals[stmt.Name.Name] = true
+		case *ast.ImportStatement:
+			if stmt.Name != nil {
+				r.globals[stmt.Name.Name] = true
+			}
+		case *ast.ForStatement:
+			r.hoist(stmt.Body.Statements)
+		case *ast.TryStatement:
+			for _, block := range []*ast.BlockStatement{stmt.Block, stmt.Catch, stmt.Finally} {
+				if block != nil {
+					r.hoist(block.Statements)
+				}
+			}
+		case *ast.ExpressionStatement:
+			if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+				r.hoist(ie.Consequence.Statements)
+				if ie.Alternative != nil {
+					r.hoist(ie.Alternative.Statements)
+				}
+			}
+		}
+	}
+}
+
+//reserve reserva en el ambito actual las variables que se declaran en 'statements',
+//incluidas las de los if, que no abren un entorno nuevo.
+func (r *Resolver) reserve(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.VarStatement:
+			r.reserveName(stmt.Name.Name)
+		case *ast.ExpressionStatement:
+			switch expr := stmt.Expression.(type) {
+			case *ast.ImplicitDeclarationExpression:
+				r.reserveName(expr.Left.Name)
+			case *ast.IfExpression:
+				r.reserve(expr.Consequence.Statements)
+				if expr.Alternative != nil {
+					r.reserve(expr.Alternative.Statements)
+				}
+			}
+		}
+	}
+}
+
+func (r *Resolver) reserveName(name string) *symbol {
+	current := r.current()
+	if sym, ok := current.symbols[name]; ok {
+		return sym
+	}
+
+	sym := &symbol{slot: current.slots}
+	current.slots++
+	current.symbols[name] = sym
+	if current == r.scopes[0] {
+		r.added = append(r.added, name)
+	}
+	return sym
+}
+
+//***************************************************************************************
+//***************************************STATEMENTS**************************************
+//***************************************************************************************
+
+func (r *Resolver) statements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		r.statement(stmt)
+	}
+}
+
+func (r *Resolver) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		r.expression(stmt.Expression)
+
+	case *ast.VarStatement:
+		r.expression(stmt.Value)
+		r.declare(stmt.Name)
+
+	case *ast.GlobalStatement:
+		r.expression(stmt.Value)
+		r.checkVisible(stmt.Name)
+
+	case *ast.ImportStatement:
+		if stmt.Name == nil {
+			break
+		}
+		if len(r.scopes) > 1 || r.current().branches > 0 {
+			r.errorf(stmt, diag.InvalidStatement, "module '%s' must be imported at the top level of the file.", stmt.Name.Name)
+		}
+		r.checkVisible(stmt.Name)
+
+	case *ast.ReturnStatement:
+		r.expression(stmt.Expression)
+
+	case *ast.ThrowStatement:
+		r.expression(stmt.Expression)
+
+	case *ast.TryStatement:
+		r.block(stmt.Block, nil)
+		if stmt.Catch != nil {
+			r.block(stmt.Catch, stmt.Parameter)
+		}
+		if stmt.Finally != nil {
+			r.block(stmt.Finally, nil)
+		}
+
+	case *ast.ForStatement:
+		r.forStatement(stmt)
+
+	case *ast.Function:
+		//el evaluador busca por nombre, no ve las variables que ya tienen posicion.
+		r.checkVisible(stmt.Name)
+		for _, param := range stmt.Parameters {
+			r.checkVisible(param.Name)
+		}
+		r.function(stmt.Parameters, stmt.Body, false)
+	}
+}
+
+//block resuelve 'block' en un entorno nuevo, 'param' es la variable del catch.
+func (r *Resolver) block(block *ast.BlockStatement, param *ast.Identifier) {
+	r.enter(false)
+	if param != nil {
+		r.define(param)
+	}
+	r.reserve(block.Statements)
+	r.statements(block.Statements)
+	r.leave()
+}
+
+//forStatement resuelve un for con los dos entornos del evaluador: el del for, con la
+//declaracion y la condicion, y el de cada vuelta, con el cuerpo.
+func (r *Resolver) forStatement(node *ast.ForStatement) {
+	r.enter(false)
+	defer r.leave()
+
+	if impl, ok := node.Condition.(*ast.ImplicitDeclarationExpression); ok {
+		r.expression(impl.Right)
+		r.define(impl.Left)
+	} else {
+		if node.Declaration != nil {
+			if decl, ok := node.Declaration.(*ast.ImplicitDeclarationExpression); ok {
+				r.reserveName(decl.Left.Name)
+			}
+			r.expression(node.Declaration)
+		}
+		r.expression(node.Condition)
+		if node.Operation != nil {
+			r.expression(node.Operation)
+		}
+	}
+
+	r.block(node.Body, nil)
+}
+
+//function resuelve el cuerpo de una funcion. Una funcion con nombre empieza un entorno
+//raiz, un closure extiende el entorno en el que se crea.
+func (r *Resolver) function(params []*ast.FunctionParameters, body *ast.BlockStatement, closure bool) {
+	r.nesting++
+	r.enter(!closure)
+	for _, param := range params {
+		r.define(param.Name)
+	}
+	r.reserve(body.Statements)
+	r.statements(body.Statements)
+	r.leave()
+	r.nesting--
+}
+
+//***************************************************************************************
+//************************************EXPRESSIONS****************************************
+//***************************************************************************************
+
+func (r *Resolver) expression(node ast.Expression) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		r.use(node)
+
+	case *ast.PrefixExpression:
+		r.expression(node.Right)
+
+	case *ast.InfixExpression:
+		r.expression(node.Left)
+		//la derecha de '.' es un campo del struct.
+		if node.Operator != "." {
+			r.expression(node.Right)
+		}
+
+	case *ast.PostfixExpression:
+		r.use(node.Left)
+
+	case *ast.ImplicitDeclarationExpression:
+		r.expression(node.Right)
+		r.declare(node.Left)
+
+	case *ast.AssignExpression:
+		r.expression(node.Right)
+		r.expression(node.Left)
+
+	case *ast.AssignOperationExpression:
+		r.expression(node.Right)
+		r.use(node.Left)
+
+	case *ast.IfExpression:
+		r.expression(node.Codition)
+		r.branch(node.Consequence)
+		if node.Alternative != nil {
+			r.branch(node.Alternative)
+		}
+
+	case *ast.List:
+		for _, element := range node.Elements {
+			r.expression(element)
+		}
+
+	case *ast.Hash:
+		for key, value := range node.Pairs {
+			r.expression(key)
+			r.expression(value)
+		}
+
+	case *ast.IndexExpression:
+		r.expression(node.Left)
+		r.expression(node.Index)
+
+	case *ast.CallExpression:
+		r.expression(node.Function)
+		for _, arg := range node.Arguments {
+			r.expression(arg)
+		}
+
+	case *ast.FunctionClosure:
+		r.function(node.Parameters, node.Body, true)
+	}
+}
+
+//branch resuelve el bloque de un if, que se ejecuta en el entorno que lo contiene.
+func (r *Resolver) branch(block *ast.BlockStatement) {
+	current := r.current()
+	current.branches++
+	r.statements(block.Statements)
+	current.branches--
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//use resuelve la lectura o la escritura de 'ident'.
+func (r *Resolver) use(ident *ast.Identifier) {
+	if r.globals[ident.Name] {
+		ident.Binding = &ast.Binding{Global: true}
+		return
+	}
+
+	depth := 0
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		s := r.scopes[i]
+		if sym, ok := s.symbols[ident.Name]; ok {
+			//dentro de un closure la variable puede declararse despues de crearlo.
+			if !sym.declared && s.function == r.nesting {
+				r.errorf(ident, diag.NameNotFound, "variable '%s' used before its declaration", ident.Name)
+				return
+			}
+			ident.Binding = &ast.Binding{Depth: depth, Slot: sym.slot}
+			return
+		}
+
+		if s.root {
+			break
+		}
+		depth++
+	}
+	//los nombres desconocidos se buscan al ejecutar, ahi se informa el error.
+}
+
+//declare declara la variable 'ident' en el ambito actual. Como en el evaluador, no se
+//puede declarar una variable con el nombre de otra que ya es visible.
+func (r *Resolver) declare(ident *ast.Identifier) {
+	if _, ok := evaluator.Builtin(ident.Name); ok || r.globals[ident.Name] {
+		r.errorf(ident, diag.AlreadyDeclared, "variable '%s' already exist.", ident.Name)
+		r.unreserve(ident.Name)
+		return
+	}
+
+	if r.checkVisible(ident) {
+		r.define(ident)
+		return
+	}
+	r.unreserve(ident.Name)
+}
+
+//unreserve quita la reserva de 'name' en el ambito actual si no se llego a declarar. Asi,
+//despues de una declaracion rechazada, los usos siguientes son de la variable o funcion
+//que ya existe y no se informan como usos antes de la declaracion.
+func (r *Resolver) unreserve(name string) {
+	current := r.current()
+	if sym, ok := current.symbols[name]; ok && !sym.declared {
+		delete(current.symbols, name)
+	}
+}
+
+//checkVisible informa si 'ident' tiene el nombre de una variable ya declarada y visible.
+//Una variable declarada en una rama de un if puede declararse otra vez en otra rama.
+func (r *Resolver) checkVisible(ident *ast.Identifier) bool {
+	current := r.current()
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		s := r.scopes[i]
+		sym, ok := s.symbols[ident.Name]
+		if ok && sym.declared && !(s == current && sym.conditional && current.branches > 0) {
+			d := r.errorf(ident, diag.AlreadyDeclared, "variable '%s' already exist.", ident.Name)
+			d.Notes = append(d.Notes, "previous declaration at "+sym.pos.String())
+			return false
+		}
+		if s.root {
+			break
+		}
+	}
+	return true
+}
+
+//define declara 'ident' en el ambito actual sin comprobar si oculta otra variable.
+func (r *Resolver) define(ident *ast.Identifier) {
+	current := r.current()
+	sym := r.reserveName(ident.Name)
+	sym.declared = true
+	sym.conditional = current.branches > 0
+	sym.pos = ident.Pos()
+	ident.Binding = &ast.Binding{Slot: sym.slot}
+}
+
+func (r *Resolver) enter(root bool) {
+	r.scopes = append(r.scopes, &scope{symbols: map[string]*symbol{}, root: root, function: r.nesting})
+}
+
+func (r *Resolver) leave() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *Resolver) current() *scope {
+	return r.scopes[len(r.scopes)-1]
+}
+
+func (r *Resolver) errorf(node ast.Node, code string, format string, a ...interface{}) *diag.Diagnostic {
+	d := diag.Errorf(code, diag.SpanAt(node.Pos(), len(node.TokenLiteral())), format, a...)
+	r.errors = append(r.errors, d)
+	return d
+}
@@ -0,0 +1,215 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+)
+
+func testParse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParserProgram()
+	if len(p.Error()) > 0 {
+		t.Fatalf("parser has errors: %q", p.Error())
+	}
+	return program
+}
+
+func testResolve(t *testing.T, input string) []string {
+	errors := []string{}
+	for _, d := range Resolve(testParse(t, input)) {
+		errors = append(errors, d.Error())
+	}
+	return errors
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"print(x);\nvar x:int = 1;", []string{"1:7 - variable 'x' used before its declaration"}},
+		{"x := 1;\nx := 2;", []string{"2:1 - variable 'x' already exist."}},
+		{"var x:int = 1;\nfor (i := 0; i < 2; i++) { x := 2; }", []string{"2:28 - variable 'x' already exist."}},
+		{"len := 1;", []string{"1:1 - variable 'len' already exist."}},
+		//despues de una declaracion rechazada los usos son de la variable que ya existe.
+		{"i := 10;\nfor (i := 0; i < 2; i++) { }", []string{"2:6 - variable 'i' already exist."}},
+		{"len := 1;\nlen([1]);", []string{"1:1 - variable 'len' already exist."}},
+		{"fn f() { }\nf := 1;", []string{"2:1 - variable 'f' already exist."}},
+		{"var a:int = 1;\nfn f(a:int) { }", []string{"2:6 - variable 'a' already exist."}},
+		{"global a:int = 1;\nvar a:int = 2;", []string{"2:5 - variable 'a' already exist."}},
+		{"fn f() int {\n\treturn y;\n\ty := 1;\n}", []string{"2:9 - variable 'y' used before its declaration"}},
+		//un closure puede usar una variable que se declara despues en su ambito.
+		{"var f:func = fn() int { return b; };\nvar b:int = 1;", []string{}},
+		//cada rama de un if puede declarar la misma variable.
+		{"if (true) { x := 1; } else { x := 2; }", []string{}},
+		//una funcion con nombre no ve las variables del programa.
+		{"var a:int = 1;\nfn f() { a := 2; }", []string{}},
+		{"try { x := 1; } catch (e) { x := 2; } finally { x := 3; }", []string{}},
+		{"var s:struct = { x:int };\nx := s.x;", []string{}},
+	}
+
+	for i, tt := range tests {
+		errors := testResolve(t, tt.input)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("tests[%d] - len(errors) is not %d. got=%q", i, len(tt.expected), errors)
+			continue
+		}
+
+		for j, msg := range tt.expected {
+			if errors[j] != msg {
+				t.Errorf("tests[%d] - errors[%d] is not %q. got=%q", i, j, msg, errors[j])
+			}
+		}
+	}
+}
+
+func TestBindings(t *testing.T) {
+	input := `
+a := 1;
+b := 2;
+for (i := 0; i < 1; i++) {
+	c := a + i;
+}
+var f:func = fn(x:int) int { return x + b; };
+fn g() int { return 1; }
+`
+	program := testParse(t, input)
+	if errors := Resolve(program); len(errors) > 0 {
+		t.Fatalf("resolver has errors: %q", errors)
+	}
+
+	bindings := map[string][]ast.Binding{}
+	collect(program, func(ident *ast.Identifier) {
+		if ident.Binding != nil {
+			bindings[ident.Name] = append(bindings[ident.Name], *ident.Binding)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		expected []ast.Binding
+	}{
+		{"a", []ast.Binding{{Slot: 0}, {Depth: 2, Slot: 0}}},
+		{"b", []ast.Binding{{Slot: 1}, {Depth: 1, Slot: 1}}},
+		{"i", []ast.Binding{{Slot: 0}, {Slot: 0}, {Slot: 0}, {Depth: 1, Slot: 0}}},
+		{"c", []ast.Binding{{Slot: 0}}},
+		{"x", []ast.Binding{{Slot: 0}, {Slot: 0}}},
+		{"g", []ast.Binding{}},
+	}
+
+	for _, tt := range tests {
+		got := bindings[tt.name]
+		if len(got) != len(tt.expected) {
+			t.Errorf("bindings of %q wrong. want=%+v, got=%+v", tt.name, tt.expected, got)
+			continue
+		}
+		for i := range tt.expected {
+			if got[i] != tt.expected[i] {
+				t.Errorf("bindings of %q wrong. want=%+v, got=%+v", tt.name, tt.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestResolvedEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a := 1; b := 2; a + b;", "3"},
+		{"sum := 0; for (i := 0; i < 5; i++) { sum += i; } sum;", "10"},
+		{"sum := 0; for (e := [1, 2, 3]) { sum = sum + e; } sum;", "6"},
+		{"var f:func = fn(x:int) int { return x + b; }; var b:int = 10; f(5);", "15"},
+		{"fn fib(n:int) int { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); } fib(10);", "55"},
+		{"global g:int = 1; fn f() int { g++; return g; } f(); f();", "3"},
+		{"var counter:func = fn() func { c := 0; return fn() int { c++; return c; }; }; var next:func = counter(); next(); next();", "2"},
+		{"if (true) { x := 1; } else { x := 2; } x;", "1"},
+		{"r := \"\"; try { throw \"boom\"; } catch (e) { r = e.message; } r;", "'boom'"},
+	}
+
+	for i, tt := range tests {
+		program := testParse(t, tt.input)
+		if errors := Resolve(program); len(errors) > 0 {
+			t.Errorf("tests[%d] - resolver has errors: %q", i, errors)
+			continue
+		}
+
+		result := evaluator.Eval(program, object.NewEnvironment())
+		if result == nil || result.Inspect() != tt.expected {
+			t.Errorf("tests[%d] - result is not %q. got=%+v", i, tt.expected, result)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	r := New()
+	for _, input := range []string{"var a:int = 1; global g:int = 2; fn f() { x := 1; }", "b := a; if (true) { c := 1; }"} {
+		if errors := r.Resolve(testParse(t, input)); len(errors) > 0 {
+			t.Fatalf("resolver has errors: %q", errors)
+		}
+	}
+
+	expected := "[a b c f g]"
+	if names := fmt.Sprint(r.Names()); names != expected {
+		t.Fatalf("names are not %s. got=%s", expected, names)
+	}
+}
+
+//collect recorre 'node' en orden y llama a 'visit' con cada identificador que puede
+//tener Binding.
+func collect(node ast.Node, visit func(*ast.Identifier)) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			collect(stmt, visit)
+		}
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			collect(stmt, visit)
+		}
+	case *ast.ExpressionStatement:
+		collect(node.Expression, visit)
+	case *ast.VarStatement:
+		collect(node.Value, visit)
+		visit(node.Name)
+	case *ast.ReturnStatement:
+		collect(node.Expression, visit)
+	case *ast.ForStatement:
+		if node.Declaration != nil {
+			collect(node.Declaration, visit)
+		}
+		collect(node.Condition, visit)
+		if node.Operation != nil {
+			collect(node.Operation, visit)
+		}
+		collect(node.Body, visit)
+	case *ast.Function:
+		visit(node.Name)
+		for _, param := range node.Parameters {
+			visit(param.Name)
+		}
+		collect(node.Body, visit)
+	case *ast.FunctionClosure:
+		for _, param := range node.Parameters {
+			visit(param.Name)
+		}
+		collect(node.Body, visit)
+	case *ast.Identifier:
+		visit(node)
+	case *ast.InfixExpression:
+		collect(node.Left, visit)
+		collect(node.Right, visit)
+	case *ast.PostfixExpression:
+		visit(node.Left)
+	case *ast.ImplicitDeclarationExpression:
+		collect(node.Right, visit)
+		visit(node.Left)
+	}
+}
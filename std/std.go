@@ -0,0 +1,41 @@
+//Package std guarda la biblioteca estandar de April dentro del ejecutable. Sus archivos
+//se importan con el prefijo reservado "std/", por ejemplo 'import "std/math" as math',
+//desde cualquier directorio.
+//
+//Antes la biblioteca estaba en el directorio "package/" del repositorio y se importaba
+//como 'import "package/math.april"'. Esos imports ya no se encuentran: hay que cambiarlos
+//por "std/math", y el error de "module not found" indica el import que corresponde.
+package std
+
+import (
+	"embed"
+	"strings"
+)
+
+//Prefix es el comienzo de las rutas de import de la biblioteca estandar.
+const Prefix = "std/"
+
+//go:embed *.april
+var files embed.FS
+
+//Is indica si el import 'path' es de la biblioteca estandar.
+func Is(path string) bool {
+	return strings.HasPrefix(path, Prefix)
+}
+
+//Name devuelve el nombre del archivo del import 'path', "std/math" es "std/math.april".
+func Name(path string) string {
+	if !strings.HasSuffix(path, ".april") {
+		path += ".april"
+	}
+	return path
+}
+
+//Read devuelve el codigo del archivo de la biblioteca estandar del import 'path'.
+func Read(path string) (string, bool) {
+	data, err := files.ReadFile(strings.TrimPrefix(Name(path), Prefix))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
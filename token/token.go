@@ -1,5 +1,10 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 //***************************************************************************************
 //***************************************************************************************
 //***************************************************************************************
@@ -78,42 +83,81 @@ const (
 	FNCLOSURE = "FNCLOSURE"
 	FOR       = "FOR"
 	BREAK     = "BREAK"
+	TRY       = "TRY"
+	CATCH     = "CATCH"
+	FINALLY   = "FINALLY"
+	THROW     = "THROW"
 )
 
 //TokenType es un tipo de dato que representa el 'tipo' de los lexemas definidos.
 type TokenType string
 
+//Position indica donde comienza un lexema dentro del codigo fuente: archivo, linea,
+//columna (ambas desde 1) y desplazamiento en bytes (desde 0).
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+//IsValid indica si la posicion fue asignada por el lexer.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+//String devuelve la posicion con el formato 'archivo:linea:columna'. Si el codigo no
+//proviene de un archivo (consola) se omite el nombre.
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.File != "" {
+			return p.File
+		}
+		return "-"
+	}
+
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
 //Token es un tipo de dato que representa un lexema.
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
 }
 
 var keywords = map[string]TokenType{
-	"var":    VAR,
-	"global": GLOBAL,
-	"true":   TRUE,
-	"false":  FALSE,
-	"not":    NOT,
-	"int":    OPEINT,
-	"bool":   OPEBOOL,
-	"string": OPESTR,
-	"double": OPEDOUBLE,
-	"func":   FUNC,
-	"stream": STREAM,
-	"struct": STRUCT,
-	"and":    AND,
-	"or":     OR,
-	"return": RETURN,
-	"if":     IF,
-	"else":   ELSE,
-	"fn":     FNCLOSURE,
-	"for":    FOR,
-	"list":   LIST,
-	"map":    MAP,
-	"import": IMPORT,
-	"break":  BREAK,
-	"nil":    NIL,
+	"var":     VAR,
+	"global":  GLOBAL,
+	"true":    TRUE,
+	"false":   FALSE,
+	"not":     NOT,
+	"int":     OPEINT,
+	"bool":    OPEBOOL,
+	"string":  OPESTR,
+	"double":  OPEDOUBLE,
+	"func":    FUNC,
+	"stream":  STREAM,
+	"struct":  STRUCT,
+	"and":     AND,
+	"or":      OR,
+	"return":  RETURN,
+	"if":      IF,
+	"else":    ELSE,
+	"fn":      FNCLOSURE,
+	"for":     FOR,
+	"list":    LIST,
+	"map":     MAP,
+	"import":  IMPORT,
+	"break":   BREAK,
+	"nil":     NIL,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
 }
 
 //LookKeyword comprueba si la variable 'name' es una palabra clave dentro del map de keywords.
@@ -123,3 +167,13 @@ func LookKeyword(name string) TokenType {
 	}
 	return IDENT
 }
+
+//Keywords devuelve las palabras clave del lenguaje en orden alfabetico.
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,581 @@
+package typecheck
+
+import (
+	"fmt"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/diag"
+)
+
+//symbol es una variable o funcion visible en un ambito. 'fn' solo existe cuando se
+//conoce la declaracion de la funcion.
+type symbol struct {
+	typ Type
+	fn  *signature
+}
+
+//scope sigue las mismas reglas de visibilidad que object.Environment: los bloques 'for'
+//y 'try' abren un ambito nuevo, los bloques 'if' usan el del codigo que los contiene.
+type scope struct {
+	names map[string]*symbol
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: make(map[string]*symbol), outer: outer}
+}
+
+func (s *scope) lookup(name string) (*symbol, bool) {
+	for ; s != nil; s = s.outer {
+		if sym, ok := s.names[name]; ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+//Checker recorre un programa ya analizado por el parser y reporta los errores de tipos
+//antes de ejecutarlo. Guarda las declaraciones entre llamadas a Check, asi la consola
+//puede verificar cada linea con lo declarado en las anteriores.
+type Checker struct {
+	globals  *scope //funciones y variables 'global', visibles desde cualquier funcion
+	top      *scope
+	function *signature //funcion que se esta verificando, nil fuera de una funcion
+	pending  []func()
+	errors   []*diag.Diagnostic
+}
+
+func New() *Checker {
+	return &Checker{globals: newScope(nil), top: newScope(nil)}
+}
+
+//Check verifica el programa 'program' con un Checker nuevo.
+func Check(program *ast.Program) []*diag.Diagnostic {
+	return New().Check(program)
+}
+
+//Check verifica el programa 'program' y devuelve los errores ordenados por posicion.
+func (c *Checker) Check(program *ast.Program) []*diag.Diagnostic {
+	c.errors = nil
+
+	//las funciones y variables globales se pueden usar dentro de una funcion declarada antes.
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.Function:
+			c.declareFunction(stmt)
+		case *ast.GlobalStatement:
+			c.globals.names[stmt.Name.Name] = &symbol{typ: typeOfName(stmt.Type.Name)}
+		case *ast.ImportStatement:
+			if stmt.Name != nil {
+				c.globals.names[stmt.Name.Name] = &symbol{typ: Module}
+			}
+		}
+	}
+
+	c.checkStatements(program.Statements, c.top)
+
+	//el cuerpo de un closure se verifica al final, cuando su ambito ya tiene todas las
+	//variables que puede ver al ejecutarse.
+	for len(c.pending) > 0 {
+		check := c.pending[0]
+		c.pending = c.pending[1:]
+		check()
+	}
+
+	diag.Sort(c.errors)
+	return c.errors
+}
+
+//Declare agrega 'name' a las funciones y variables 'global' con el tipo 'typ'. Lo usa el
+//paquete april para los valores y las funciones que registra el programa Go.
+func (c *Checker) Declare(name string, typ Type) {
+	c.globals.names[name] = &symbol{typ: typ}
+}
+
+//TypeOf devuelve el tipo estatico de 'expr' con lo declarado en las llamadas anteriores a
+//Check. No guarda los errores ni las declaraciones de la expresion.
+func (c *Checker) TypeOf(expr ast.Expression) Type {
+	errors, pending := c.errors, c.pending
+	defer func() { c.errors, c.pending = errors, pending }()
+	return c.typeOf(expr, newScope(c.top))
+}
+
+func (c *Checker) errorf(node ast.Node, code string, format string, a ...interface{}) {
+	c.errors = append(c.errors, diag.Errorf(code, spanOf(node), format, a...))
+}
+
+func spanOf(node ast.Node) diag.Span {
+	if call, ok := node.(*ast.CallExpression); ok && call.Function != nil {
+		return spanOf(call.Function)
+	}
+	return diag.SpanAt(node.Pos(), len(node.TokenLiteral()))
+}
+
+func (c *Checker) declareFunction(node *ast.Function) {
+	sig := &signature{name: node.Name.Name}
+	for _, param := range node.Parameters {
+		sig.params = append(sig.params, typeOfName(param.Type.Name))
+	}
+	if node.Type != nil {
+		sig.result = typeOfName(node.Type.Name)
+	}
+	c.globals.names[node.Name.Name] = &symbol{typ: Func, fn: sig}
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+func (c *Checker) checkStatements(stmts []ast.Statement, s *scope) {
+	for _, stmt := range stmts {
+		c.checkStatement(stmt, s)
+	}
+}
+
+func (c *Checker) checkStatement(stmt ast.Statement, s *scope) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		c.typeOf(stmt.Expression, s)
+
+	case *ast.VarStatement:
+		c.checkDeclaration(stmt, stmt.Name, stmt.Type, stmt.Value, s)
+		s.names[stmt.Name.Name] = &symbol{typ: typeOfName(stmt.Type.Name), fn: c.closureSignature(stmt.Value)}
+
+	case *ast.GlobalStatement:
+		c.checkDeclaration(stmt, stmt.Name, stmt.Type, stmt.Value, s)
+		c.globals.names[stmt.Name.Name] = &symbol{typ: typeOfName(stmt.Type.Name), fn: c.closureSignature(stmt.Value)}
+
+	case *ast.Function:
+		if _, ok := c.globals.names[stmt.Name.Name]; !ok {
+			c.declareFunction(stmt)
+		}
+		c.checkFunction(stmt)
+
+	case *ast.ImportStatement:
+		if stmt.Name != nil {
+			c.globals.names[stmt.Name.Name] = &symbol{typ: Module}
+		}
+
+	case *ast.ReturnStatement:
+		c.checkReturn(stmt, s)
+
+	case *ast.ThrowStatement:
+		c.typeOf(stmt.Expression, s)
+
+	case *ast.ForStatement:
+		c.checkFor(stmt, s)
+
+	case *ast.TryStatement:
+		c.checkStatements(stmt.Block.Statements, newScope(s))
+		if stmt.Catch != nil {
+			catch := newScope(s)
+			catch.names[stmt.Parameter.Name] = &symbol{typ: Struct}
+			c.checkStatements(stmt.Catch.Statements, catch)
+		}
+		if stmt.Finally != nil {
+			c.checkStatements(stmt.Finally.Statements, newScope(s))
+		}
+
+	case *ast.BlockStatement:
+		c.checkStatements(stmt.Statements, s)
+	}
+}
+
+func (c *Checker) checkDeclaration(node ast.Node, name, declared *ast.Identifier, value ast.Expression, s *scope) {
+	typ := c.typeOf(value, s)
+	if to := typeOfName(declared.Name); isKnown(to) && isKnown(typ) && to != typ {
+		c.errorf(node, diag.TypeMismatch, "cannot use value of type '%s' to declare var %s:%s", typ, name.Name, declared.Name)
+	}
+}
+
+//closureSignature devuelve la firma de 'value' si es un closure escrito en la declaracion,
+//asi las llamadas a la variable se pueden verificar.
+func (c *Checker) closureSignature(value ast.Expression) *signature {
+	closure, ok := value.(*ast.FunctionClosure)
+	if !ok {
+		return nil
+	}
+	return closureSignatureOf(closure)
+}
+
+func closureSignatureOf(closure *ast.FunctionClosure) *signature {
+	sig := &signature{name: "<closure>"}
+	for _, param := range closure.Parameters {
+		sig.params = append(sig.params, typeOfName(param.Type.Name))
+	}
+	if closure.Type != nil {
+		sig.result = typeOfName(closure.Type.Name)
+	}
+	return sig
+}
+
+//checkFunction verifica el cuerpo de una funcion, solo ve sus parametros, sus variables
+//y las globales.
+func (c *Checker) checkFunction(node *ast.Function) {
+	body := newScope(nil)
+	for _, param := range node.Parameters {
+		body.names[param.Name.Name] = &symbol{typ: typeOfName(param.Type.Name)}
+	}
+
+	sym := c.globals.names[node.Name.Name]
+	c.checkBody(node, sym.fn, node.Body, body)
+}
+
+func (c *Checker) checkClosure(node *ast.FunctionClosure, s *scope) {
+	body := newScope(s)
+	for _, param := range node.Parameters {
+		body.names[param.Name.Name] = &symbol{typ: typeOfName(param.Type.Name)}
+	}
+	c.checkBody(node, closureSignatureOf(node), node.Body, body)
+}
+
+func (c *Checker) checkBody(node ast.Node, sig *signature, body *ast.BlockStatement, s *scope) {
+	previous := c.function
+	c.function = sig
+	c.checkStatements(body.Statements, s)
+	c.function = previous
+
+	if sig.result != "" && !returns(body.Statements) {
+		c.errorf(node, diag.MissingReturn, "missing return in function '%s', expected '%s'", sig.name, sig.result)
+	}
+}
+
+func (c *Checker) checkReturn(node *ast.ReturnStatement, s *scope) {
+	typ := c.typeOf(node.Expression, s)
+	if c.function == nil || c.function.result == "" {
+		return
+	}
+
+	if !assignable(c.function.result, typ) {
+		c.errorf(node, diag.TypeMismatch, "cannot return value of type '%s' from function '%s', expected '%s'", typ, c.function.name, c.function.result)
+	}
+}
+
+func (c *Checker) checkFor(node *ast.ForStatement, s *scope) {
+	loop := newScope(s)
+
+	if each, ok := node.Condition.(*ast.ImplicitDeclarationExpression); ok {
+		if typ := c.typeOf(each.Right, loop); isKnown(typ) && typ != List {
+			c.errorf(node, diag.TypeMismatch, "expression incompatible with for, expression must be type list, got '%s'", typ)
+		}
+		loop.names[each.Left.Name] = &symbol{typ: Any}
+		c.checkStatements(node.Body.Statements, newScope(loop))
+		return
+	}
+
+	if node.Declaration != nil {
+		c.typeOf(node.Declaration, loop)
+	}
+	if typ := c.typeOf(node.Condition, loop); isKnown(typ) && typ != Bool {
+		c.errorf(node, diag.TypeMismatch, "for condition must be type bool, got '%s'", typ)
+	}
+	if node.Operation != nil {
+		c.typeOf(node.Operation, loop)
+	}
+	c.checkStatements(node.Body.Statements, newScope(loop))
+}
+
+//returns indica si la ejecucion de 'stmts' termina siempre con un return o un throw.
+func returns(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement:
+			return true
+		case *ast.ExpressionStatement:
+			if ie, ok := stmt.Expression.(*ast.IfExpression); ok && ie.Alternative != nil {
+				if returns(ie.Consequence.Statements) && returns(ie.Alternative.Statements) {
+					return true
+				}
+			}
+		case *ast.TryStatement:
+			if stmt.Finally != nil && returns(stmt.Finally.Statements) {
+				return true
+			}
+			if returns(stmt.Block.Statements) && (stmt.Catch == nil || returns(stmt.Catch.Statements)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//***************************************************************************************
+//***************************************************************************************
+//***************************************************************************************
+
+//typeOf verifica la expresion 'node' y devuelve su tipo.
+func (c *Checker) typeOf(node ast.Expression, s *scope) Type {
+	switch node := node.(type) {
+	case nil:
+		return Any
+	case *ast.Integer:
+		return Int
+	case *ast.Double:
+		return Double
+	case *ast.Boolean:
+		return Bool
+	case *ast.String:
+		return String
+	case *ast.Nil:
+		return Nil
+	case *ast.Stream:
+		return Stream
+	case *ast.Struct:
+		return Struct
+
+	case *ast.List:
+		for _, element := range node.Elements {
+			c.typeOf(element, s)
+		}
+		return List
+
+	case *ast.Hash:
+		for key, value := range node.Pairs {
+			c.typeOf(key, s)
+			c.typeOf(value, s)
+		}
+		return Map
+
+	case *ast.FunctionClosure:
+		c.pending = append(c.pending, func() { c.checkClosure(node, s) })
+		return Func
+
+	case *ast.Identifier:
+		if sym, ok := c.lookup(node.Name, s); ok {
+			return sym.typ
+		}
+		if _, ok := builtins[node.Name]; ok {
+			return Func
+		}
+		c.errorf(node, diag.NameNotFound, "identifier not found: %s", node.Name)
+		return Any
+
+	case *ast.PrefixExpression:
+		return c.typeOfPrefix(node, s)
+
+	case *ast.InfixExpression:
+		return c.typeOfInfix(node, s)
+
+	case *ast.IfExpression:
+		c.typeOf(node.Codition, s)
+		c.checkStatements(node.Consequence.Statements, s)
+		if node.Alternative != nil {
+			c.checkStatements(node.Alternative.Statements, s)
+		}
+		return Any
+
+	case *ast.CallExpression:
+		return c.typeOfCall(node, s)
+
+	case *ast.IndexExpression:
+		left := c.typeOf(node.Left, s)
+		index := c.typeOf(node.Index, s)
+		if (left == List || left == String) && isKnown(index) && index != Int {
+			c.errorf(node, diag.TypeMismatch, "index of type '%s' must be int, got '%s'", left, index)
+		}
+		if left == String {
+			return String
+		}
+		if isKnown(left) && left != List && left != Map {
+			c.errorf(node, diag.TypeMismatch, "index operator not supported on type '%s'", left)
+		}
+		return Any
+
+	case *ast.ImplicitDeclarationExpression:
+		typ := c.typeOf(node.Right, s)
+		if typ == Nil {
+			c.errorf(node, diag.TypeMismatch, "declaration not compatible '%s' := '%s'", node.Left.Name, typ)
+		}
+		s.names[node.Left.Name] = &symbol{typ: typ, fn: c.closureSignature(node.Right)}
+		return Nil
+
+	case *ast.AssignExpression:
+		c.checkAssign(node, s)
+		return Nil
+
+	case *ast.AssignOperationExpression:
+		left := c.typeOfName(node.Left, s)
+		right := c.typeOf(node.Right, s)
+		if result := c.arithmetic(node, string(node.Operator[0]), left, right); !assignable(left, result) {
+			c.errorf(node, diag.TypeMismatch, "assign not compatible '%s' : '%s'", result, left)
+		}
+		return Nil
+
+	case *ast.PostfixExpression:
+		if typ := c.typeOfName(node.Left, s); isKnown(typ) && typ != Int {
+			c.errorf(node, diag.TypeMismatch, "operator '%s' must be integer, got '%s'", node.Operator, typ)
+		}
+		return Int
+	}
+
+	return Any
+}
+
+func (c *Checker) lookup(name string, s *scope) (*symbol, bool) {
+	if sym, ok := c.globals.names[name]; ok {
+		return sym, true
+	}
+	return s.lookup(name)
+}
+
+//typeOfName devuelve el tipo de la variable 'ident' que se va a modificar.
+func (c *Checker) typeOfName(ident *ast.Identifier, s *scope) Type {
+	if sym, ok := c.lookup(ident.Name, s); ok {
+		return sym.typ
+	}
+	c.errorf(ident, diag.NameNotFound, "variable '%s' not exist", ident.Name)
+	return Any
+}
+
+func (c *Checker) checkAssign(node *ast.AssignExpression, s *scope) {
+	right := c.typeOf(node.Right, s)
+
+	ident, ok := node.Left.(*ast.Identifier)
+	if !ok {
+		c.typeOf(node.Left, s)
+		return
+	}
+
+	left := c.typeOfName(ident, s)
+	if !assignable(left, right) {
+		c.errorf(node, diag.TypeMismatch, "assign not compatible '%s' : '%s'", right, left)
+	}
+}
+
+func (c *Checker) typeOfPrefix(node *ast.PrefixExpression, s *scope) Type {
+	right := c.typeOf(node.Right, s)
+	switch node.Operator {
+	case "not":
+		return Bool
+	case "-":
+		if isKnown(right) && !isNumeric(right) {
+			c.errorf(node, diag.TypeMismatch, "unknown operator: -%s", right)
+			return Any
+		}
+		return right
+	}
+	return Any
+}
+
+func (c *Checker) typeOfInfix(node *ast.InfixExpression, s *scope) Type {
+	left := c.typeOf(node.Left, s)
+
+	//el lado derecho de '.' es el nombre de un campo del struct o del modulo.
+	if node.Operator == "." {
+		if isKnown(left) && left != Struct && left != Module {
+			c.errorf(node.Left, diag.TypeMismatch, "the variable '%s' is not type struct", node.Left.String())
+		}
+		return Any
+	}
+
+	right := c.typeOf(node.Right, s)
+	if !isKnown(left) || !isKnown(right) {
+		switch node.Operator {
+		case "==", "!=", "<", ">", "<=", ">=", "and", "or":
+			return Bool
+		}
+		return Any
+	}
+
+	switch node.Operator {
+	case "and", "or":
+		if left != Bool || right != Bool {
+			c.errorf(node, diag.TypeMismatch, "unknown operator: %s %s %s", left, node.Operator, right)
+		}
+		return Bool
+	case "==", "!=", "<", ">", "<=", ">=":
+		if left != right && !(isNumeric(left) && isNumeric(right)) {
+			c.errorf(node, diag.TypeMismatch, "type mismatch: %s %s %s", left, node.Operator, right)
+		} else if left == Bool && node.Operator != "==" && node.Operator != "!=" {
+			c.errorf(node, diag.TypeMismatch, "unknown operator: %s %s %s", left, node.Operator, right)
+		}
+		return Bool
+	}
+
+	return c.arithmetic(node, node.Operator, left, right)
+}
+
+//arithmetic devuelve el tipo de 'left operator right' para los operadores + - * / %.
+func (c *Checker) arithmetic(node ast.Node, operator string, left, right Type) Type {
+	switch {
+	case !isKnown(left) || !isKnown(right):
+		return Any
+	case left == Int && right == Int:
+		return Int
+	case isNumeric(left) && isNumeric(right) && operator != "%":
+		return Double
+	case left == String && right == String && operator == "+":
+		return String
+	case left != right && !(isNumeric(left) && isNumeric(right)):
+		c.errorf(node, diag.TypeMismatch, "type mismatch: %s %s %s", left, operator, right)
+	default:
+		c.errorf(node, diag.TypeMismatch, "unknown operator: %s %s %s", left, operator, right)
+	}
+	return Any
+}
+
+func (c *Checker) typeOfCall(node *ast.CallExpression, s *scope) Type {
+	args := []Type{}
+	for _, arg := range node.Arguments {
+		args = append(args, c.typeOf(arg, s))
+	}
+
+	var sig *signature
+	switch fn := node.Function.(type) {
+	case *ast.Identifier:
+		sym, ok := c.lookup(fn.Name, s)
+		if !ok {
+			if b, ok := builtins[fn.Name]; ok {
+				return c.checkBuiltinCall(node, fn.Name, b, len(args))
+			}
+			c.errorf(fn, diag.NameNotFound, "identifier not found: %s", fn.Name)
+			return Any
+		}
+		if isKnown(sym.typ) && sym.typ != Func {
+			c.errorf(node, diag.TypeMismatch, "not a function: %s", sym.typ)
+			return Any
+		}
+		sig = sym.fn
+
+	case *ast.FunctionClosure:
+		c.typeOf(fn, s)
+		sig = closureSignatureOf(fn)
+
+	default:
+		if typ := c.typeOf(fn, s); isKnown(typ) && typ != Func {
+			c.errorf(node, diag.TypeMismatch, "not a function: %s", typ)
+		}
+	}
+
+	if sig == nil {
+		return Any
+	}
+
+	if len(args) != len(sig.params) {
+		c.errorf(node, diag.WrongArgumentNum, "function '%s' expects %d arguments, got %d", sig.name, len(sig.params), len(args))
+	} else {
+		for i, arg := range args {
+			if !assignable(sig.params[i], arg) {
+				c.errorf(node.Arguments[i], diag.TypeMismatch, "argument %d of function '%s' must be '%s', got '%s'", i+1, sig.name, sig.params[i], arg)
+			}
+		}
+	}
+
+	if sig.result == "" {
+		return Nil
+	}
+	return sig.result
+}
+
+func (c *Checker) checkBuiltinCall(node *ast.CallExpression, name string, b builtin, count int) Type {
+	if count < b.min || (b.max != variadic && count > b.max) {
+		want := fmt.Sprintf("%d", b.min)
+		if b.max == variadic {
+			want += " or more"
+		} else if b.max != b.min {
+			want += fmt.Sprintf(" or %d", b.max)
+		}
+		c.errorf(node, diag.WrongArgumentNum, "function '%s' expects %s arguments, got %d", name, want, count)
+	}
+	return b.result
+}
@@ -0,0 +1,141 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/kenshindeveloper/april/ast"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/parser"
+)
+
+func testCheck(t *testing.T, input string) []string {
+	p := parser.New(lexer.New(input))
+	program := p.ParserProgram()
+	if len(p.Error()) > 0 {
+		t.Fatalf("parser has errors: %q", p.Error())
+	}
+
+	errors := []string{}
+	for _, d := range Check(program) {
+		errors = append(errors, d.Error())
+	}
+	return errors
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"var s:string = \"hola\";\nvar x:int = s;", []string{"2:1 - cannot use value of type 'string' to declare var x:int"}},
+		{"var x:double = 1.5; x = 2; x = true;", []string{"1:30 - assign not compatible 'bool' : 'double'"}},
+		{"print(y);", []string{"1:7 - identifier not found: y"}},
+		{"z = 1;", []string{"1:1 - variable 'z' not exist"}},
+		{"fn add(a:int, b:int) int { return a + b; }\nadd(1);", []string{"2:1 - function 'add' expects 2 arguments, got 1"}},
+		{"fn add(a:int, b:int) int { return a + b; }\nadd(1, \"2\");", []string{"2:8 - argument 2 of function 'add' must be 'int', got 'string'"}},
+		{"fn half(a:double) double { return a / 2; }\nhalf(3);", []string{}},
+		{"fn sign(a:int) int {\n\tif (a < 0) { return -1; }\n}", []string{"1:1 - missing return in function 'sign', expected 'int'"}},
+		{"fn sign(a:int) int {\n\tif (a < 0) { return -1; } else { return 1; }\n}", []string{}},
+		{"fn name() string { return 1; }", []string{"1:20 - cannot return value of type 'int' from function 'name', expected 'string'"}},
+		{"var f:func = fn(x:int) int { return x; };\nf(1, 2);", []string{"2:1 - function '<closure>' expects 1 arguments, got 2"}},
+		{"len([1], [2]);", []string{"1:1 - function 'len' expects 1 arguments, got 2"}},
+		{"x := 1 + \"a\";", []string{"1:8 - type mismatch: int + string"}},
+		{"var x:int = 1; x();", []string{"1:16 - not a function: int"}},
+		{"for (i := 0; i < 10; i++) { print(i); }\nprint(i);", []string{"2:7 - identifier not found: i"}},
+		{"for (e := 5) { print(e); }", []string{"1:1 - expression incompatible with for, expression must be type list, got 'int'"}},
+		{"try { 1 / 0; } catch (e) { print(e.message); }", []string{}},
+		{"var a:string = \"hola\"; a[\"x\"];", []string{"1:25 - index of type 'string' must be int, got 'string'"}},
+	}
+
+	for i, tt := range tests {
+		errors := testCheck(t, tt.input)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("tests[%d] - len(errors) is not %d. got=%q", i, len(tt.expected), errors)
+			continue
+		}
+
+		for j, msg := range tt.expected {
+			if errors[j] != msg {
+				t.Errorf("tests[%d] - errors[%d] is not %q. got=%q", i, j, msg, errors[j])
+			}
+		}
+	}
+}
+
+func TestCheckScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		//las funciones solo ven sus variables y las globales.
+		{"var a:int = 1;\nfn f() int { return a; }", []string{"2:21 - identifier not found: a"}},
+		{"global a:int = 1;\nfn f() int { return a + g(); }\nfn g() int { return 2; }", []string{}},
+		//un closure ve las variables declaradas despues en su ambito.
+		{"var f:func = fn() int { return b; };\nvar b:int = 1;", []string{}},
+		//las variables de un bloque 'if' quedan en el ambito que lo contiene.
+		{"if (true) { x := 1; }\nprint(x);", []string{}},
+	}
+
+	for i, tt := range tests {
+		errors := testCheck(t, tt.input)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("tests[%d] - len(errors) is not %d. got=%q", i, len(tt.expected), errors)
+			continue
+		}
+
+		for j, msg := range tt.expected {
+			if errors[j] != msg {
+				t.Errorf("tests[%d] - errors[%d] is not %q. got=%q", i, j, msg, errors[j])
+			}
+		}
+	}
+}
+
+func TestCheckerKeepsDeclarations(t *testing.T) {
+	checker := New()
+
+	inputs := []string{"var x:int = 1;", "fn double2(a:int) int { return a * 2; }", "double2(x);"}
+	for i, input := range inputs {
+		program := parser.New(lexer.New(input)).ParserProgram()
+		if errors := checker.Check(program); len(errors) != 0 {
+			t.Fatalf("inputs[%d] - checker has errors: %v", i, errors[0].Error())
+		}
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	checker := New()
+	program := parser.New(lexer.New("var x:int = 1; fn name() string { return \"a\"; }")).ParserProgram()
+	if errors := checker.Check(program); len(errors) != 0 {
+		t.Fatalf("checker has errors: %v", errors[0].Error())
+	}
+
+	tests := []struct {
+		input    string
+		expected Type
+	}{
+		{"x", Int},
+		{"x + 1.5", Double},
+		{"name()", String},
+		{"[x]", List},
+		{"len", Func},
+		{"y", Any},
+		{"z := 1", Nil},
+		{"z", Any},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParserProgram()
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("input %q: statement is not *ast.ExpressionStatement. got=%T", tt.input, program.Statements[0])
+		}
+		if typ := checker.TypeOf(stmt.Expression); typ != tt.expected {
+			t.Errorf("input %q: type is not '%s'. got='%s'", tt.input, tt.expected, typ)
+		}
+	}
+
+	if errors := checker.Check(parser.New(lexer.New("x;")).ParserProgram()); len(errors) != 0 {
+		t.Fatalf("checker keeps errors of TypeOf: %v", errors[0].Error())
+	}
+}
@@ -0,0 +1,110 @@
+package typecheck
+
+//Type es el tipo estatico de una expresion. Any indica que el tipo no se conoce antes de
+//ejecutar el programa y es compatible con cualquier otro.
+type Type string
+
+const (
+	Any    Type = "any"
+	Nil    Type = "nil"
+	Int    Type = "int"
+	Double Type = "double"
+	Bool   Type = "bool"
+	String Type = "string"
+	List   Type = "list"
+	Map    Type = "map"
+	Func   Type = "func"
+	Stream Type = "stream"
+	Struct Type = "struct"
+	Module Type = "module"
+)
+
+//typeOfName devuelve el tipo que representa el nombre 'name' en una declaracion
+//(var x:int), los nombres desconocidos se toman como Any.
+func typeOfName(name string) Type {
+	switch typ := Type(name); typ {
+	case Int, Double, Bool, String, List, Map, Func, Stream, Struct:
+		return typ
+	}
+	return Any
+}
+
+func isNumeric(typ Type) bool {
+	return typ == Int || typ == Double
+}
+
+func isKnown(typ Type) bool {
+	return typ != Any && typ != Nil
+}
+
+//assignable indica si un valor de tipo 'from' se puede guardar en una variable de tipo
+//'to'. Un entero se convierte en double, igual que en el evaluador.
+func assignable(to, from Type) bool {
+	if !isKnown(to) || !isKnown(from) || to == from {
+		return true
+	}
+	return to == Double && from == Int
+}
+
+//signature describe los parametros y el valor devuelto de una funcion. Result vacio
+//indica que la funcion no devuelve un valor.
+type signature struct {
+	name   string
+	params []Type
+	result Type
+}
+
+//builtin describe una funcion del lenguaje: la cantidad de argumentos que acepta y el
+//tipo que devuelve.
+type builtin struct {
+	min, max int
+	result   Type
+}
+
+//variadic se usa como maximo de argumentos de las funciones que aceptan cualquier cantidad.
+const variadic = -1
+
+var builtins = map[string]builtin{
+	"len":     {1, 1, Int},
+	"front":   {1, 1, Any},
+	"back":    {1, 1, Any},
+	"pop":     {1, 2, Any},
+	"push":    {2, 2, Any},
+	"index":   {2, 2, Any},
+	"range":   {1, 2, List},
+	"str":     {1, 1, String},
+	"int":     {1, 1, Int},
+	"double":  {1, 1, Double},
+	"delete":  {2, 2, Any},
+	"find":    {2, 2, Any},
+	"type":    {1, 1, String},
+	"print":   {0, variadic, Nil},
+	"printf":  {1, 1, Nil},
+	"input":   {0, 0, Any},
+	"argv":    {0, 0, List},
+	"exit":    {0, 1, Nil},
+	"open":    {1, 1, Stream},
+	"isExist": {1, 1, Bool},
+	"isOpen":  {1, 1, Bool},
+	"create":  {1, 1, Stream},
+	"rename":  {2, 2, Any},
+	"move":    {2, 2, Any},
+	"write":   {2, 2, Any},
+	"read":    {1, 1, Any},
+	"close":   {1, 1, Any},
+	"remove":  {1, 1, Any},
+	"GoCeil":  {1, 1, Any},
+	"GoFloor": {1, 1, Any},
+	"GoLog":   {1, 1, Any},
+	"GoLog10": {1, 1, Any},
+	"GoPow":   {2, 2, Any},
+	"GoPow10": {1, 1, Any},
+	"GoRound": {1, 1, Any},
+	"GoTrunc": {1, 1, Any},
+	"GoMax":   {2, 2, Any},
+	"GoMin":   {2, 2, Any},
+	"GoNow":   {0, 0, Any},
+	"GoYear":  {0, 0, Any},
+	"GoMonth": {0, 0, Any},
+	"GoDay":   {0, 0, Any},
+}
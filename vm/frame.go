@@ -0,0 +1,28 @@
+package vm
+
+import (
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/object"
+)
+
+//Frame es una llamada en curso: el closure que se ejecuta, la instruccion actual y las
+//variables locales. 'base' es la altura de la pila al llamar, al volver la pila se
+//recorta hasta ahi. 'tail' indica que la llamada es un 'return f(...)' del frame de
+//abajo, que devuelve su resultado sin cambiarlo.
+type Frame struct {
+	cl    *object.Closure
+	ip    int
+	base  int
+	scope *object.Scope
+	call  diag.Span
+	tail  bool
+}
+
+func NewFrame(cl *object.Closure, base int, call diag.Span) *Frame {
+	scope := &object.Scope{
+		Locals: make([]object.Object, cl.Fn.NumLocals),
+		Names:  cl.Fn.LocalNames,
+		Outer:  cl.Scope,
+	}
+	return &Frame{cl: cl, ip: -1, base: base, scope: scope, call: call}
+}
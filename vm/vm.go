@@ -0,0 +1,602 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kenshindeveloper/april/code"
+	"github.com/kenshindeveloper/april/compiler"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/evaluator"
+	"github.com/kenshindeveloper/april/object"
+)
+
+//StackSize y FrameSize son los tamaños iniciales de la pila y de las llamadas, crecen
+//hasta lo que permite la profundidad maxima del Runtime.
+const (
+	StackSize = 2048
+	FrameSize = 64
+)
+
+var (
+	NIL   = evaluator.NIL
+	TRUE  = evaluator.TRUE
+	FALSE = evaluator.FALSE
+)
+
+//handler es un bloque try activo: si se produce un error la vm vuelve al frame 'frames'
+//con el Scope 'scope' y a la altura de pila 'sp' y continua en 'ip' con el error en la
+//pila.
+type handler struct {
+	frames int
+	sp     int
+	ip     int
+	scope  *object.Scope
+}
+
+type VM struct {
+	constants   []object.Object
+	globals     []object.Object
+	globalNames []string
+
+	stack []object.Object
+	sp    int //apunta al siguiente espacio libre, el tope es stack[sp-1]
+
+	frames      []*Frame
+	framesIndex int
+	handlers    []handler
+	runtime     *object.Runtime //flujos que usan las funciones del lenguaje
+
+	lastPopped object.Object
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	main := &object.Closure{Fn: bytecode.Main, Scope: &object.Scope{}}
+	frames := make([]*Frame, 1, FrameSize)
+	frames[0] = NewFrame(main, 0, diag.Span{})
+
+	return &VM{
+		constants:   bytecode.Constants,
+		globals:     make([]object.Object, len(bytecode.GlobalNames)),
+		globalNames: bytecode.GlobalNames,
+		stack:       make([]object.Object, StackSize),
+		frames:      frames,
+		framesIndex: 1,
+		runtime:     object.NewRuntime(),
+		lastPopped:  NIL,
+	}
+}
+
+//SetIO cambia los flujos con los que escriben y leen las funciones del lenguaje.
+func (vm *VM) SetIO(streams *object.IO) {
+	vm.runtime.IO = streams
+}
+
+//SetFileSystem cambia los permisos de las funciones de archivos, nil permite todo.
+func (vm *VM) SetFileSystem(fs *object.FileSystem) {
+	vm.runtime.FS = fs
+}
+
+//Limit limita la ejecucion: 'ctx' puede cancelarla y 'maxSteps' es la cantidad maxima de
+//llamadas y saltos, 0 indica que no hay limite.
+func (vm *VM) Limit(ctx context.Context, maxSteps int64) {
+	vm.runtime.Limit(ctx, maxSteps)
+}
+
+//SetMaxDepth cambia la cantidad maxima de llamadas en curso.
+func (vm *VM) SetMaxDepth(maxDepth int) {
+	vm.runtime.SetMaxDepth(maxDepth)
+}
+
+//Run ejecuta el programa y devuelve el valor de la ultima sentencia o del return del
+//programa. Un error que no se atrapa se devuelve como *object.Error.
+func (vm *VM) Run() object.Object {
+	for {
+		frame := vm.currentFrame()
+		frame.ip++
+		ip := frame.ip
+		ins := frame.cl.Fn.Instructions
+		op := code.Opcode(ins[ip])
+
+		var result object.Object
+
+		switch op {
+		case code.OpConstant:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			result = vm.push(vm.constants[index])
+
+		case code.OpPop:
+			vm.lastPopped = vm.pop()
+
+		case code.OpDup:
+			result = vm.push(vm.stack[vm.sp-1])
+
+		case code.OpTrue:
+			result = vm.push(TRUE)
+
+		case code.OpFalse:
+			result = vm.push(FALSE)
+
+		case code.OpNil:
+			result = vm.push(NIL)
+
+		case code.OpInfix:
+			operator := code.Operators[code.ReadUint8(ins[ip+1:])]
+			frame.ip++
+			right := vm.pop()
+			left := vm.pop()
+			result = vm.pushResult(evaluator.Infix(operator, left, right))
+
+		case code.OpPrefix:
+			operator := code.Operators[code.ReadUint8(ins[ip+1:])]
+			frame.ip++
+			result = vm.pushResult(evaluator.Prefix(operator, vm.pop()))
+
+		case code.OpAnd:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			if left, ok := vm.stack[vm.sp-1].(*object.Boolean); ok && !left.Value {
+				vm.stack[vm.sp-1] = FALSE
+				frame.ip = pos - 1
+			}
+
+		case code.OpIncrement:
+			operator := "++"
+			if code.ReadUint8(ins[ip+1:]) == 1 {
+				operator = "--"
+			}
+			frame.ip++
+			value := vm.pop()
+			if _, ok := value.(*object.Integer); !ok {
+				result = newError(diag.TypeMismatch, "operator '%s' must be integer. got='%T'", operator, value)
+				break
+			}
+			result = vm.pushResult(evaluator.Infix(operator[:1], value, &object.Integer{Value: 1}))
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip = pos - 1
+			if err := vm.runtime.Step(); err != nil {
+				result = err
+			}
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			if !evaluator.IsTruthy(vm.pop()) {
+				frame.ip = pos - 1
+			}
+
+		case code.OpGetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			value := vm.globals[index]
+			if value == nil {
+				result = newError(diag.NameNotFound, "identifier not found: %s", vm.globalNames[index])
+				break
+			}
+			result = vm.push(value)
+
+		case code.OpSetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			vm.globals[index] = vm.pop()
+
+		case code.OpGetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			result = vm.pushLocal(frame.scope, int(index))
+
+		case code.OpSetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			frame.scope.Locals[index] = vm.pop()
+
+		case code.OpGetOuter:
+			depth := code.ReadUint8(ins[ip+1:])
+			index := code.ReadUint8(ins[ip+2:])
+			frame.ip += 2
+			scope := frame.scope
+			for i := 0; i < int(depth); i++ {
+				scope = scope.Outer
+			}
+			result = vm.pushLocal(scope, int(index))
+
+		case code.OpSetOuter:
+			depth := code.ReadUint8(ins[ip+1:])
+			index := code.ReadUint8(ins[ip+2:])
+			frame.ip += 2
+			scope := frame.scope
+			for i := 0; i < int(depth); i++ {
+				scope = scope.Outer
+			}
+			scope.Locals[index] = vm.pop()
+
+		case code.OpGetBuiltin:
+			name := vm.constantString(ins[ip+1:])
+			frame.ip += 2
+			builtin, _ := evaluator.Builtin(name)
+			result = vm.push(builtin)
+
+		case code.OpNotFound:
+			message := vm.constantString(ins[ip+1:])
+			frame.ip += 2
+			result = newError(diag.NameNotFound, "%s", message)
+
+		case code.OpDeclare:
+			name := vm.constantString(ins[ip+1:])
+			typeName := vm.constantString(ins[ip+3:])
+			frame.ip += 4
+			if err := evaluator.Declare(name, typeName, vm.stack[vm.sp-1]); err != nil {
+				result = err
+			}
+
+		case code.OpImplicit:
+			name := vm.constantString(ins[ip+1:])
+			frame.ip += 2
+			if err := evaluator.Implicit(name, vm.stack[vm.sp-1]); err != nil {
+				result = err
+			}
+
+		case code.OpAssign:
+			current := vm.pop()
+			value := vm.pop()
+			result = vm.pushResult(evaluator.Assign(current, value))
+
+		case code.OpCheckList:
+			if _, ok := vm.stack[vm.sp-1].(*object.List); !ok {
+				result = newError(diag.TypeMismatch, "expression is not type list.")
+			}
+
+		case code.OpLen:
+			list := vm.pop().(*object.List)
+			result = vm.push(&object.Integer{Value: int64(len(list.Elements))})
+
+		case code.OpList:
+			count := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			elements := make([]object.Object, count)
+			copy(elements, vm.stack[vm.sp-count:vm.sp])
+			vm.sp -= count
+			result = vm.push(&object.List{Elements: elements})
+
+		case code.OpHash:
+			count := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			result = vm.pushResult(vm.buildHash(vm.sp-count, vm.sp))
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			result = vm.pushResult(evaluator.Index(left, index))
+
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			result = vm.pushResult(evaluator.SetIndex(left, index, value))
+
+		case code.OpStruct:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			literal := vm.constants[index].(*compiler.StructLiteral)
+			result = vm.pushResult(evaluator.Struct(literal.Node))
+
+		case code.OpCheckStruct:
+			name := vm.constantString(ins[ip+1:])
+			frame.ip += 2
+			if typ := vm.stack[vm.sp-1].Type(); typ != object.STRUCT_OBJ && typ != object.MODULE_OBJ {
+				result = newError(diag.TypeMismatch, "the variable '%s' is not type struct.", name)
+			}
+
+		case code.OpGetField:
+			name := vm.constantString(ins[ip+1:])
+			frame.ip += 2
+			result = vm.pushResult(evaluator.Field(vm.pop(), name))
+
+		case code.OpSetField:
+			name := vm.constantString(ins[ip+1:])
+			frame.ip += 2
+			value := vm.pop()
+			result = vm.pushResult(evaluator.SetField(vm.pop(), name, value))
+
+		case code.OpCall:
+			args := int(code.ReadUint8(ins[ip+1:]))
+			frame.ip++
+			tail := frame.ip+1 < len(ins) && code.Opcode(ins[frame.ip+1]) == code.OpReturnValue
+			result = vm.call(args, frame.cl.Fn.SourceMap.Lookup(ip), tail)
+
+		case code.OpReturnValue:
+			value := vm.pop()
+			if vm.framesIndex == 1 {
+				return value
+			}
+			fn := frame.cl.Fn
+			value = evaluator.ReturnValue(fn.Name, fn.Return, value)
+			if _, ok := value.(*object.Error); ok {
+				result = value
+				break
+			}
+			vm.popFrame()
+			result = vm.push(value)
+
+		case code.OpReturn:
+			if vm.framesIndex == 1 {
+				return vm.lastPopped
+			}
+			fn := frame.cl.Fn
+			if fn.Return != nil {
+				err := evaluator.MissingReturn(fn.Name, fn.Return)
+				err.Span = fn.Span
+				result = err
+				break
+			}
+			vm.popFrame()
+			result = vm.push(NIL)
+
+		case code.OpClosure:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			fn := vm.constants[index].(*object.CompiledFunction)
+			result = vm.push(&object.Closure{Fn: fn, Scope: frame.scope})
+
+		case code.OpEnterScope:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			names := vm.constants[index].(*compiler.LoopScope).Names
+			frame.scope = &object.Scope{Locals: make([]object.Object, len(names)), Names: names, Outer: frame.scope}
+
+		case code.OpLeaveScope:
+			frame.scope = frame.scope.Outer
+
+		case code.OpTry:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			vm.handlers = append(vm.handlers, handler{frames: vm.framesIndex, sp: vm.sp, ip: pos, scope: frame.scope})
+
+		case code.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		case code.OpCaught:
+			err := vm.pop().(*object.Error)
+			result = vm.push(evaluator.Caught(err))
+
+		case code.OpImport:
+			path := vm.constantString(ins[ip+1:])
+			from := vm.constantString(ins[ip+3:])
+			frame.ip += 4
+			result = vm.pushResult(vm.importModule(from, path))
+
+		case code.OpThrow:
+			value := vm.pop()
+			if err, ok := value.(*object.Error); ok {
+				result = err
+			} else {
+				result = evaluator.Throw(value)
+			}
+
+		default:
+			result = newError(diag.RuntimeError, "unknown opcode %d", op)
+		}
+
+		if err, ok := result.(*object.Error); ok {
+			if err := vm.raise(err, frame, ip); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+//raise entrega el error al try activo mas interno. Si no hay ninguno devuelve el error
+//con su posicion y la traza de llamadas.
+func (vm *VM) raise(err *object.Error, frame *Frame, ip int) *object.Error {
+	if !err.Span.Start.IsValid() {
+		err.Span = frame.cl.Fn.SourceMap.Lookup(ip)
+	}
+	if err.Trace == nil && vm.framesIndex > 1 {
+		err.Trace = vm.trace()
+	}
+
+	if len(vm.handlers) == 0 || !err.Catchable() {
+		return err
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.framesIndex = h.frames
+	vm.sp = h.sp
+	vm.push(err)
+	vm.currentFrame().ip = h.ip - 1
+	vm.currentFrame().scope = h.scope
+	return nil
+}
+
+//call llama a la funcion que esta en la pila debajo de sus 'args' argumentos. Si 'tail'
+//indica que el resultado se devuelve enseguida y la funcion actual se llama a si misma o
+//ya tiene un frame que espera este resultado, el nuevo frame reemplaza al actual: ese
+//frame comprueba el tipo del resultado y la recursion, directa o mutua, no ocupa mas
+//frames.
+func (vm *VM) call(args int, span diag.Span, tail bool) object.Object {
+	if err := vm.runtime.Step(); err != nil {
+		return err
+	}
+	callee := vm.stack[vm.sp-1-args]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		fn := callee.Fn
+		if len(fn.Parameters) != args {
+			return newError(diag.WrongArgumentNum, "count parameters not match.")
+		}
+
+		tail = tail && vm.framesIndex > 1
+		replace := tail && (vm.currentFrame().cl.Fn == fn || vm.waiting(vm.currentFrame().cl.Fn))
+		if !replace && vm.framesIndex > vm.runtime.MaxDepth() {
+			return newError(diag.StackOverflow, "maximum call depth of %d exceeded", vm.runtime.MaxDepth())
+		}
+
+		frame := NewFrame(callee, vm.sp-1-args, span)
+		for i, param := range fn.Parameters {
+			arg, ok := evaluator.Argument(param.Type.Name, vm.stack[vm.sp-args+i])
+			if !ok {
+				if fn.Name == "<closure>" {
+					return newError(diag.TypeMismatch, "data type mismatch into function closure.")
+				}
+				return newError(diag.TypeMismatch, "data type mismatch into function '%s'", fn.Name)
+			}
+			frame.scope.Locals[i] = arg
+		}
+
+		frame.tail = tail
+		if replace {
+			current := vm.popFrame()
+			frame.base = current.base
+			frame.tail = current.tail
+		}
+		vm.sp = frame.base
+		vm.pushFrame(frame)
+		return nil
+
+	//las funciones de los modulos se ejecutan con el evaluador.
+	case *object.Function, *object.FunctionClosure:
+		arguments := make([]object.Object, args)
+		copy(arguments, vm.stack[vm.sp-args:vm.sp])
+		vm.sp = vm.sp - 1 - args
+		return vm.pushResult(evaluator.Call(vm.runtime, callee, arguments, span.Start))
+
+	case *object.Builtin:
+		arguments := make([]object.Object, args)
+		copy(arguments, vm.stack[vm.sp-args:vm.sp])
+		vm.sp = vm.sp - 1 - args
+
+		result := callee.Call(vm.runtime, arguments...)
+		if result == nil {
+			result = NIL
+		}
+		return vm.pushResult(result)
+
+	default:
+		return newError(diag.TypeMismatch, "not a function: %s", callee.Type())
+	}
+}
+
+//waiting indica si debajo del frame actual, en su cadena de llamadas en cola, hay un
+//frame de la funcion 'fn' que devuelve el resultado de la llamada actual.
+func (vm *VM) waiting(fn *object.CompiledFunction) bool {
+	for i := vm.framesIndex - 1; i > 0 && vm.frames[i].tail; i-- {
+		if vm.frames[i-1].cl.Fn == fn {
+			return true
+		}
+	}
+	return false
+}
+
+//importModule carga el modulo 'path' importado desde el archivo 'from'. Los modulos se
+//ejecutan con el evaluador y comparten la ejecucion de la vm.
+func (vm *VM) importModule(from, path string) object.Object {
+	if vm.runtime.Importer == nil {
+		return newError(diag.ImportNotFound, "modules can not be imported here: '%s'.", path)
+	}
+	return vm.runtime.Importer.Import(vm.runtime, from, path)
+}
+
+//SetArgs cambia los argumentos que el programa recibe con argv().
+func (vm *VM) SetArgs(args []string) {
+	vm.runtime.Args = args
+}
+
+//SetImporter cambia quien carga los modulos de 'import ... as'.
+func (vm *VM) SetImporter(importer object.Importer) {
+	vm.runtime.Importer = importer
+}
+
+func (vm *VM) buildHash(start, end int) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	for i := start; i < end; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError(diag.TypeMismatch, "unusable as hash key: %s", key.Type())
+		}
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	vm.sp = start
+	return &object.Hash{Pairs: pairs}
+}
+
+//trace devuelve las llamadas en curso, la mas reciente primero.
+func (vm *VM) trace() []object.Frame {
+	trace := []object.Frame{}
+	for i := vm.framesIndex - 1; i > 0; i-- {
+		frame := vm.frames[i]
+		trace = append(trace, object.Frame{Function: frame.cl.Fn.Name, Call: frame.call.Start})
+	}
+	return trace
+}
+
+func (vm *VM) pushLocal(scope *object.Scope, index int) object.Object {
+	value := scope.Locals[index]
+	if value == nil {
+		return newError(diag.NameNotFound, "identifier not found: %s", scope.Names[index])
+	}
+	return vm.push(value)
+}
+
+func (vm *VM) constantString(ins code.Instructions) string {
+	return vm.constants[code.ReadUint16(ins)].(*object.String).Value
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.framesIndex] = f
+	}
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	frame := vm.frames[vm.framesIndex]
+	vm.sp = frame.base
+	return frame
+}
+
+//push agrega 'obj' a la pila, si esta llena la agranda. La profundidad de las llamadas
+//ya limita su tamaño.
+func (vm *VM) push(obj object.Object) object.Object {
+	if vm.sp >= len(vm.stack) {
+		vm.stack = append(vm.stack, make([]object.Object, len(vm.stack))...)
+	}
+	vm.stack[vm.sp] = obj
+	vm.sp++
+	return nil
+}
+
+//pushResult agrega el resultado de una operacion, si es un error lo devuelve sin
+//agregarlo.
+func (vm *VM) pushResult(obj object.Object) object.Object {
+	if _, ok := obj.(*object.Error); ok {
+		return obj
+	}
+	return vm.push(obj)
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[vm.sp-1]
+	vm.sp--
+	return obj
+}
+
+func newError(code string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,680 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenshindeveloper/april/compiler"
+	"github.com/kenshindeveloper/april/diag"
+	"github.com/kenshindeveloper/april/lexer"
+	"github.com/kenshindeveloper/april/module"
+	"github.com/kenshindeveloper/april/object"
+	"github.com/kenshindeveloper/april/parser"
+)
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"var identity:func = fn(x:int) int { return x;}; identity(5);", 5},
+		{"var identity:func = fn(x:int) int { return x; }; identity(5);", 5},
+		{"var add:func = fn(x:int, y:int) int { return x + y;}; add(5, 5);", 10},
+		{"var prueba:func = fn(x:int) int { return x * 2;}; prueba(5);", 10},
+		{"var add:func = fn(x:int, y:int) int { return x + y;}; add(5 + 5, add(5, 5));", 20},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestForExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"var n:int = 0; for (i := 0; i < 10; i++) { if (i == 4) { break; } n = n + i; } n;", 6},
+		{"var n:int = 0; for (i := 0; i < 3; i++) { for (j := 0; j < 3; j++) { if (j == 1) { break; } n = n + 1; } n = n + 10; } n;", 33},
+		{"var n:int = 0; for (x := [1, 2, 3]) { for (y := [1, 2, 3]) { if (y == 2) { break; } n = n + x; } } n;", 6},
+		{"fn f() int { for (i := 0; i < 3; i++) { for (j := 0; j < 3; j++) { if (j == 1) { return i * 10 + j; } } } return -1; } f();", 1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestListIndexExpresssion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"var i:int = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"[1, 2, 3][3];", nil},
+		{"[1, 2, 3][-1];", nil},
+	}
+
+	for _, data := range tests {
+		evaluated := testEval(data.input)
+		integer, ok := data.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestListEvaluator(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.List)
+	if !ok {
+		t.Fatalf("evaluated is not '*object.List'. got='%T'", evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("len(result.Elements) is not '%d'. got='%d'", 3, len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestIfElseExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (1 <= 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	return obj == NIL
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestEvalVarStatetement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"var x:int = 15; x;", 15},
+	}
+
+	for i, response := range tests {
+		l := lexer.New(response.input)
+		p := parser.New(l)
+		program := p.ParserProgram()
+		if program == nil {
+			t.Fatalf("program is null.")
+		}
+
+		if len(program.Statements) != 2 {
+			t.Fatalf("len(program.Statement) is not '%d'. got='%d'.", 2, len(program.Statements))
+		}
+		evaluated := testEval(response.input)
+
+		if i == 1 {
+			integer, ok := evaluated.(*object.Integer)
+			if !ok {
+				t.Fatalf("evaluated is not '*object.Integer'. got='%T'", evaluated)
+			}
+
+			if integer.Value != response.expected {
+				t.Fatalf("evaluated is not '%d'. got='%d'", response.expected, integer.Value)
+			}
+		}
+	}
+}
+
+func TestNotExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"not true", false},
+		{"not false", true},
+		{"not not true", true},
+		{"not not false", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 > 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"true == true and false == false", true},
+		{"false == false", true},
+		{"true == false or true == false", false},
+		{"true == false or true == true", true},
+		{"true != false", true},
+		{"false != true", true},
+		{"(1 < 2) == true", true},
+		{"(1 <= 2) == false", false},
+		{"(1 > 2) == true", false},
+		{"(1 >= 2) == false", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func testEval(input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParserProgram()
+
+	c := compiler.New()
+	if errors := c.Compile(program); len(errors) > 0 {
+		return &object.Error{Code: errors[0].Code, Span: errors[0].Span, Message: errors[0].Message}
+	}
+	return New(c.Bytecode()).Run()
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"10", 10},
+		{"5", 5},
+		{"10", 10},
+		{"-5", -5},
+		{"-10", -10},
+		{"5", 5},
+		{"10", 10},
+		{"-5", -5},
+		{"-10", -10},
+		{"8 + 6", 14},
+		{"6 + 5 + 5 + 5 - 10", 11},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"3 * (3 * 3) + 10", 37},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 -50", 0},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"20 + 2 * -10", 0},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"3 * (3 * 3) + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+	}
+
+	for _, response := range tests {
+		l := lexer.New(response.input)
+		p := parser.New(l)
+		program := p.ParserProgram()
+		if program == nil {
+			t.Fatalf("ParserProgram is nil.")
+		}
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("len(program.Statement) is not 1. got=%d", len(program.Statements))
+		}
+
+		evaluated := testEval(response.input)
+
+		integer, ok := evaluated.(*object.Integer)
+		if !ok {
+			t.Fatalf("object is not Integer. got=%T", evaluated)
+		}
+
+		if integer.Value != response.expected {
+			t.Fatalf("object was wrong. got=%d, want=%d", integer.Value, response.expected)
+		}
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     string
+		expected string
+	}{
+		{"var x:int = 1;\nx + y;", diag.NameNotFound, "2:5 - identifier not found: y"},
+		{"var x:int = 1;\n  x / 0;", diag.DivisionByZero, "2:5 - division by zero"},
+		{"var l:list = [1, 2];\nlen(l, l);", diag.WrongArgumentNum, "2:1 - wrong number of arguments. got'2', want='1'"},
+		{"[1, 2][5];", diag.IndexOutOfRange, "1:7 - list index out of range."},
+	}
+
+	for i, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("tests[%d] - evaluated is not '*object.Error'.", i)
+		}
+
+		if err.Code != tt.code {
+			t.Errorf("tests[%d] - err.Code is not '%s'. got='%s'", i, tt.code, err.Code)
+		}
+
+		if err.Diagnostic().Error() != tt.expected {
+			t.Errorf("tests[%d] - err is not %q. got=%q", i, tt.expected, err.Diagnostic().Error())
+		}
+	}
+}
+
+func TestErrorTrace(t *testing.T) {
+	input := `fn div(a:int, b:int) int {
+	return a / b;
+}
+fn half(x:int) int {
+	return div(x, 0);
+}
+var f:func = fn(x:int) int { return half(x); };
+f(4);`
+
+	err, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("evaluated is not '*object.Error'.")
+	}
+
+	if err.Diagnostic().Error() != "2:11 - division by zero" {
+		t.Fatalf("err is not %q. got=%q", "2:11 - division by zero", err.Diagnostic().Error())
+	}
+
+	expected := []struct {
+		function string
+		call     string
+	}{
+		{"div", "5:9"},
+		{"half", "7:37"},
+		{"<closure>", "8:1"},
+	}
+
+	if len(err.Trace) != len(expected) {
+		t.Fatalf("len(err.Trace) is not %d. got=%d", len(expected), len(err.Trace))
+	}
+
+	for i, tt := range expected {
+		if err.Trace[i].Function != tt.function || err.Trace[i].Call.String() != tt.call {
+			t.Errorf("err.Trace[%d] is not '%s %s'. got='%s %s'", i, tt.function, tt.call, err.Trace[i].Function, err.Trace[i].Call)
+		}
+	}
+
+	if err := testEval("fn id(x:int) int { return x; } id(1); 1 / 0;").(*object.Error); len(err.Trace) != 0 {
+		t.Fatalf("err.Trace is not empty outside functions. got=%d", len(err.Trace))
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var r:string = ""; try { 1 / 0; } catch (e) { r = e.kind + ": " + e.message; } r;`, "DivisionByZero: division by zero"},
+		{`var r:string = ""; try { [1, 2][5]; } catch (e) { r = e.kind; } r;`, "IndexOutOfRange"},
+		{`var r:string = ""; try { open("no/existe.april"); } catch (e) { r = e.kind; } r;`, "IOError"},
+		{`var r:string = ""; try { throw "fallo"; } catch (e) { r = e.kind + ": " + e.message; } r;`, "Error: fallo"},
+		{`fn check(x:int) int { if (x < 0) { throw "negative"; } return x; } var r:string = ""; try { check(-1); r = "no"; } catch (e) { r = e.message; } r;`, "negative"},
+		{`var r:string = ""; try { r = "try"; } catch (e) { r = "catch"; } finally { r = r + " finally"; } r;`, "try finally"},
+		{`var r:string = ""; try { 1 / 0; } catch (e) { r = "catch"; } finally { r = r + " finally"; } r;`, "catch finally"},
+		{`var r:string = ""; for (i := 0; i < 5; i++) { try { if (i == 2) { break; } } finally { r = r + str(i); } } r;`, "012"},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("tests[%d] - evaluated is not '*object.String'. got='%T' (%s)", i, evaluated, evaluated.Inspect())
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("tests[%d] - str.Value is not '%s'. got='%s'", i, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "fallo";`, "1:1 - fallo"},
+		{`try { throw "fallo"; } finally { }`, "1:7 - fallo"},
+		{`try { 1 / 0; } catch (e) { throw e; }`, "1:28 - division by zero"},
+	}
+
+	for i, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("tests[%d] - evaluated is not '*object.Error'.", i)
+		}
+
+		if err.Diagnostic().Error() != tt.expected {
+			t.Errorf("tests[%d] - err is not %q. got=%q", i, tt.expected, err.Diagnostic().Error())
+		}
+	}
+}
+
+func TestReturnType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn f() int { return 5; } f();", int64(5)},
+		{"fn f() double { return 5; } f();", 5.0},
+		{"var f:func = fn() double { return 2; }; f();", 2.0},
+		{"fn f() func { return fn() int { return 1; }; } var g:func = f(); g();", int64(1)},
+		{"fn f() int { return \"5\"; } f();", "1:14 - function 'f' must return 'int', got 'STRING'"},
+		{"var f:func = fn() string { return 1; };\nf();", "1:28 - function '<closure>' must return 'string', got 'INTEGER'"},
+		{"fn f(x:int) int {\n\tif (x > 0) { return x; }\n}\nf(-1);", "1:4 - missing return in function 'f', expected 'int'"},
+		{"var f:func = fn(x:int) bool { x; };\nf(1);", "1:29 - missing return in function '<closure>', expected 'bool'"},
+		{"fn f() { return 1; } f();", "1:10 - function 'f' has no return type, got 'INTEGER'"},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case float64:
+			double, ok := evaluated.(*object.Double)
+			if !ok || double.Value != expected {
+				t.Errorf("tests[%d] - evaluated is not Double %f. got=%T (%s)", i, expected, evaluated, evaluated.Inspect())
+			}
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("tests[%d] - evaluated is not '*object.Error'. got=%T (%s)", i, evaluated, evaluated.Inspect())
+				continue
+			}
+			if err.Diagnostic().Error() != expected {
+				t.Errorf("tests[%d] - err is not %q. got=%q", i, expected, err.Diagnostic().Error())
+			}
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn counter() func { var n:int = 0; return fn() int { n++; return n; }; } var c:func = counter(); c(); c(); c();", 3},
+		{"fn adder(a:int) func { return fn(b:int) int { return a + b; }; } var add2:func = adder(2); add2(5);", 7},
+		{"var total:int = 0; var add:func = fn(x:int) { total += x; }; add(3); add(4); total;", 7},
+		{"var f:func = fn() int { return b; }; var b:int = 9; f();", 9},
+		{"fn f() int { var l:list = [1, 2, 3]; var s:int = 0; for (e := l) { s += e; } return s; } f();", 6},
+		{"fn fib(n:int) int { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); } fib(15);", 610},
+		{"fn f() int { try { return 1; } finally { global g:int = 2; } } f() + g;", 3},
+		{"fns := []; for (i := 0; i < 3; i++) { v := i; push(fns, fn() int { return v; }); } fns[0]();", 0},
+		{"fn f() int { fns := []; for (e := [5, 6]) { v := e; push(fns, fn() int { return v; }); } return fns[0]() + fns[1](); } f();", 11},
+		{"fns := []; for (i := 0; i < 3; i++) { for (j := 0; j < 2; j++) { v := i * 10 + j; push(fns, fn() int { return v; }); if (j == 0) { break; } } } fns[2]();", 20},
+		{"n := 0; for (i := 0; i < 3; i++) { v := i; try { throw v; } catch (e) { n += v; } } n;", 3},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		if err, ok := evaluated.(*object.Error); ok {
+			t.Errorf("tests[%d] - evaluated is an error: %s", i, err.Inspect())
+			continue
+		}
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestScopeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var a:int = 1;\nfn f() int { return a; }\nf();", "2:21 - identifier not found: a"},
+		{"x := 1; x := 2;", "1:11 - variable 'x' already exist."},
+		{"for (i := 0; i < 2; i++) { }\ni;", "2:1 - identifier not found: i"},
+		{"var f:func = fn() int { return b; }; f();", "1:32 - identifier not found: b"},
+	}
+
+	for i, tt := range tests {
+		err, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("tests[%d] - evaluated is not '*object.Error'.", i)
+			continue
+		}
+
+		if err.Diagnostic().Error() != tt.expected {
+			t.Errorf("tests[%d] - err is not %q. got=%q", i, tt.expected, err.Diagnostic().Error())
+		}
+	}
+}
+
+func TestBuiltinIO(t *testing.T) {
+	tests := []struct {
+		input    string
+		stdin    string
+		expected string
+	}{
+		{"print(1, \"a\"); printf(\"x\\ty\");", "", "1\n'a'\n'x\ty'\n"},
+		{"printf(\"100% done\");", "", "'100% done'\n"},
+		{"fn show(v:int) { print(v * 2); } show(21);", "", "42\n"},
+		{"print(input()); print(input()); print(input());", "uno\ndos", "'uno'\n'dos'\nnull\n"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParserProgram()
+		c := compiler.New()
+		if errors := c.Compile(program); len(errors) > 0 {
+			t.Fatalf("tests[%d] - compiler has errors: %v", i, errors)
+		}
+
+		var stdout bytes.Buffer
+		machine := New(c.Bytecode())
+		machine.SetIO(object.NewIO(&stdout, &stdout, strings.NewReader(tt.stdin)))
+		if err, ok := machine.Run().(*object.Error); ok {
+			t.Errorf("tests[%d] - unexpected error: %s", i, err.Message)
+			continue
+		}
+		if stdout.String() != tt.expected {
+			t.Errorf("tests[%d] - output wrong. want=%q, got=%q", i, tt.expected, stdout.String())
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{"for (true) { }", diag.StepLimit},
+		{"fn loop(n:int) int { return loop(n + 1); } loop(0);", diag.StepLimit},
+		{"try { for (true) { } } catch (e) { }", diag.StepLimit},
+		{"try { exit(2); } catch (e) { }", diag.Exit},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParserProgram()
+		c := compiler.New()
+		if errors := c.Compile(program); len(errors) > 0 {
+			t.Fatalf("tests[%d] - compiler has errors: %v", i, errors)
+		}
+
+		machine := New(c.Bytecode())
+		machine.Limit(context.Background(), 500)
+		err, ok := machine.Run().(*object.Error)
+		if !ok {
+			t.Errorf("tests[%d] - result is not an error", i)
+			continue
+		}
+		if err.Code != tt.code {
+			t.Errorf("tests[%d] - code is not %s. got=%s (%s)", i, tt.code, err.Code, err.Message)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn loop(n:int, acc:int) int { if (n == 0) { return acc; } return loop(n - 1, acc + 1); } loop(100000, 0);", int64(100000)},
+		{"var count:func = fn(n:int) int { if (n == 0) { return 7; } return count(n - 1); }; count(100000);", int64(7)},
+		{"fn down(n:int) int { if (n == 0) { return 0; } return 1 + down(n - 1); } down(3000);", int64(3000)},
+		{"fn even(n:int) bool { if (n == 0) { return true; } return odd(n - 1); }\nfn odd(n:int) bool { if (n == 0) { return false; } return even(n - 1); }\neven(100001);", false},
+		{"fn a(n:int) string { if (n == 0) { return \"x\"; } return b(n - 1); } fn b(n:int) int { return a(n); } b(100000);", diag.TypeMismatch},
+		{"fn down(n:int) int { if (n == 0) { return 0; } return 1 + down(n - 1); } down(100000);", diag.StackOverflow},
+		{"fn fail(n:int) int { if (n == 0) { return 1 / 0; } return fail(n - 1); } fn f() int { try { return fail(5000); } catch (e) { return 2; } } f();", int64(2)},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok || err.Code != expected {
+				t.Errorf("tests[%d] - evaluated is not an error %s. got=%T (%s)", i, expected, evaluated, evaluated.Inspect())
+			}
+		}
+	}
+
+	program := parser.New(lexer.New("fn down(n:int) int { if (n == 0) { return 0; } return 1 + down(n - 1); } down(20);")).ParserProgram()
+	c := compiler.New()
+	if errors := c.Compile(program); len(errors) > 0 {
+		t.Fatalf("compiler has errors: %v", errors)
+	}
+	machine := New(c.Bytecode())
+	machine.SetMaxDepth(10)
+	err, ok := machine.Run().(*object.Error)
+	if !ok || err.Code != diag.StackOverflow {
+		t.Fatalf("SetMaxDepth(10) does not limit down(20).")
+	}
+	if err.Message != "maximum call depth of 10 exceeded" {
+		t.Fatalf("err.Message is not %q. got=%q", "maximum call depth of 10 exceeded", err.Message)
+	}
+}
+
+func TestImportModule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "april")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lib := filepath.Join(dir, "lib.april")
+	source := `global Base:int = 10; fn Add(x:int) int { return x + Base; } fn hidden() int { return 0; }`
+	if err := ioutil.WriteFile(lib, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "` + lib + `" as lib
+lib.Add(5);`, int64(15)},
+		{`import "` + lib + `" as lib
+fn twice() int { return lib.Add(1) * 2; } twice();`, int64(22)},
+		{`import "` + lib + `" as lib
+lib.Base;`, int64(10)},
+		{`import "` + lib + `" as lib
+lib.hidden();`, diag.NameNotFound},
+		{`import "` + filepath.Join(dir, "nope.april") + `" as nope`, diag.ImportNotFound},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParserProgram()
+		c := compiler.New()
+		if errors := c.Compile(program); len(errors) > 0 {
+			t.Fatalf("compiler has errors: %v", errors)
+		}
+		machine := New(c.Bytecode())
+		machine.SetImporter(module.New(diag.Sources{}))
+		evaluated := machine.Run()
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok || err.Code != expected {
+				t.Errorf("tests[%d] - evaluated is not an error %s. got=%T (%s)", i, expected, evaluated, evaluated.Inspect())
+			}
+		}
+	}
+}